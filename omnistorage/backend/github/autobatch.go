@@ -0,0 +1,163 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Auto-batch defaults.
+const (
+	defaultAutoBatchMaxFiles = 100
+	defaultAutoBatchMaxBytes = 10 << 20
+	defaultAutoBatchWindow   = 5 * time.Second
+)
+
+// CommitWaiter is implemented by writers returned from Backend.NewWriter.
+// Wait blocks until the writer's content has been committed and returns
+// the error of that commit. In auto-batch mode, Close only queues the
+// content, so Wait is how a failed batch commit is reported per writer.
+type CommitWaiter interface {
+	Wait(ctx context.Context) error
+}
+
+// Wait blocks until the content has been committed, or ctx is done.
+func (w *writer) Wait(ctx context.Context) error {
+	select {
+	case <-w.done:
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// finish records the outcome of committing the writer's content.
+func (w *writer) finish(result *CommitResult, err error) {
+	w.mu.Lock()
+	w.result, w.err = result, err
+	w.mu.Unlock()
+	close(w.done)
+}
+
+// autoBatcher queues closed writers and commits them together.
+type autoBatcher struct {
+	mu      sync.Mutex
+	pending []*writer
+	bytes   int64
+	timer   *time.Timer
+
+	// flushMu serializes commits so that batches do not race for the
+	// branch head
+	flushMu sync.Mutex
+}
+
+// add queues a closed writer, and commits the queue if it has reached the
+// file or byte limit. The writer's context is used for that commit; the
+// commit at the end of the time window runs in the background.
+func (a *autoBatcher) add(w *writer) error {
+	b := w.backend
+	if err := b.checkClosed(); err != nil {
+		w.finish(nil, err)
+		return err
+	}
+	if err := w.ctx.Err(); err != nil {
+		w.finish(nil, err)
+		return err
+	}
+
+	maxFiles := b.config.AutoBatchMaxFiles
+	if maxFiles <= 0 {
+		maxFiles = defaultAutoBatchMaxFiles
+	}
+	maxBytes := b.config.AutoBatchMaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultAutoBatchMaxBytes
+	}
+	window := b.config.AutoBatchWindow
+	if window <= 0 {
+		window = defaultAutoBatchWindow
+	}
+
+	a.mu.Lock()
+	a.pending = append(a.pending, w)
+	a.bytes += int64(w.buffer.Len())
+	full := len(a.pending) >= maxFiles || a.bytes >= maxBytes
+	if a.timer == nil && !full {
+		a.timer = time.AfterFunc(window, func() {
+			_ = b.Flush(context.Background())
+		})
+	}
+	a.mu.Unlock()
+
+	if full {
+		return b.Flush(w.ctx)
+	}
+	return nil
+}
+
+// Flush commits the writes queued in auto-batch mode as a single commit.
+// If the commit fails, the error is returned and also reported by Wait of
+// each queued writer. Flush is a no-op if nothing is queued.
+func (b *Backend) Flush(ctx context.Context) error {
+	a := &b.batcher
+	a.flushMu.Lock()
+	defer a.flushMu.Unlock()
+
+	a.mu.Lock()
+	pending := a.pending
+	a.pending, a.bytes = nil, 0
+	if a.timer != nil {
+		a.timer.Stop()
+		a.timer = nil
+	}
+	a.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	result, err := b.commitWriters(ctx, pending)
+	for _, w := range pending {
+		if err != nil {
+			w.finish(nil, fmt.Errorf("github: auto-batch commit of %s: %w", w.filePath, err))
+		} else {
+			w.finish(result, nil)
+		}
+	}
+	return err
+}
+
+// commitWriters commits the content of writers in one commit. A path
+// written more than once gets the content of the last writer.
+func (b *Backend) commitWriters(ctx context.Context, writers []*writer) (*CommitResult, error) {
+	latest := make(map[string]*writer, len(writers))
+	var paths []string
+	for _, w := range writers {
+		if _, ok := latest[w.filePath]; !ok {
+			paths = append(paths, w.filePath)
+		}
+		latest[w.filePath] = w
+	}
+
+	batch, err := b.NewBatch(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	// Writes with commit options are not queued, so only the Config's
+	// commit options apply
+	if err := batch.SetCommitOptions(CommitOptions{}); err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		if err := batch.Write(p, latest[p].buffer.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := batch.Commit(); err != nil {
+		return nil, err
+	}
+	return batch.CommitResult(), nil
+}
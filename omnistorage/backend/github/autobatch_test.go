@@ -0,0 +1,151 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// queueWrite writes and closes a file on an auto-batching backend.
+func queueWrite(t *testing.T, ctx context.Context, backend *Backend, p, content string) io.WriteCloser {
+	t.Helper()
+	w, err := backend.NewWriter(ctx, p)
+	if err != nil {
+		t.Fatalf("NewWriter(%q) failed: %v", p, err)
+	}
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatalf("Write(%q) failed: %v", p, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close(%q) failed: %v", p, err)
+	}
+	return w
+}
+
+func TestAutoBatch(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	backend.config.AutoBatch = true
+	backend.config.AutoBatchMaxFiles = 3
+	backend.config.AutoBatchWindow = time.Hour
+
+	ctx := context.Background()
+	commits := func() int {
+		n := 0
+		for _, req := range srv.Requests() {
+			if strings.HasSuffix(req, "/git/commits") && strings.HasPrefix(req, "POST") {
+				n++
+			}
+		}
+		return n
+	}
+
+	var writers []io.WriteCloser
+	for i := 0; i < 2; i++ {
+		writers = append(writers, queueWrite(t, ctx, backend, fmt.Sprintf("batch/%d.txt", i), fmt.Sprint(i)))
+	}
+	if exists, _ := backend.Exists(ctx, "batch/0.txt"); exists {
+		t.Error("Expected queued writes to be invisible before the commit")
+	}
+
+	// The third file reaches AutoBatchMaxFiles; a repeated path keeps the
+	// last content
+	writers = append(writers, queueWrite(t, ctx, backend, "batch/0.txt", "zero"))
+	if n := commits(); n != 1 {
+		t.Errorf("Expected 1 commit, got %d", n)
+	}
+
+	var sha string
+	for _, w := range writers {
+		if err := w.(CommitWaiter).Wait(ctx); err != nil {
+			t.Errorf("Wait failed: %v", err)
+		}
+		result := w.(CommitResultProvider).CommitResult()
+		if result == nil || (sha != "" && result.CommitSHA != sha) {
+			t.Fatalf("Expected all writers to share one commit, got %+v", result)
+		}
+		sha = result.CommitSHA
+	}
+	assertContent(t, ctx, backend, "batch/0.txt", []byte("zero"))
+	assertContent(t, ctx, backend, "batch/1.txt", []byte("1"))
+
+	// Close commits what is still queued
+	queueWrite(t, ctx, backend, "batch/2.txt", "2")
+	if err := backend.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if data, ok := srv.File("main", "batch/2.txt"); !ok || string(data) != "2" {
+		t.Errorf("Expected Close to commit the queued write, got %q", data)
+	}
+}
+
+func TestAutoBatchWindow(t *testing.T) {
+	backend, _ := fakeServerBackend(t)
+	backend.config.AutoBatch = true
+	backend.config.AutoBatchWindow = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	w := queueWrite(t, ctx, backend, "window.txt", "window")
+	if err := w.(CommitWaiter).Wait(ctx); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	assertContent(t, ctx, backend, "window.txt", []byte("window"))
+}
+
+func TestAutoBatchFlushError(t *testing.T) {
+	backend, _ := fakeServerBackend(t)
+	backend.config.AutoBatch = true
+	backend.config.AutoBatchWindow = time.Hour
+
+	ctx := context.Background()
+	a := queueWrite(t, ctx, backend, "a.txt", "a")
+	b := queueWrite(t, ctx, backend, "b.txt", "b")
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := backend.Flush(canceled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Flush error = %v, want context.Canceled", err)
+	}
+
+	// Each queued writer reports the failure
+	for _, w := range []io.WriteCloser{a, b} {
+		err := w.(CommitWaiter).Wait(ctx)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Wait error = %v, want context.Canceled", err)
+		}
+	}
+	if exists, _ := backend.Exists(ctx, "a.txt"); exists {
+		t.Error("Expected nothing to be committed")
+	}
+}
+
+func TestAutoBatchCommitOptions(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	defer func() { _ = backend.Close() }()
+	backend.config.AutoBatch = true
+	backend.config.AutoBatchWindow = time.Hour
+	backend.config.StatModTime = true
+	backend.config.CommitAuthor = &CommitAuthor{Name: "Test Bot", Email: "bot@example.com"}
+
+	// Commit options give the write a commit of its own rather than being
+	// dropped in a shared batch commit
+	jan := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ctx := WithCommitOptions(context.Background(), CommitOptions{CommitDate: jan})
+	queueWrite(t, ctx, backend, "dated.txt", "dated")
+	if _, ok := srv.File("main", "dated.txt"); !ok {
+		t.Fatal("Expected the write with commit options to be committed on Close")
+	}
+
+	info, err := backend.Stat(context.Background(), "dated.txt")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if !info.ModTime().Equal(jan) {
+		t.Errorf("commit date = %v, want %v", info.ModTime(), jan)
+	}
+}
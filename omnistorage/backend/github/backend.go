@@ -7,6 +7,7 @@ import (
 	"io"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/go-github/v84/github"
 	gherrors "github.com/grokify/gogithub/errors"
@@ -26,10 +27,23 @@ func init() {
 
 // Backend implements omnistorage.ExtendedBackend for GitHub repositories.
 type Backend struct {
-	client *github.Client
-	config Config
-	closed bool
-	mu     sync.RWMutex
+	client   *github.Client
+	config   Config
+	tree     treeCache
+	modTimes modTimeCache
+	sha1s    contentSHA1Cache
+	lfsAttrs lfsPatternCache
+	batcher  autoBatcher
+	closed   bool
+	mu       sync.RWMutex
+
+	// slots bounds concurrent batch requests, see acquire
+	slots     chan struct{}
+	slotsOnce sync.Once
+
+	// pausedUntil holds batch requests back after a secondary rate limit
+	pausedUntil time.Time
+	pauseMu     sync.Mutex
 }
 
 // writer is a buffered writer that commits content to GitHub on Close.
@@ -38,6 +52,10 @@ type writer struct {
 	ctx      context.Context
 	filePath string
 	buffer   *bytes.Buffer
+	result   *CommitResult
+	err      error
+	opts     writerOptions
+	done     chan struct{}
 	closed   bool
 	mu       sync.Mutex
 }
@@ -48,6 +66,20 @@ func New(cfg Config) (*Backend, error) {
 		return nil, err
 	}
 
+	client, err := newClient(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backend{
+		client: client,
+		config: cfg,
+	}, nil
+}
+
+// newClient applies connection defaults to cfg and creates an
+// authenticated GitHub client for it.
+func newClient(cfg *Config) (*github.Client, error) {
 	// Set defaults
 	if cfg.Branch == "" {
 		cfg.Branch = "main"
@@ -65,29 +97,28 @@ func New(cfg Config) (*Backend, error) {
 	)
 	tc := oauth2.NewClient(context.Background(), ts)
 
-	// Create GitHub client
-	var client *github.Client
-	var err error
+	// Revalidate Contents, Trees and Refs responses with ETags
+	if !cfg.DisableETags {
+		tc.Transport = newETagTransport(tc.Transport)
+	}
 
 	if cfg.BaseURL != "https://api.github.com/" {
 		// GitHub Enterprise
-		client, err = github.NewClient(tc).WithEnterpriseURLs(cfg.BaseURL, cfg.UploadURL)
+		client, err := github.NewClient(tc).WithEnterpriseURLs(cfg.BaseURL, cfg.UploadURL)
 		if err != nil {
 			return nil, fmt.Errorf("github: creating enterprise client: %w", err)
 		}
-	} else {
-		client = github.NewClient(tc)
+		return client, nil
 	}
 
-	return &Backend{
-		client: client,
-		config: cfg,
-	}, nil
+	return github.NewClient(tc), nil
 }
 
 // NewWriter creates a writer for the given path.
 // The content is buffered and committed to GitHub when Close() is called.
-// Each Close() creates a new commit in the repository.
+// Each Close() creates a new commit in the repository. The commit can be
+// customized with the writer options of this package, such as
+// WithCommitMessage and WithExpectedSHA.
 func (b *Backend) NewWriter(ctx context.Context, filePath string, opts ...omnistorage.WriterOption) (io.WriteCloser, error) {
 	if err := b.checkClosed(); err != nil {
 		return nil, err
@@ -105,11 +136,18 @@ func (b *Backend) NewWriter(ctx context.Context, filePath string, opts ...omnist
 		return nil, omnistorage.ErrInvalidPath
 	}
 
+	writerOpts, err := newWriterOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
 	return &writer{
 		backend:  b,
 		ctx:      ctx,
 		filePath: pathutil.Normalize(filePath),
 		buffer:   &bytes.Buffer{},
+		opts:     writerOpts,
+		done:     make(chan struct{}),
 	}, nil
 }
 
@@ -125,27 +163,48 @@ func (w *writer) Write(p []byte) (n int, err error) {
 	return w.buffer.Write(p)
 }
 
-// Close commits the buffered content to GitHub.
+// Close commits the buffered content to GitHub. In auto-batch mode, the
+// content is queued for the next batch commit instead; see Config.AutoBatch.
 func (w *writer) Close() error {
 	w.mu.Lock()
-	defer w.mu.Unlock()
-
 	if w.closed {
+		w.mu.Unlock()
 		return nil
 	}
 	w.closed = true
+	w.mu.Unlock()
+
+	// Queue the content for the next auto-batch commit, unless the write
+	// needs a commit of its own
+	if w.backend.config.AutoBatch && w.opts == (writerOptions{}) && !hasCommitOptions(w.ctx) {
+		return w.backend.batcher.add(w)
+	}
+
+	result, err := w.commit()
+	w.finish(result, err)
+	return err
+}
 
+// commit creates a commit with the buffered content.
+func (w *writer) commit() (*CommitResult, error) {
 	// Check if backend is closed
 	if err := w.backend.checkClosed(); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Check context
 	if err := w.ctx.Err(); err != nil {
-		return err
+		return nil, err
+	}
+
+	// The Contents API cannot carry a signature or set a file mode, and
+	// batches check the expected SHA against the commit they build on
+	if w.backend.config.CommitSigner != nil || w.opts.mode != "" || w.opts.expectedSHA != nil {
+		return w.commitBatch()
 	}
 
 	// Get existing file SHA if it exists (required for updates)
+	branch := w.branch()
 	var existingSHA *string
 	fileContent, _, resp, err := w.backend.client.Repositories.GetContents(
 		w.ctx,
@@ -153,42 +212,76 @@ func (w *writer) Close() error {
 		w.backend.config.Repo,
 		w.filePath,
 		&github.RepositoryContentGetOptions{
-			Ref: w.backend.config.Branch,
+			Ref: branch,
 		},
 	)
 	if err == nil && fileContent != nil {
+		// Batches keep the mode of symbolic links, which the API follows to
+		// the file they point to, and replace submodules
+		if fileContent.GetType() != "file" || fileContent.GetPath() != w.filePath {
+			return w.commitBatch()
+		}
 		sha := fileContent.GetSHA()
 		existingSHA = &sha
 	} else if resp != nil && resp.StatusCode != 404 {
 		// Only ignore 404 errors (file doesn't exist yet)
 		if errResp, ok := err.(*github.ErrorResponse); ok {
 			if errResp.Response == nil || errResp.Response.StatusCode != 404 {
-				return w.backend.translateError(err, resp)
+				return nil, w.backend.translateError(err, resp)
 			}
 		} else {
-			return w.backend.translateError(err, resp)
+			return nil, w.backend.translateError(err, resp)
 		}
 	}
 
-	// Prepare commit options
-	commitMessage := w.backend.config.FormatCommitMessage(w.filePath)
-	opts := &github.RepositoryContentFileOptions{
-		Message: &commitMessage,
-		Content: w.buffer.Bytes(),
-		Branch:  &w.backend.config.Branch,
-		SHA:     existingSHA,
+	// Upload to Git LFS if the path is tracked and uploads are enabled
+	content, err := w.backend.lfsContent(w.ctx, w.filePath, w.buffer.Bytes())
+	if err != nil {
+		return nil, err
 	}
 
-	// Set commit author if configured
-	if w.backend.config.CommitAuthor != nil {
-		opts.Author = &github.CommitAuthor{
-			Name:  &w.backend.config.CommitAuthor.Name,
-			Email: &w.backend.config.CommitAuthor.Email,
+	// Skip the commit if the content is unchanged
+	if existingSHA != nil && *existingSHA == GitBlobSHA(content) {
+		return nil, nil
+	}
+
+	// Prepare commit options
+	commitOpts, err := w.backend.resolveCommitOptions(w.commitOptions())
+	if err != nil {
+		return nil, err
+	}
+	commitMessage := w.opts.message
+	if commitMessage == "" {
+		op := ChangeCreate
+		if existingSHA != nil {
+			op = ChangeUpdate
+		}
+		commitMessage, err = w.backend.config.RenderCommitMessage(newCommitMessageData(commitOpts.date(),
+			newCommitMessageFile(op, w.filePath, int64(w.buffer.Len()), GitBlobSHA(content))))
+		if err != nil {
+			return nil, err
+		}
+	}
+	commitMessage = commitOpts.message(commitMessage)
+	if w.backend.config.CommitEngine == CommitEngineGraphQL {
+		if existingSHA != nil {
+			if err := w.checkGraphQLMode(branch); err != nil {
+				return nil, err
+			}
 		}
+		return w.commitGraphQL(branch, commitMessage, content)
+	}
+	opts := &github.RepositoryContentFileOptions{
+		Message:   &commitMessage,
+		Content:   content,
+		Branch:    &branch,
+		SHA:       existingSHA,
+		Author:    commitOpts.author(),
+		Committer: commitOpts.committer(),
 	}
 
 	// Create or update the file
-	_, resp, err = w.backend.client.Repositories.CreateFile(
+	contentResp, resp, err := w.backend.client.Repositories.CreateFile(
 		w.ctx,
 		w.backend.config.Owner,
 		w.backend.config.Repo,
@@ -196,12 +289,112 @@ func (w *writer) Close() error {
 		opts,
 	)
 	if err != nil {
-		return w.backend.translateError(err, resp)
+		return nil, w.backend.translateError(err, resp)
+	}
+
+	result := newCommitResult(&contentResp.Commit)
+	result.BlobSHAs[w.filePath] = contentResp.GetContent().GetSHA()
+
+	if parents := contentResp.Commit.Parents; len(parents) == 1 {
+		w.backend.updateTreeCache(branch, parents[0].GetSHA(), contentResp.Commit.GetSHA(), []treeChange{
+			{path: w.filePath, sha: contentResp.GetContent().GetSHA(), size: int64(len(content))},
+		})
+	}
+
+	// Seed the cache with the committed content
+	if w.backend.config.Cache != nil {
+		w.backend.config.Cache.Set(w.ctx, contentResp.GetContent().GetSHA(), content)
+	}
+
+	return result, nil
+}
+
+// branch returns the branch the writer commits to.
+func (w *writer) branch() string {
+	if w.opts.branch != "" {
+		return w.opts.branch
+	}
+	return w.backend.config.Branch
+}
+
+// commitOptions returns the commit options of the writer's context, with
+// the author set by WithCommitAuthor.
+func (w *writer) commitOptions() CommitOptions {
+	opts := contextCommitOptions(w.ctx)
+	if w.opts.author != nil {
+		opts.Author = w.opts.author
+	}
+	return opts
+}
+
+// commitBatch commits the content as a single-file batch.
+func (w *writer) commitBatch() (*CommitResult, error) {
+	batch, err := w.backend.NewBatch(w.ctx, w.opts.message)
+	if err != nil {
+		return nil, err
+	}
+	batch.branch = w.branch()
+	if w.opts.expectedSHA != nil {
+		batch.expected = map[string]string{w.filePath: *w.opts.expectedSHA}
 	}
+	if err := batch.SetCommitOptions(w.commitOptions()); err != nil {
+		return nil, err
+	}
+	if err := batch.write(w.filePath, w.buffer.Bytes(), w.opts.mode); err != nil {
+		return nil, err
+	}
+	if _, err := batch.Commit(); err != nil {
+		return nil, err
+	}
+	return batch.CommitResult(), nil
+}
 
+// checkGraphQLMode fails if the existing file is executable, since the
+// GraphQL engine would commit it as a regular file.
+func (w *writer) checkGraphQLMode(branch string) error {
+	entry, err := w.backend.lookupTreeEntry(w.ctx, branch, w.filePath)
+	if err != nil {
+		return err
+	}
+	if mode := FileMode(entry.GetMode()); mode != "" && mode != FileModeRegular {
+		return fmt.Errorf("%w: keeping file mode %s with the GraphQL commit engine", omnistorage.ErrNotSupported, mode)
+	}
 	return nil
 }
 
+// commitGraphQL commits the content with the GraphQL engine.
+func (w *writer) commitGraphQL(branch, message string, content []byte) (*CommitResult, error) {
+	ref, resp, err := w.backend.client.Git.GetRef(w.ctx, w.backend.config.Owner, w.backend.config.Repo, "heads/"+branch)
+	if err != nil {
+		return nil, w.backend.translateError(err, resp)
+	}
+	headSHA := ref.GetObject().GetSHA()
+
+	sha := GitBlobSHA(content)
+	commit, err := w.backend.commitOnBranch(w.ctx, branch, headSHA, message, []*github.TreeEntry{{
+		Path:    github.Ptr(w.filePath),
+		SHA:     github.Ptr(sha),
+		Content: github.Ptr(string(content)),
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	result := newCommitResult(commit)
+	result.BlobSHAs[w.filePath] = sha
+
+	w.backend.updateTreeCache(branch, headSHA, commit.GetSHA(), []treeChange{
+		{path: w.filePath, sha: sha, size: int64(len(content))},
+	})
+
+	// Seed the cache with the committed content
+	if w.backend.config.Cache != nil {
+		w.backend.config.Cache.Set(w.ctx, sha, content)
+	}
+
+	return result, nil
+}
+
 // NewReader creates a reader for the given path.
 // Uses GitHub Contents API: GET /repos/{owner}/{repo}/contents/{path}?ref={branch}
 func (b *Backend) NewReader(ctx context.Context, filePath string, opts ...omnistorage.ReaderOption) (io.ReadCloser, error) {
@@ -219,6 +412,57 @@ func (b *Backend) NewReader(ctx context.Context, filePath string, opts ...omnist
 
 	normalPath := pathutil.Normalize(filePath)
 
+	// Get file content from GitHub or the cache
+	data, err := b.readContent(ctx, filePath, normalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply reader options
+	cfg := omnistorage.ApplyReaderOptions(opts...)
+
+	// Resolve Git LFS pointers to the real content
+	if pointer, ok := b.detectLFSPointer(data); ok {
+		return b.newLFSReader(ctx, pointer, cfg)
+	}
+
+	// Handle offset
+	if cfg.Offset > 0 {
+		if cfg.Offset >= int64(len(data)) {
+			data = []byte{}
+		} else {
+			data = data[cfg.Offset:]
+		}
+	}
+
+	// Handle limit
+	if cfg.Limit > 0 && int64(len(data)) > cfg.Limit {
+		data = data[:cfg.Limit]
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// readContent returns the content of a file. With a Cache configured, the
+// blob SHA is looked up in the tree and the content is only downloaded on a
+// cache miss.
+func (b *Backend) readContent(ctx context.Context, filePath, normalPath string) ([]byte, error) {
+	if b.config.Cache != nil {
+		entry, err := b.lookupEntry(ctx, normalPath)
+		if err != nil {
+			return nil, err
+		}
+		switch entry.typ {
+		case "blob":
+			return b.readBlob(ctx, entry.sha)
+		case "tree":
+			return nil, fmt.Errorf("github: path is a directory: %s", filePath)
+		case "commit":
+			return nil, fmt.Errorf("%w: %s", ErrSubmodule, filePath)
+		}
+		// Not in the tree, which may be out of date: ask the Contents API
+	}
+
 	// Get file content from GitHub
 	fileContent, _, resp, err := b.client.Repositories.GetContents(
 		ctx,
@@ -233,8 +477,26 @@ func (b *Backend) NewReader(ctx context.Context, filePath string, opts ...omnist
 		return nil, b.translateError(err, resp)
 	}
 
-	// Check if it's a file (not a directory)
-	if fileContent == nil || fileContent.GetType() != "file" {
+	// Check if it's a file (not a directory). Symbolic links read as
+	// their target path, like their blob in Git.
+	switch {
+	case fileContent == nil:
+		return nil, fmt.Errorf("github: path is a directory: %s", filePath)
+	case fileContent.GetType() == "symlink":
+		return []byte(fileContent.GetTarget()), nil
+	case fileContent.GetPath() != normalPath:
+		// A symbolic link to a file, which the API followed
+		entry, err := b.lookupTreeEntry(ctx, b.config.Branch, normalPath)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return nil, fmt.Errorf("%w: %s", omnistorage.ErrNotFound, filePath)
+		}
+		return b.readBlob(ctx, entry.GetSHA())
+	case fileContent.GetType() == "submodule":
+		return nil, fmt.Errorf("%w: %s", ErrSubmodule, filePath)
+	case fileContent.GetType() != "file":
 		return nil, fmt.Errorf("github: path is a directory: %s", filePath)
 	}
 
@@ -244,26 +506,44 @@ func (b *Backend) NewReader(ctx context.Context, filePath string, opts ...omnist
 		return nil, fmt.Errorf("github: decoding content: %w", err)
 	}
 
-	data := []byte(content)
+	return []byte(content), nil
+}
 
-	// Apply reader options
-	cfg := omnistorage.ApplyReaderOptions(opts...)
+// lookupEntry returns the tree entry of a path on the branch, from the
+// tree cache if possible, or an empty entry if it is not found.
+func (b *Backend) lookupEntry(ctx context.Context, normalPath string) (treeCacheEntry, error) {
+	snapshot, err := b.treeSnapshot(ctx)
+	if err != nil {
+		return treeCacheEntry{}, err
+	}
+	if snapshot != nil {
+		return snapshot.entries[normalPath], nil
+	}
 
-	// Handle offset
-	if cfg.Offset > 0 {
-		if cfg.Offset >= int64(len(data)) {
-			data = []byte{}
-		} else {
-			data = data[cfg.Offset:]
+	entry, err := b.lookupTreeEntry(ctx, b.config.Branch, normalPath)
+	if err != nil || entry == nil {
+		return treeCacheEntry{}, err
+	}
+	return treeCacheEntry{typ: entry.GetType(), mode: FileMode(entry.GetMode()), sha: entry.GetSHA()}, nil
+}
+
+// readBlob returns the content of a blob, from the cache if possible.
+func (b *Backend) readBlob(ctx context.Context, sha string) ([]byte, error) {
+	if b.config.Cache != nil {
+		if data, ok := b.config.Cache.Get(ctx, sha); ok {
+			return data, nil
 		}
 	}
 
-	// Handle limit
-	if cfg.Limit > 0 && int64(len(data)) > cfg.Limit {
-		data = data[:cfg.Limit]
+	data, resp, err := b.client.Git.GetBlobRaw(ctx, b.config.Owner, b.config.Repo, sha)
+	if err != nil {
+		return nil, b.translateError(err, resp)
 	}
 
-	return io.NopCloser(bytes.NewReader(data)), nil
+	if b.config.Cache != nil {
+		b.config.Cache.Set(ctx, sha, data)
+	}
+	return data, nil
 }
 
 // Exists checks if a path exists.
@@ -282,6 +562,15 @@ func (b *Backend) Exists(ctx context.Context, filePath string) (bool, error) {
 
 	normalPath := pathutil.Normalize(filePath)
 
+	snapshot, err := b.treeSnapshot(ctx)
+	if err != nil {
+		return false, err
+	}
+	if snapshot != nil {
+		_, ok := snapshot.entries[normalPath]
+		return ok, nil
+	}
+
 	_, _, resp, err := b.client.Repositories.GetContents(
 		ctx,
 		b.config.Owner,
@@ -360,29 +649,45 @@ func (b *Backend) Delete(ctx context.Context, filePath string) error {
 	}
 
 	// Prepare delete options
-	commitMessage := fmt.Sprintf("Delete %s via omnistorage", normalPath)
-	if b.config.CommitMessage != "" {
-		commitMessage = strings.ReplaceAll(b.config.CommitMessage, "{path}", normalPath)
-		commitMessage = strings.ReplaceAll(commitMessage, "Update", "Delete")
+	commitOpts, err := b.commitOptions(ctx)
+	if err != nil {
+		return err
 	}
+	commitMessage, err := b.config.RenderCommitMessage(newCommitMessageData(commitOpts.date(),
+		newCommitMessageFile(ChangeDelete, normalPath, 0, fileContent.GetSHA())))
+	if err != nil {
+		return err
+	}
+	commitMessage = commitOpts.message(commitMessage)
 
-	sha := fileContent.GetSHA()
-	opts := &github.RepositoryContentFileOptions{
-		Message: &commitMessage,
-		SHA:     &sha,
-		Branch:  &b.config.Branch,
+	if b.config.CommitEngine == CommitEngineGraphQL {
+		return b.deleteGraphQL(ctx, normalPath, commitMessage)
 	}
 
-	// Set commit author if configured
-	if b.config.CommitAuthor != nil {
-		opts.Author = &github.CommitAuthor{
-			Name:  &b.config.CommitAuthor.Name,
-			Email: &b.config.CommitAuthor.Email,
+	// The Contents API cannot carry a signature
+	if b.config.CommitSigner != nil {
+		batch, err := b.NewBatch(ctx, commitMessage)
+		if err != nil {
+			return err
+		}
+		if err := batch.Delete(normalPath); err != nil {
+			return err
 		}
+		_, err = batch.Commit()
+		return err
+	}
+
+	sha := fileContent.GetSHA()
+	opts := &github.RepositoryContentFileOptions{
+		Message:   &commitMessage,
+		SHA:       &sha,
+		Branch:    &b.config.Branch,
+		Author:    commitOpts.author(),
+		Committer: commitOpts.committer(),
 	}
 
 	// Delete the file
-	_, resp, err = b.client.Repositories.DeleteFile(
+	deleteResp, resp, err := b.client.Repositories.DeleteFile(
 		ctx,
 		b.config.Owner,
 		b.config.Repo,
@@ -393,6 +698,27 @@ func (b *Backend) Delete(ctx context.Context, filePath string) error {
 		return b.translateError(err, resp)
 	}
 
+	if parents := deleteResp.Commit.Parents; len(parents) == 1 {
+		b.updateTreeCache(b.config.Branch, parents[0].GetSHA(), deleteResp.Commit.GetSHA(), []treeChange{{path: normalPath}})
+	}
+
+	return nil
+}
+
+// deleteGraphQL deletes a file with the GraphQL engine.
+func (b *Backend) deleteGraphQL(ctx context.Context, normalPath, message string) error {
+	ref, resp, err := b.client.Git.GetRef(ctx, b.config.Owner, b.config.Repo, "heads/"+b.config.Branch)
+	if err != nil {
+		return b.translateError(err, resp)
+	}
+	headSHA := ref.GetObject().GetSHA()
+
+	commit, err := b.commitOnBranch(ctx, b.config.Branch, headSHA, message, []*github.TreeEntry{{Path: github.Ptr(normalPath)}})
+	if err != nil {
+		return err
+	}
+
+	b.updateTreeCache(b.config.Branch, headSHA, commit.GetSHA(), []treeChange{{path: normalPath}})
 	return nil
 }
 
@@ -409,6 +735,14 @@ func (b *Backend) List(ctx context.Context, prefix string) ([]string, error) {
 
 	normalPrefix := pathutil.Normalize(prefix)
 
+	snapshot, err := b.treeSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot != nil {
+		return snapshot.list(normalPrefix), nil
+	}
+
 	// Get the tree recursively
 	tree, resp, err := b.client.Git.GetTree(
 		ctx,
@@ -450,14 +784,27 @@ func (b *Backend) List(ctx context.Context, prefix string) ([]string, error) {
 }
 
 // Close releases any resources held by the backend.
+// Writes queued in auto-batch mode are committed first.
 func (b *Backend) Close() error {
+	err := b.Flush(context.Background())
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	b.closed = true
-	return nil
+	return err
 }
 
-// Stat returns metadata about an object.
+// Stat returns metadata about an object. Files and submodules report
+// their Git mode under MetadataFileMode, symbolic links their target under
+// MetadataSymlinkTarget, and submodules their commit under
+// MetadataSubmoduleCommit. Without the tree cache, the mode of regular
+// files and executables costs a second request, and is only reported with
+// Config.StatFileMode. With Config.StatModTime, ModTime is the time of the
+// last commit that changed the path.
+//
+// Files report their Git blob object ID under HashGitBlob. Git does not
+// store the SHA-1 of their content, so HashSHA1 is only reported with
+// Config.StatContentSHA1 or when the API returned the content.
 func (b *Backend) Stat(ctx context.Context, filePath string) (omnistorage.ObjectInfo, error) {
 	if err := b.checkClosed(); err != nil {
 		return nil, err
@@ -473,6 +820,42 @@ func (b *Backend) Stat(ctx context.Context, filePath string) (omnistorage.Object
 
 	normalPath := pathutil.Normalize(filePath)
 
+	info, err := b.stat(ctx, normalPath)
+	if err != nil {
+		return nil, err
+	}
+	if b.config.StatContentSHA1 {
+		if err := b.setContentSHA1(ctx, info); err != nil {
+			return nil, err
+		}
+	}
+	if b.config.StatModTime && !info.IsDir() {
+		if err := b.setModTime(ctx, info); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// stat returns metadata about an object, without its ModTime.
+func (b *Backend) stat(ctx context.Context, normalPath string) (*omnistorage.BasicObjectInfo, error) {
+	snapshot, err := b.treeSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var entry treeCacheEntry
+	if snapshot != nil {
+		if info, ok, err := snapshot.stat(normalPath); ok {
+			return info, err
+		}
+		entry = snapshot.entries[normalPath]
+	}
+	return b.statContents(ctx, normalPath, entry)
+}
+
+// statContents returns metadata about an object from the Contents API.
+// entry is its tree entry, if known.
+func (b *Backend) statContents(ctx context.Context, normalPath string, entry treeCacheEntry) (*omnistorage.BasicObjectInfo, error) {
 	fileContent, dirContents, resp, err := b.client.Repositories.GetContents(
 		ctx,
 		b.config.Owner,
@@ -495,15 +878,48 @@ func (b *Backend) Stat(ctx context.Context, filePath string) (omnistorage.Object
 		}, nil
 	}
 
-	// It's a file
-	return &omnistorage.BasicObjectInfo{
-		ObjectPath:  normalPath,
-		ObjectSize:  int64(fileContent.GetSize()),
-		ObjectIsDir: false,
-		ObjectHashes: map[omnistorage.HashType]string{
-			omnistorage.HashSHA1: fileContent.GetSHA(),
-		},
-	}, nil
+	switch fileContent.GetType() {
+	case "submodule":
+		return submoduleInfo(normalPath, fileContent.GetSHA()), nil
+	case "symlink":
+		return symlinkInfo(normalPath, fileContent.GetSHA(), fileContent.GetTarget()), nil
+	}
+
+	// It's a file. The Contents API does not report its mode, and follows
+	// symbolic links to files, reporting the path of the target. Look the
+	// path up in the tree in that case, or if the mode is wanted.
+	if entry.typ == "" && (fileContent.GetPath() != normalPath || b.config.StatFileMode) {
+		treeEntry, err := b.lookupTreeEntry(ctx, b.config.Branch, normalPath)
+		if err != nil {
+			return nil, err
+		}
+		entry = treeCacheEntry{typ: treeEntry.GetType(), mode: FileMode(treeEntry.GetMode()), sha: treeEntry.GetSHA()}
+	}
+	mode := entry.mode
+	if mode == FileModeSymlink {
+		target, resp, err := b.client.Git.GetBlobRaw(ctx, b.config.Owner, b.config.Repo, entry.sha)
+		if err != nil {
+			return nil, b.translateError(err, resp)
+		}
+		return symlinkInfo(normalPath, entry.sha, string(target)), nil
+	}
+	info := fileInfo(normalPath, int64(fileContent.GetSize()), fileContent.GetSHA(), mode)
+
+	// Report the real size and SHA-256 of Git LFS objects, and the SHA-1
+	// of other content the API returned
+	content, err := fileContent.GetContent()
+	if err != nil || content == "" && fileContent.GetSize() > 0 {
+		return info, nil
+	}
+	if pointer, ok := b.detectLFSPointer([]byte(content)); ok {
+		info.ObjectSize = pointer.Size
+		info.ObjectHashes[omnistorage.HashSHA256] = pointer.OID
+		return info, nil
+	}
+	info.ObjectHashes[omnistorage.HashSHA1] = ContentSHA1([]byte(content))
+	b.sha1s.set(fileContent.GetSHA(), info.ObjectHashes[omnistorage.HashSHA1])
+
+	return info, nil
 }
 
 // Mkdir returns ErrNotSupported (read-only backend).
@@ -538,15 +954,21 @@ func (b *Backend) Move(ctx context.Context, src, dst string) error {
 	return omnistorage.ErrNotSupported
 }
 
-// Features returns the capabilities of the GitHub backend.
+// Features returns the capabilities of the GitHub backend. HashSHA1 is
+// only listed with Config.StatContentSHA1.
 func (b *Backend) Features() omnistorage.Features {
+	hashes := []omnistorage.HashType{HashGitBlob}
+	if b.config.StatContentSHA1 {
+		hashes = append(hashes, omnistorage.HashSHA1)
+	}
+
 	return omnistorage.Features{
 		Copy:                 false,
 		Move:                 false,
 		Mkdir:                false,
 		Rmdir:                false,
 		Stat:                 true,
-		Hashes:               []omnistorage.HashType{omnistorage.HashSHA1},
+		Hashes:               hashes,
 		CanStream:            false, // Must buffer entire file
 		ServerSideEncryption: false,
 		Versioning:           true, // Git provides versioning via commits
@@ -567,6 +989,12 @@ func (b *Backend) checkClosed() error {
 
 // translateError converts GitHub API errors to omnistorage errors.
 func (b *Backend) translateError(err error, resp *github.Response) error {
+	return translateAPIError(err, resp)
+}
+
+// translateAPIError converts GitHub API errors to omnistorage errors.
+// It is shared by all backends in this package.
+func translateAPIError(err error, resp *github.Response) error {
 	if err == nil {
 		return nil
 	}
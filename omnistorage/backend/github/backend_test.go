@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/grokify/gogithub/pathutil"
+	"github.com/plexusone/omni-github/omnistorage/backend/github/githubtest"
 	omnistorage "github.com/plexusone/omnistorage-core/object"
 )
 
@@ -21,10 +22,47 @@ func skipIfNoToken(t *testing.T) {
 	}
 }
 
+// fakeBackend creates a backend against an in-memory fake of
+// grokify/omnistorage, seeded with the files the tests expect.
+func fakeBackend(t *testing.T) *Backend {
+	t.Helper()
+	backend, _ := fakeServerBackend(t)
+	return backend
+}
+
+// fakeServerBackend is like fakeBackend but also returns the fake server.
+func fakeServerBackend(t *testing.T) (*Backend, *githubtest.Server) {
+	t.Helper()
+
+	srv := githubtest.NewServer("grokify", "omnistorage")
+	t.Cleanup(srv.Close)
+	srv.CommitFiles(githubtest.DefaultBranch, "Seed test repository", map[string][]byte{
+		"README.md":             []byte("# Omnistorage\n\nA unified storage abstraction for Go.\n"),
+		"backend/file/file.go":  []byte("package file\n"),
+		"backend/memory/mem.go": []byte("package memory\n"),
+	})
+
+	backend, err := New(Config{
+		Owner:     "grokify",
+		Repo:      "omnistorage",
+		Branch:    githubtest.DefaultBranch,
+		Token:     "token",
+		BaseURL:   srv.BaseURL(),
+		UploadURL: srv.BaseURL(),
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return backend, srv
+}
+
 // testBackend creates a backend for testing against grokify/omnistorage.
+// Falls back to an in-memory fake if GITHUB_TOKEN is not set.
 func testBackend(t *testing.T) *Backend {
 	t.Helper()
-	skipIfNoToken(t)
+	if os.Getenv("GITHUB_TOKEN") == "" {
+		return fakeBackend(t)
+	}
 
 	backend, err := New(Config{
 		Owner:  "grokify",
@@ -219,10 +257,10 @@ func TestStat(t *testing.T) {
 		t.Error("Expected IsDir to be false")
 	}
 
-	// Check that SHA1 hash is present
-	sha := info.Hash(omnistorage.HashSHA1)
+	// Check that the blob hash is present
+	sha := info.Hash(HashGitBlob)
 	if sha == "" {
-		t.Error("Expected SHA1 hash to be present")
+		t.Error("Expected blob hash to be present")
 	}
 }
 
@@ -254,21 +292,16 @@ func TestStatNotFound(t *testing.T) {
 	}
 }
 
-// skipIfNoWriteRepo skips the test if write testing is not enabled.
-// Write tests modify the repository, so they require explicit opt-in.
-func skipIfNoWriteRepo(t *testing.T) {
-	t.Helper()
-	if os.Getenv("OMNISTORAGE_GITHUB_TEST_WRITE") != "true" {
-		t.Skip("OMNISTORAGE_GITHUB_TEST_WRITE not set to 'true', skipping write test")
-	}
-}
-
 // writeTestBackend creates a backend for write testing.
 // Uses OMNISTORAGE_GITHUB_TEST_WRITE_REPO or falls back to the read test repo.
+// Write tests modify the repository, so they require explicit opt-in with
+// OMNISTORAGE_GITHUB_TEST_WRITE=true and otherwise run against a fake.
 func writeTestBackend(t *testing.T) *Backend {
 	t.Helper()
+	if os.Getenv("OMNISTORAGE_GITHUB_TEST_WRITE") != "true" {
+		return fakeBackend(t)
+	}
 	skipIfNoToken(t)
-	skipIfNoWriteRepo(t)
 
 	repo := os.Getenv("OMNISTORAGE_GITHUB_TEST_WRITE_REPO")
 	if repo == "" {
@@ -311,6 +344,14 @@ func TestNewWriter(t *testing.T) {
 		t.Fatalf("Close failed: %v", err)
 	}
 
+	// The writer exposes the created commit
+	result := w.(CommitResultProvider).CommitResult()
+	if result == nil || result.CommitSHA == "" || result.HTMLURL == "" {
+		t.Errorf("Expected commit result after Close, got %+v", result)
+	} else if result.BlobSHA(testPath) == "" {
+		t.Errorf("Expected blob SHA for %s", testPath)
+	}
+
 	// Verify the file was created
 	r, err := backend.NewReader(ctx, testPath)
 	if err != nil {
@@ -561,8 +602,15 @@ func TestFeatures(t *testing.T) {
 	if !features.ListPrefix {
 		t.Error("Expected ListPrefix to be true")
 	}
-	if !features.SupportsHash(omnistorage.HashSHA1) {
-		t.Error("Expected SHA1 hash to be supported")
+	if !features.SupportsHash(HashGitBlob) {
+		t.Error("Expected blob hash to be supported")
+	}
+	if features.SupportsHash(omnistorage.HashSHA1) {
+		t.Error("Expected SHA1 hash to need StatContentSHA1")
+	}
+	backend.config.StatContentSHA1 = true
+	if !backend.Features().SupportsHash(omnistorage.HashSHA1) {
+		t.Error("Expected SHA1 hash to be supported with StatContentSHA1")
 	}
 }
 
@@ -614,6 +662,7 @@ func TestConfigFromMap(t *testing.T) {
 		"token":      "test-token",
 		"base_url":   "https://github.example.com/api/v3/",
 		"upload_url": "https://github.example.com/uploads/",
+		"gist_id":    "aa5a315d61ae9438b18d",
 	}
 
 	cfg := ConfigFromMap(m)
@@ -636,6 +685,9 @@ func TestConfigFromMap(t *testing.T) {
 	if cfg.UploadURL != "https://github.example.com/uploads/" {
 		t.Errorf("UploadURL = %q, want %q", cfg.UploadURL, "https://github.example.com/uploads/")
 	}
+	if cfg.GistID != "aa5a315d61ae9438b18d" {
+		t.Errorf("GistID = %q, want %q", cfg.GistID, "aa5a315d61ae9438b18d")
+	}
 }
 
 func TestConfigFromMapDefaults(t *testing.T) {
@@ -772,9 +824,19 @@ func TestBatchWrite(t *testing.T) {
 	}
 
 	// Commit the batch
-	if err := batch.Commit(); err != nil {
+	sha, err := batch.Commit()
+	if err != nil {
 		t.Fatalf("batch.Commit failed: %v", err)
 	}
+	if len(sha) != 40 {
+		t.Errorf("batch.Commit() SHA = %q, want 40 hex characters", sha)
+	}
+	result := batch.CommitResult()
+	if result == nil || result.CommitSHA != sha || result.TreeSHA == "" {
+		t.Errorf("Expected commit result for %s, got %+v", sha, result)
+	} else if len(result.BlobSHAs) != 2 {
+		t.Errorf("Expected 2 blob SHAs, got %d", len(result.BlobSHAs))
+	}
 
 	// Verify both files were created
 	r1, err := backend.NewReader(ctx, testPath1)
@@ -836,7 +898,7 @@ func TestBatchWriteAndDelete(t *testing.T) {
 	}
 
 	// Commit
-	if err := batch.Commit(); err != nil {
+	if _, err := batch.Commit(); err != nil {
 		t.Fatalf("batch.Commit failed: %v", err)
 	}
 
@@ -875,9 +937,85 @@ func TestBatchEmptyCommit(t *testing.T) {
 	}
 
 	// Commit should succeed with no operations
-	if err := batch.Commit(); err != nil {
+	sha, err := batch.Commit()
+	if err != nil {
 		t.Errorf("Empty batch.Commit should not fail: %v", err)
 	}
+	if sha != "" {
+		t.Errorf("Empty batch.Commit() SHA = %q, want empty", sha)
+	}
+}
+
+func TestBatchUnchangedCommit(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	ctx := context.Background()
+	head := srv.Head(githubtest.DefaultBranch)
+	readme, _ := srv.File(head, "README.md")
+
+	// Rewriting the current content, or deleting a missing file, changes nothing
+	batch, err := backend.NewBatch(ctx, "Unchanged batch")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	_ = batch.Write("README.md", readme)
+	_ = batch.Delete("nonexistent-file.txt")
+
+	sha, err := batch.Commit()
+	if err != nil {
+		t.Fatalf("batch.Commit failed: %v", err)
+	}
+	if sha != "" || batch.CommitResult() != nil {
+		t.Errorf("Unchanged batch.Commit() SHA = %q, want empty", sha)
+	}
+	if got := srv.Head(githubtest.DefaultBranch); got != head {
+		t.Errorf("Expected the branch not to move, got %s", got)
+	}
+	for _, req := range srv.Requests() {
+		if strings.Contains(req, "/git/blobs") {
+			t.Errorf("Expected no blob upload for unchanged content, got %s", req)
+		}
+	}
+
+	// Only the changed file gets a new blob
+	srv.ResetRequests()
+	batch, _ = backend.NewBatch(ctx, "Partly changed batch")
+	_ = batch.Write("README.md", readme)
+	_ = batch.Write("new.txt", []byte("new"))
+	if sha, err := batch.Commit(); err != nil || sha == "" {
+		t.Fatalf("batch.Commit = %q, %v", sha, err)
+	}
+	blobs := 0
+	for _, req := range srv.Requests() {
+		if strings.HasPrefix(req, "POST") && strings.Contains(req, "/git/blobs") {
+			blobs++
+		}
+	}
+	if blobs != 1 {
+		t.Errorf("Expected 1 blob upload, got %d", blobs)
+	}
+}
+
+func TestWriteUnchanged(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	ctx := context.Background()
+	head := srv.Head(githubtest.DefaultBranch)
+	readme, _ := srv.File(head, "README.md")
+
+	w, err := backend.NewWriter(ctx, "README.md")
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	_, _ = w.Write(readme)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if result := w.(CommitResultProvider).CommitResult(); result != nil {
+		t.Errorf("Expected no commit for unchanged content, got %+v", result)
+	}
+	if got := srv.Head(githubtest.DefaultBranch); got != head {
+		t.Errorf("Expected the branch not to move, got %s", got)
+	}
 }
 
 func TestBatchDoubleCommit(t *testing.T) {
@@ -892,12 +1030,12 @@ func TestBatchDoubleCommit(t *testing.T) {
 	}
 
 	// First commit should succeed
-	if err := batch.Commit(); err != nil {
+	if _, err := batch.Commit(); err != nil {
 		t.Fatalf("First Commit failed: %v", err)
 	}
 
 	// Second commit should fail
-	if err := batch.Commit(); err == nil {
+	if _, err := batch.Commit(); err == nil {
 		t.Error("Expected error on second Commit")
 	}
 }
@@ -913,7 +1051,7 @@ func TestBatchWriteAfterCommit(t *testing.T) {
 		t.Fatalf("NewBatch failed: %v", err)
 	}
 
-	if err := batch.Commit(); err != nil {
+	if _, err := batch.Commit(); err != nil {
 		t.Fatalf("Commit failed: %v", err)
 	}
 
@@ -934,7 +1072,7 @@ func TestBatchDeleteAfterCommit(t *testing.T) {
 		t.Fatalf("NewBatch failed: %v", err)
 	}
 
-	if err := batch.Commit(); err != nil {
+	if _, err := batch.Commit(); err != nil {
 		t.Fatalf("Commit failed: %v", err)
 	}
 
@@ -1006,7 +1144,7 @@ func TestBatchDeleteNonExistent(t *testing.T) {
 	}
 
 	// Commit should succeed - the non-existent delete is just skipped
-	if err := batch.Commit(); err != nil {
+	if _, err := batch.Commit(); err != nil {
 		t.Fatalf("batch.Commit failed: %v", err)
 	}
 
@@ -1037,3 +1175,185 @@ func TestNewBatchAfterClose(t *testing.T) {
 		t.Errorf("Expected ErrBackendClosed, got: %v", err)
 	}
 }
+
+func TestBatchCommitWithTag(t *testing.T) {
+	backend := writeTestBackend(t)
+	defer func() { _ = backend.Close() }()
+
+	ctx := context.Background()
+	timestamp := time.Now().UnixNano()
+	testPath := fmt.Sprintf("test/batch-tag-%d.txt", timestamp)
+	tagName := fmt.Sprintf("omnistorage-test-%d", timestamp)
+
+	batch, err := backend.NewBatch(ctx, "Batch tag test")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	if err := batch.Write(testPath, []byte("tagged content")); err != nil {
+		t.Fatalf("batch.Write failed: %v", err)
+	}
+	if err := batch.Tag(TagOptions{Name: tagName, Message: "Annotated test tag"}); err != nil {
+		t.Fatalf("batch.Tag failed: %v", err)
+	}
+
+	sha, err := batch.Commit()
+	if err != nil {
+		t.Fatalf("batch.Commit failed: %v", err)
+	}
+
+	// The annotated tag should resolve to the new commit
+	ref, _, err := backend.client.Git.GetRef(ctx, backend.config.Owner, backend.config.Repo, "refs/tags/"+tagName)
+	if err != nil {
+		t.Fatalf("GetRef failed: %v", err)
+	}
+	tag, _, err := backend.client.Git.GetTag(ctx, backend.config.Owner, backend.config.Repo, ref.GetObject().GetSHA())
+	if err != nil {
+		t.Fatalf("GetTag failed: %v", err)
+	}
+	if tag.GetObject().GetSHA() != sha {
+		t.Errorf("Tag points at %q, want %q", tag.GetObject().GetSHA(), sha)
+	}
+
+	// Clean up
+	_, _ = backend.client.Git.DeleteRef(ctx, backend.config.Owner, backend.config.Repo, "tags/"+tagName)
+	_ = backend.Delete(ctx, testPath)
+}
+
+func TestBatchTagValidation(t *testing.T) {
+	backend, err := New(Config{Owner: "owner", Repo: "repo", Token: "token"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer func() { _ = backend.Close() }()
+
+	batch, err := backend.NewBatch(context.Background(), "Test")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+
+	if err := batch.Tag(TagOptions{}); err != ErrTagNameRequired {
+		t.Errorf("Expected ErrTagNameRequired, got: %v", err)
+	}
+
+	// An empty batch commits nothing, so Tag is rejected afterwards
+	if _, err := batch.Commit(); err != nil {
+		t.Fatalf("Empty batch.Commit failed: %v", err)
+	}
+	if err := batch.Tag(TagOptions{Name: "v1.0.0"}); err == nil {
+		t.Error("Expected error on Tag after Commit")
+	}
+}
+
+func TestBatchTagEmpty(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	head := srv.Head("main")
+	ctx := context.Background()
+
+	batch, err := backend.NewBatch(ctx, "Empty batch")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	if err := batch.Tag(TagOptions{Name: "v1.0.0"}); err != nil {
+		t.Fatalf("batch.Tag failed: %v", err)
+	}
+
+	sha, err := batch.Commit()
+	if err != nil {
+		t.Fatalf("batch.Commit failed: %v", err)
+	}
+	if sha != head {
+		t.Errorf("Commit returned %q, want the branch head %q", sha, head)
+	}
+
+	ref, _, err := backend.client.Git.GetRef(ctx, backend.config.Owner, backend.config.Repo, "refs/tags/v1.0.0")
+	if err != nil {
+		t.Fatalf("GetRef failed: %v", err)
+	}
+	if ref.GetObject().GetSHA() != head {
+		t.Errorf("Tag points at %q, want %q", ref.GetObject().GetSHA(), head)
+	}
+}
+
+func TestBatchTagUnchanged(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	head := srv.Head("main")
+	ctx := context.Background()
+	content, _ := srv.File("main", "README.md")
+
+	batch, err := backend.NewBatch(ctx, "Unchanged batch")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	_ = batch.Write("README.md", content)
+	if err := batch.Tag(TagOptions{Name: "v1.0.1", Message: "Republished"}); err != nil {
+		t.Fatalf("batch.Tag failed: %v", err)
+	}
+
+	sha, err := batch.Commit()
+	if err != nil {
+		t.Fatalf("batch.Commit failed: %v", err)
+	}
+	if sha != head {
+		t.Errorf("Commit returned %q, want the branch head %q", sha, head)
+	}
+	if got := srv.Head("main"); got != head {
+		t.Errorf("Expected the branch not to move, got %s", got)
+	}
+
+	ref, _, err := backend.client.Git.GetRef(ctx, backend.config.Owner, backend.config.Repo, "refs/tags/v1.0.1")
+	if err != nil {
+		t.Fatalf("GetRef failed: %v", err)
+	}
+	tag, _, err := backend.client.Git.GetTag(ctx, backend.config.Owner, backend.config.Repo, ref.GetObject().GetSHA())
+	if err != nil {
+		t.Fatalf("GetTag failed: %v", err)
+	}
+	if tag.GetObject().GetSHA() != head {
+		t.Errorf("Tag points at %q, want %q", tag.GetObject().GetSHA(), head)
+	}
+}
+
+func TestBatchTagRelease(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	ctx := context.Background()
+
+	batch, err := backend.NewBatch(ctx, "Release batch")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	_ = batch.Write("dist/bundle.js", []byte("console.log(1)\n"))
+	err = batch.Tag(TagOptions{
+		Name: "v2.0.0",
+		Release: &ReleaseOptions{
+			Body:          "Highlights",
+			GenerateNotes: true,
+			Prerelease:    true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("batch.Tag failed: %v", err)
+	}
+
+	sha, err := batch.Commit()
+	if err != nil {
+		t.Fatalf("batch.Commit failed: %v", err)
+	}
+
+	releases := srv.Releases()
+	if len(releases) != 1 {
+		t.Fatalf("Expected 1 release, got %d", len(releases))
+	}
+	release := releases[0]
+	if release.GetTagName() != "v2.0.0" || release.GetTargetCommitish() != sha {
+		t.Errorf("Release for %q at %q, want %q at %q", release.GetTagName(), release.GetTargetCommitish(), "v2.0.0", sha)
+	}
+	if release.GetName() != "v2.0.0" {
+		t.Errorf("Release name = %q, want the tag name", release.GetName())
+	}
+	if release.GetBody() != "Highlights" || !release.GetGenerateReleaseNotes() {
+		t.Errorf("Release body = %q, generate notes = %v", release.GetBody(), release.GetGenerateReleaseNotes())
+	}
+	if !release.GetPrerelease() || release.GetDraft() {
+		t.Errorf("Release prerelease = %v, draft = %v", release.GetPrerelease(), release.GetDraft())
+	}
+}
@@ -2,8 +2,11 @@ package github
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/google/go-github/v84/github"
 	"github.com/grokify/gogithub/pathutil"
@@ -15,6 +18,10 @@ type BatchOperation struct {
 	Type    BatchOperationType
 	Path    string
 	Content []byte
+
+	// Mode is the Git mode of a written file. Default: the mode of the
+	// existing file, or FileModeRegular for new files.
+	Mode FileMode
 }
 
 // BatchOperationType indicates the type of batch operation.
@@ -27,6 +34,18 @@ const (
 	BatchOpDelete
 )
 
+// defaultMaxConcurrency is the number of concurrent batch requests when
+// Config.MaxConcurrency is not set.
+const defaultMaxConcurrency = 4
+
+// maxRateLimitRetries is how often a blob upload is retried after hitting
+// a secondary rate limit.
+const maxRateLimitRetries = 3
+
+// defaultRetryAfter is how long batch requests pause after a secondary
+// rate limit without a Retry-After header, as GitHub recommends.
+const defaultRetryAfter = time.Minute
+
 // Batch accumulates multiple file operations to be committed atomically.
 // Use NewBatch to create a batch, then call Write/Delete to queue operations,
 // and finally Commit to apply all changes in a single commit.
@@ -35,12 +54,19 @@ type Batch struct {
 	ctx        context.Context
 	message    string
 	operations []BatchOperation
+	tag        *TagOptions
+	options    *CommitOptions    // replaces the options of ctx if set
+	branch     string            // Config.Branch if empty
+	expected   map[string]string // blob SHAs the base tree must have
+	result     *CommitResult
 	committed  bool
 	mu         sync.Mutex
 }
 
 // NewBatch creates a new batch for accumulating file operations.
-// The message is used as the commit message when Commit is called.
+// The message is used as the commit message when Commit is called. If it
+// is empty, the message is rendered from the commit message template of
+// the changes; see Config.CommitMessageBatch.
 func (b *Backend) NewBatch(ctx context.Context, message string) (*Batch, error) {
 	if err := b.checkClosed(); err != nil {
 		return nil, err
@@ -50,10 +76,6 @@ func (b *Backend) NewBatch(ctx context.Context, message string) (*Batch, error)
 		return nil, err
 	}
 
-	if message == "" {
-		message = "Batch update via omnistorage"
-	}
-
 	return &Batch{
 		backend:    b,
 		ctx:        ctx,
@@ -65,6 +87,21 @@ func (b *Backend) NewBatch(ctx context.Context, message string) (*Batch, error)
 // Write queues a file write operation.
 // The file will be created or updated when Commit is called.
 func (batch *Batch) Write(filePath string, content []byte) error {
+	return batch.write(filePath, content, "")
+}
+
+// WriteMode queues a file write operation that sets the Git mode of the
+// file, such as FileModeExecutable or FileModeSymlink. The content of a
+// symbolic link is its target path.
+func (batch *Batch) WriteMode(filePath string, content []byte, mode FileMode) error {
+	if err := checkFileMode(mode); err != nil {
+		return err
+	}
+	return batch.write(filePath, content, mode)
+}
+
+// write queues a file write operation with a file mode.
+func (batch *Batch) write(filePath string, content []byte, mode FileMode) error {
 	batch.mu.Lock()
 	defer batch.mu.Unlock()
 
@@ -84,6 +121,7 @@ func (batch *Batch) Write(filePath string, content []byte) error {
 		Type:    BatchOpWrite,
 		Path:    pathutil.Normalize(filePath),
 		Content: content,
+		Mode:    mode,
 	})
 
 	return nil
@@ -129,29 +167,50 @@ func (batch *Batch) Len() int {
 // The process:
 // 1. Get the current commit SHA from the branch reference
 // 2. Get the current tree SHA from that commit
-// 3. Create blobs for all new/updated file contents
+// 3. Create blobs for new and changed file contents, skipping the rest
 // 4. Create a new tree with the changes
 // 5. Create a new commit pointing to the new tree
 // 6. Update the branch reference to the new commit
-func (batch *Batch) Commit() error {
+// 7. Create the tag and release, if requested via Tag
+//
+// It returns the SHA of the new commit. If the batch is empty or changes
+// nothing, no commit is created: with a Tag request the branch head is
+// tagged and its SHA returned, otherwise Commit returns "".
+// Use CommitResult for the tree SHA, blob SHAs and URL of the commit.
+// If tagging fails after the branch was updated, the commit SHA is
+// returned together with the error.
+func (batch *Batch) Commit() (string, error) {
 	batch.mu.Lock()
 	defer batch.mu.Unlock()
 
 	if batch.committed {
-		return fmt.Errorf("github: batch already committed")
+		return "", fmt.Errorf("github: batch already committed")
 	}
 
 	if err := batch.backend.checkClosed(); err != nil {
-		return err
+		return "", err
 	}
 
 	if err := batch.ctx.Err(); err != nil {
-		return err
+		return "", err
 	}
 
-	if len(batch.operations) == 0 {
+	if len(batch.operations) == 0 && batch.tag == nil {
 		batch.committed = true
-		return nil // Nothing to commit
+		return "", nil // Nothing to commit
+	}
+
+	opts, err := batch.backend.commitOptions(batch.ctx)
+	if batch.options != nil {
+		opts, err = batch.backend.resolveCommitOptions(*batch.options)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	branch := batch.branch
+	if branch == "" {
+		branch = batch.backend.config.Branch
 	}
 
 	// Step 1: Get the current branch reference
@@ -159,13 +218,16 @@ func (batch *Batch) Commit() error {
 		batch.ctx,
 		batch.backend.config.Owner,
 		batch.backend.config.Repo,
-		"refs/heads/"+batch.backend.config.Branch,
+		"refs/heads/"+branch,
 	)
 	if err != nil {
-		return batch.backend.translateError(err, resp)
+		return "", batch.backend.translateError(err, resp)
 	}
 
 	currentCommitSHA := ref.Object.GetSHA()
+	if len(batch.operations) == 0 {
+		return batch.tagUnchanged(currentCommitSHA)
+	}
 
 	// Step 2: Get the current commit to find the tree SHA
 	currentCommit, resp, err := batch.backend.client.Git.GetCommit(
@@ -175,17 +237,91 @@ func (batch *Batch) Commit() error {
 		currentCommitSHA,
 	)
 	if err != nil {
-		return batch.backend.translateError(err, resp)
+		return "", batch.backend.translateError(err, resp)
 	}
 
 	baseTreeSHA := currentCommit.Tree.GetSHA()
 
-	// Step 3: Build tree entries for all operations
-	treeEntries, err := batch.buildTreeEntries()
+	// Step 3: Build tree entries for the operations that change files
+	paths := make([]string, len(batch.operations))
+	for i, op := range batch.operations {
+		paths[i] = op.Path
+	}
+	base, err := batch.backend.baseTree(batch.ctx, currentCommitSHA, baseTreeSHA, paths)
 	if err != nil {
-		return err
+		return "", err
+	}
+	if err := batch.checkExpected(base); err != nil {
+		return "", err
+	}
+	treeEntries, err := batch.buildTreeEntries(base)
+	if err != nil {
+		return "", err
+	}
+	if len(treeEntries) == 0 {
+		return batch.tagUnchanged(currentCommitSHA) // Nothing changed
+	}
+
+	message := batch.message
+	if message == "" {
+		data := newCommitMessageData(opts.date(), batch.messageFiles(base, treeEntries)...)
+		if message, err = batch.backend.config.RenderCommitMessage(data); err != nil {
+			return "", err
+		}
+	}
+	message = opts.message(message)
+
+	// Steps 4-6: Create the commit and move the branch to it
+	var newCommit *github.Commit
+	if batch.backend.config.CommitEngine == CommitEngineGraphQL {
+		newCommit, err = batch.backend.commitOnBranch(batch.ctx, branch, currentCommitSHA, message, treeEntries)
+	} else {
+		newCommit, err = batch.createCommit(ref.GetRef(), currentCommitSHA, baseTreeSHA, message, opts, treeEntries)
+	}
+	if err != nil {
+		return "", err
+	}
+	if newCommit == nil {
+		return batch.tagUnchanged(currentCommitSHA) // Nothing changed
+	}
+	newCommitSHA := newCommit.GetSHA()
+
+	batch.committed = true
+	batch.result = newCommitResult(newCommit)
+	for _, entry := range treeEntries {
+		if entry.SHA != nil {
+			batch.result.BlobSHAs[entry.GetPath()] = entry.GetSHA()
+		}
 	}
+	batch.backend.updateTreeCache(branch, currentCommitSHA, newCommitSHA, batch.treeChanges(treeEntries))
 
+	// Step 7: Tag the new commit
+	if batch.tag != nil {
+		if err := batch.backend.createTag(batch.ctx, newCommitSHA, batch.tag); err != nil {
+			return newCommitSHA, err
+		}
+	}
+
+	return newCommitSHA, nil
+}
+
+// tagUnchanged finishes a batch that changed nothing. If a tag was
+// requested, it tags headSHA, the unchanged branch head, and returns it.
+func (batch *Batch) tagUnchanged(headSHA string) (string, error) {
+	batch.committed = true
+	if batch.tag == nil {
+		return "", nil
+	}
+	if err := batch.backend.createTag(batch.ctx, headSHA, batch.tag); err != nil {
+		return "", err
+	}
+	return headSHA, nil
+}
+
+// createCommit creates a commit of treeEntries on top of parentSHA with the
+// Git Data API and moves refName to it. It returns nil if the new tree
+// equals the base tree.
+func (batch *Batch) createCommit(refName, parentSHA, baseTreeSHA, message string, opts CommitOptions, treeEntries []*github.TreeEntry) (*github.Commit, error) {
 	// Step 4: Create the new tree
 	newTree, resp, err := batch.backend.client.Git.CreateTree(
 		batch.ctx,
@@ -195,21 +331,27 @@ func (batch *Batch) Commit() error {
 		treeEntries,
 	)
 	if err != nil {
-		return batch.backend.translateError(err, resp)
+		return nil, batch.backend.translateError(err, resp)
+	}
+	if newTree.GetSHA() == baseTreeSHA {
+		return nil, nil // Nothing changed
 	}
 
 	// Step 5: Create the new commit
 	commitOpts := github.Commit{
-		Message: github.Ptr(batch.message),
+		Message: github.Ptr(message),
 		Tree:    newTree,
-		Parents: []*github.Commit{{SHA: github.Ptr(currentCommitSHA)}},
+		Parents: []*github.Commit{{SHA: github.Ptr(parentSHA)}},
 	}
 
-	// Set commit author if configured
-	if batch.backend.config.CommitAuthor != nil {
-		commitOpts.Author = &github.CommitAuthor{
-			Name:  github.Ptr(batch.backend.config.CommitAuthor.Name),
-			Email: github.Ptr(batch.backend.config.CommitAuthor.Email),
+	// Set the commit author and committer if configured
+	commitOpts.Author = opts.author()
+	commitOpts.Committer = opts.committer()
+
+	// Sign the commit if configured
+	if batch.backend.config.CommitSigner != nil {
+		if err := batch.backend.signCommit(batch.ctx, &commitOpts); err != nil {
+			return nil, err
 		}
 	}
 
@@ -221,7 +363,7 @@ func (batch *Batch) Commit() error {
 		nil, // CreateCommitOptions
 	)
 	if err != nil {
-		return batch.backend.translateError(err, resp)
+		return nil, batch.backend.translateError(err, resp)
 	}
 
 	// Step 6: Update the branch reference
@@ -235,65 +377,372 @@ func (batch *Batch) Commit() error {
 		batch.ctx,
 		batch.backend.config.Owner,
 		batch.backend.config.Repo,
-		ref.GetRef(),
+		refName,
 		updateRef,
 	)
 	if err != nil {
-		return batch.backend.translateError(err, resp)
+		return nil, batch.backend.translateError(err, resp)
 	}
 
-	batch.committed = true
-	return nil
+	return newCommit, nil
 }
 
-// buildTreeEntries creates GitHub tree entries for all operations.
-func (batch *Batch) buildTreeEntries() ([]*github.TreeEntry, error) {
-	entries := make([]*github.TreeEntry, 0, len(batch.operations))
-
-	for _, op := range batch.operations {
-		switch op.Type {
-		case BatchOpWrite:
-			// Create a blob for the content
-			blob, resp, err := batch.backend.client.Git.CreateBlob(
+// checkExpected checks the blob SHAs expected by writes with
+// WithExpectedSHA against the base tree. The ref update of the commit
+// fails if the branch moves after this check.
+func (batch *Batch) checkExpected(base *treeSnapshot) error {
+	for filePath, expected := range batch.expected {
+		var actual string
+		if !base.truncated {
+			if entry, ok := base.entries[filePath]; ok && entry.typ == "blob" {
+				actual = entry.sha
+			}
+		} else {
+			fileContent, _, resp, err := batch.backend.client.Repositories.GetContents(
 				batch.ctx,
 				batch.backend.config.Owner,
 				batch.backend.config.Repo,
-				github.Blob{
-					Content:  github.Ptr(string(op.Content)),
-					Encoding: github.Ptr("utf-8"),
-				},
+				filePath,
+				&github.RepositoryContentGetOptions{Ref: base.commitSHA},
 			)
-			if err != nil {
-				return nil, batch.backend.translateError(err, resp)
+			if err != nil && (resp == nil || resp.StatusCode != 404) {
+				return batch.backend.translateError(err, resp)
 			}
+			actual = fileContent.GetSHA()
+		}
+		if err := checkExpectedSHA(filePath, &expected, actual); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// messageFiles describes the files changed by the committed tree entries,
+// for commit message templates.
+func (batch *Batch) messageFiles(base *treeSnapshot, treeEntries []*github.TreeEntry) []CommitMessageFile {
+	sizes := make(map[string]int64, len(batch.operations))
+	for _, op := range batch.operations {
+		sizes[op.Path] = int64(len(op.Content))
+	}
+
+	files := make([]CommitMessageFile, len(treeEntries))
+	for i, entry := range treeEntries {
+		filePath := entry.GetPath()
+		existing, exists := base.entries[filePath]
+		switch {
+		case entry.SHA == nil:
+			files[i] = newCommitMessageFile(ChangeDelete, filePath, 0, existing.sha)
+		case exists && existing.typ == "blob":
+			files[i] = newCommitMessageFile(ChangeUpdate, filePath, sizes[filePath], entry.GetSHA())
+		default:
+			files[i] = newCommitMessageFile(ChangeCreate, filePath, sizes[filePath], entry.GetSHA())
+		}
+	}
+	return files
+}
+
+// treeChanges returns the changes made by the committed tree entries, for
+// the tree cache.
+func (batch *Batch) treeChanges(treeEntries []*github.TreeEntry) []treeChange {
+	sizes := make(map[string]int64, len(batch.operations))
+	for _, op := range batch.operations {
+		sizes[op.Path] = int64(len(op.Content))
+	}
+
+	changes := make([]treeChange, 0, len(treeEntries))
+	for _, entry := range treeEntries {
+		changes = append(changes, treeChange{
+			path: entry.GetPath(),
+			mode: FileMode(entry.GetMode()),
+			sha:  entry.GetSHA(),
+			size: sizes[entry.GetPath()],
+		})
+	}
+	return changes
+}
+
+// buildTreeEntries creates GitHub tree entries for the operations that
+// change the base tree. Only the last operation on a path is applied, and
+// writes of content the base tree already has are skipped.
+//
+// Operations run concurrently, bounded by Config.MaxConcurrency across
+// the backend. Entries keep the order of the operations, and the first
+// error cancels the remaining operations.
+func (batch *Batch) buildTreeEntries(base *treeSnapshot) ([]*github.TreeEntry, error) {
+	last := make(map[string]int, len(batch.operations))
+	for i, op := range batch.operations {
+		last[op.Path] = i
+	}
+
+	ctx, cancel := context.WithCancel(batch.ctx)
+	defer cancel()
 
-			entries = append(entries, &github.TreeEntry{
-				Path: github.Ptr(op.Path),
-				Mode: github.Ptr("100644"), // Regular file
-				Type: github.Ptr("blob"),
-				SHA:  blob.SHA,
-			})
-
-		case BatchOpDelete:
-			// To delete a file, we set SHA to nil (or omit it) with the path
-			// The GitHub API interprets this as a deletion when creating a tree
-			// We need to check if the file exists first
-			exists, err := batch.backend.Exists(batch.ctx, op.Path)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	results := make([]*github.TreeEntry, len(batch.operations))
+	for i, op := range batch.operations {
+		if last[op.Path] != i {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			entry, err := batch.treeEntry(ctx, op, base)
 			if err != nil {
-				return nil, err
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+				return
 			}
-			if exists {
-				// Use a nil SHA to indicate deletion
-				entries = append(entries, &github.TreeEntry{
-					Path: github.Ptr(op.Path),
-					Mode: github.Ptr("100644"),
-					Type: github.Ptr("blob"),
-					SHA:  nil, // nil SHA means delete
-				})
+			results[i] = entry
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	entries := make([]*github.TreeEntry, 0, len(results))
+	for _, entry := range results {
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// treeEntry creates the tree entry for an operation, or returns nil if the
+// operation does not change the base tree.
+func (batch *Batch) treeEntry(ctx context.Context, op BatchOperation, base *treeSnapshot) (*github.TreeEntry, error) {
+	switch op.Type {
+	case BatchOpWrite:
+		release, err := batch.backend.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		// Upload to Git LFS if the path is tracked and uploads are enabled
+		content, err := batch.backend.lfsContent(ctx, op.Path, op.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		mode, err := batch.fileMode(ctx, base, op)
+		if err != nil {
+			return nil, err
+		}
+
+		// Skip unchanged files, comparing the blob SHA Git would assign
+		sha := GitBlobSHA(content)
+		if entry, ok := base.entries[op.Path]; ok && entry.typ == "blob" && entry.sha == sha && entry.mode == mode {
+			return nil, nil
+		}
+
+		// The GraphQL engine sends the content with the commit
+		if batch.backend.config.CommitEngine == CommitEngineGraphQL {
+			if mode != FileModeRegular {
+				return nil, fmt.Errorf("%w: file mode %s with the GraphQL commit engine", omnistorage.ErrNotSupported, mode)
 			}
+			if batch.backend.config.Cache != nil {
+				batch.backend.config.Cache.Set(ctx, sha, content)
+			}
+			return &github.TreeEntry{
+				Path:    github.Ptr(op.Path),
+				Mode:    github.Ptr(string(mode)),
+				Type:    github.Ptr("blob"),
+				SHA:     github.Ptr(sha),
+				Content: github.Ptr(string(content)),
+			}, nil
+		}
+
+		// Create a blob for the content, base64 encoded so binary
+		// content survives the JSON request body
+		var blob *github.Blob
+		err = batch.backend.retryRateLimited(ctx, func() (*github.Response, error) {
+			var resp *github.Response
+			blob, resp, err = batch.backend.client.Git.CreateBlob(
+				ctx,
+				batch.backend.config.Owner,
+				batch.backend.config.Repo,
+				github.Blob{
+					Content:  github.Ptr(base64.StdEncoding.EncodeToString(content)),
+					Encoding: github.Ptr("base64"),
+				},
+			)
+			return resp, err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Seed the cache with the committed content
+		if batch.backend.config.Cache != nil {
+			batch.backend.config.Cache.Set(ctx, blob.GetSHA(), content)
+		}
+
+		return &github.TreeEntry{
+			Path: github.Ptr(op.Path),
+			Mode: github.Ptr(string(mode)),
+			Type: github.Ptr("blob"),
+			SHA:  blob.SHA,
+		}, nil
+
+	case BatchOpDelete:
+		// To delete a file, we set SHA to nil (or omit it) with the path
+		// The GitHub API interprets this as a deletion when creating a tree
+		// We need to check if the file exists first
+		exists, err := batch.baseHas(ctx, base, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
 			// If file doesn't exist, skip it (idempotent)
+			return nil, nil
 		}
+
+		// Use a nil SHA to indicate deletion
+		return &github.TreeEntry{
+			Path: github.Ptr(op.Path),
+			Mode: github.Ptr("100644"),
+			Type: github.Ptr("blob"),
+			SHA:  nil, // nil SHA means delete
+		}, nil
 	}
 
-	return entries, nil
+	return nil, nil
+}
+
+// fileMode returns the mode of a written file: the mode of the operation
+// if set, or else the mode of the existing file, so that overwrites keep
+// executable bits and symbolic links.
+func (batch *Batch) fileMode(ctx context.Context, base *treeSnapshot, op BatchOperation) (FileMode, error) {
+	if op.Mode != "" {
+		return op.Mode, nil
+	}
+
+	entry, ok := base.entries[op.Path]
+	if base.truncated {
+		treeEntry, err := batch.backend.lookupTreeEntry(ctx, base.commitSHA, op.Path)
+		if err != nil {
+			return "", err
+		}
+		entry = treeCacheEntry{typ: treeEntry.GetType(), mode: FileMode(treeEntry.GetMode())}
+		ok = treeEntry != nil
+	}
+	if ok && entry.typ == "blob" && entry.mode != "" {
+		return entry.mode, nil
+	}
+	return FileModeRegular, nil
+}
+
+// baseHas reports whether a path exists in the base tree. If the base
+// tree was too large to fetch, it looks up the path at the base commit.
+func (batch *Batch) baseHas(ctx context.Context, base *treeSnapshot, filePath string) (bool, error) {
+	if !base.truncated {
+		_, ok := base.entries[filePath]
+		return ok, nil
+	}
+
+	release, err := batch.backend.acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+	entry, err := batch.backend.lookupTreeEntry(ctx, base.commitSHA, filePath)
+	return entry != nil, err
+}
+
+// acquire waits for one of the backend's Config.MaxConcurrency request
+// slots, and for any secondary rate limit pause to pass, and returns the
+// function that releases the slot.
+func (b *Backend) acquire(ctx context.Context) (func(), error) {
+	b.slotsOnce.Do(func() {
+		n := b.config.MaxConcurrency
+		if n <= 0 {
+			n = defaultMaxConcurrency
+		}
+		b.slots = make(chan struct{}, n)
+	})
+
+	select {
+	case b.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	release := func() { <-b.slots }
+	if err := b.waitPause(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// retryRateLimited calls do, which makes an API request, and retries it
+// up to maxRateLimitRetries times after secondary rate limits, which
+// parallel requests can trigger. Other errors are returned translated.
+func (b *Backend) retryRateLimited(ctx context.Context, do func() (*github.Response, error)) error {
+	for attempt := 0; ; attempt++ {
+		resp, err := do()
+		if err == nil {
+			return nil
+		}
+		if attempt == maxRateLimitRetries || !b.pauseForRateLimit(err) {
+			return b.translateError(err, resp)
+		}
+		if err := b.waitPause(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// pauseForRateLimit reports whether err is a secondary rate limit, and if
+// so pauses all request slots for the time GitHub asks.
+func (b *Backend) pauseForRateLimit(err error) bool {
+	var limited *github.AbuseRateLimitError
+	if !errors.As(err, &limited) {
+		return false
+	}
+
+	wait := defaultRetryAfter
+	if limited.RetryAfter != nil {
+		wait = *limited.RetryAfter
+	}
+
+	b.pauseMu.Lock()
+	defer b.pauseMu.Unlock()
+	if until := time.Now().Add(wait); until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+	return true
+}
+
+// waitPause blocks until a pause set by pauseForRateLimit has passed.
+func (b *Backend) waitPause(ctx context.Context) error {
+	for {
+		b.pauseMu.Lock()
+		wait := time.Until(b.pausedUntil)
+		b.pauseMu.Unlock()
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
 }
@@ -0,0 +1,243 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// blobTransport delays blob uploads and records how many run at once.
+type blobTransport struct {
+	base    http.RoundTripper
+	fail    bool // fail the first upload
+	limited bool // hit a secondary rate limit on the first upload
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	uploads     int
+}
+
+func (t *blobTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/git/blobs") {
+		return t.base.RoundTrip(req)
+	}
+
+	t.mu.Lock()
+	t.uploads++
+	first := t.uploads == 1
+	t.inFlight++
+	t.maxInFlight = max(t.maxInFlight, t.inFlight)
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		t.inFlight--
+		t.mu.Unlock()
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	if t.fail && first {
+		return &http.Response{
+			StatusCode: http.StatusUnprocessableEntity,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	}
+	if t.limited && first {
+		return &http.Response{
+			StatusCode: http.StatusForbidden,
+			Header: http.Header{
+				"Content-Type": {"application/json"},
+				"Retry-After":  {"1"},
+			},
+			Body: io.NopCloser(strings.NewReader(`{"message":"You have exceeded a secondary rate limit",` +
+				`"documentation_url":"https://docs.github.com/rest/overview/rate-limits-for-the-rest-api#about-secondary-rate-limits"}`)),
+			Request: req,
+		}, nil
+	}
+	return t.base.RoundTrip(req)
+}
+
+// withBlobTransport routes the backend's requests through a blobTransport.
+func withBlobTransport(t *testing.T, backend *Backend, fail bool) *blobTransport {
+	t.Helper()
+	tr := &blobTransport{base: http.DefaultTransport, fail: fail}
+	client, err := github.NewClient(&http.Client{Transport: tr}).WithEnterpriseURLs(backend.config.BaseURL, backend.config.UploadURL)
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+	backend.client = client
+	return tr
+}
+
+func TestBatchConcurrency(t *testing.T) {
+	backend, _ := fakeServerBackend(t)
+	backend.config.MaxConcurrency = 2
+	tr := withBlobTransport(t, backend, false)
+
+	ctx := context.Background()
+	batch, err := backend.NewBatch(ctx, "Concurrent batch")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	for i := 0; i < 8; i++ {
+		_ = batch.Write(fmt.Sprintf("concurrent/%d.txt", i), []byte(fmt.Sprint(i)))
+	}
+	if _, err := batch.Commit(); err != nil {
+		t.Fatalf("batch.Commit failed: %v", err)
+	}
+
+	if tr.maxInFlight != 2 {
+		t.Errorf("Expected 2 concurrent uploads, got %d", tr.maxInFlight)
+	}
+	for i := 0; i < 8; i++ {
+		assertContent(t, ctx, backend, fmt.Sprintf("concurrent/%d.txt", i), []byte(fmt.Sprint(i)))
+	}
+}
+
+func TestBatchFirstErrorCancels(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	backend.config.MaxConcurrency = 2
+	tr := withBlobTransport(t, backend, true)
+	head := srv.Head("main")
+
+	batch, err := backend.NewBatch(context.Background(), "Failing batch")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	for i := 0; i < 8; i++ {
+		_ = batch.Write(fmt.Sprintf("failing/%d.txt", i), []byte(fmt.Sprint(i)))
+	}
+	if _, err := batch.Commit(); err == nil {
+		t.Fatal("Expected batch.Commit to fail")
+	}
+
+	if tr.uploads >= 8 {
+		t.Errorf("Expected the failure to cancel remaining uploads, got %d uploads", tr.uploads)
+	}
+	if got := srv.Head("main"); got != head {
+		t.Errorf("Expected the branch not to move, got %s", got)
+	}
+}
+
+func TestBatchSecondaryRateLimit(t *testing.T) {
+	backend, _ := fakeServerBackend(t)
+	backend.config.MaxConcurrency = 2
+	tr := withBlobTransport(t, backend, false)
+	tr.limited = true
+
+	ctx := context.Background()
+	batch, err := backend.NewBatch(ctx, "Rate limited batch")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		_ = batch.Write(fmt.Sprintf("limited/%d.txt", i), []byte(fmt.Sprint(i)))
+	}
+	start := time.Now()
+	if _, err := batch.Commit(); err != nil {
+		t.Fatalf("batch.Commit failed: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Expected the batch to wait for Retry-After, took %v", elapsed)
+	}
+	if tr.uploads != 5 {
+		t.Errorf("Expected the limited upload to be retried, got %d uploads", tr.uploads)
+	}
+	for i := 0; i < 4; i++ {
+		assertContent(t, ctx, backend, fmt.Sprintf("limited/%d.txt", i), []byte(fmt.Sprint(i)))
+	}
+}
+
+func TestBatchDeleteMany(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	files := make(map[string][]byte)
+	for i := 0; i < 50; i++ {
+		files[fmt.Sprintf("data/bulk/%d.txt", i)] = []byte(fmt.Sprint(i))
+	}
+	srv.CommitFiles("main", "Seed bulk files", files)
+	srv.ResetRequests()
+
+	ctx := context.Background()
+	batch, err := backend.NewBatch(ctx, "Delete bulk files")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	for p := range files {
+		_ = batch.Delete(p)
+	}
+	_ = batch.Delete("data/bulk/missing.txt")
+	if _, err := batch.Commit(); err != nil {
+		t.Fatalf("batch.Commit failed: %v", err)
+	}
+
+	// Ref, commit, the trees of the root, data/ and data/bulk/, new tree,
+	// new commit and ref update
+	if n := len(srv.Requests()); n != 8 {
+		t.Errorf("Expected 8 requests, got %d: %v", n, srv.Requests())
+	}
+	paths, err := backend.List(ctx, "data/")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(paths) != 0 {
+		t.Errorf("Expected all bulk files deleted, got %v", paths)
+	}
+}
+
+func TestCommonDir(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"README.md"}, ""},
+		{[]string{"a/b/c.txt"}, "a/b"},
+		{[]string{"a/b/c.txt", "a/b/d/e.txt"}, "a/b"},
+		{[]string{"a/b/c.txt", "a/x.txt"}, "a"},
+		{[]string{"a/b.txt", "ab/c.txt"}, ""},
+		{[]string{"a/b.txt", "c.txt"}, ""},
+	}
+	for _, tt := range tests {
+		if got := commonDir(tt.paths); got != tt.want {
+			t.Errorf("commonDir(%q) = %q, want %q", tt.paths, got, tt.want)
+		}
+	}
+}
+
+func TestBatchDeleteTruncatedTree(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	srv.CommitFiles("feature", "Add extra", map[string][]byte{"extra.txt": []byte("extra")})
+	srv.CommitFiles("main", "Remove README", map[string][]byte{"README.md": nil})
+	srv.SetTreeLimit(1)
+
+	// Existence is checked at the base commit of the batch's branch, not
+	// at the head of Config.Branch
+	batch, err := backend.NewBatch(context.Background(), "Clean up feature")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	batch.branch = "feature"
+	_ = batch.Delete("extra.txt")
+	_ = batch.Delete("README.md")
+	_ = batch.Delete("missing.txt")
+	if _, err := batch.Commit(); err != nil {
+		t.Fatalf("batch.Commit failed: %v", err)
+	}
+
+	for _, p := range []string{"extra.txt", "README.md"} {
+		if _, ok := srv.File("feature", p); ok {
+			t.Errorf("Expected %s deleted from feature", p)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package github
+
+import (
+	"container/list"
+	"context"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Cache stores file content keyed by Git blob SHA.
+//
+// Blobs are content-addressed, so a cached entry never goes stale: a changed
+// file has a new blob SHA. Implementations only need to bound their size.
+// Get must treat errors as misses, and callers must not modify the returned
+// slice. Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the content of the blob with the given SHA.
+	Get(ctx context.Context, sha string) ([]byte, bool)
+
+	// Set stores the content of the blob with the given SHA.
+	Set(ctx context.Context, sha string, data []byte)
+
+	// Stats returns usage counters for the cache.
+	Stats() CacheStats
+}
+
+// CacheStats holds usage counters for a Cache.
+type CacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	Entries   int64
+	Bytes     int64
+}
+
+// cacheCounters tracks the hit, miss and eviction counters of a cache.
+type cacheCounters struct {
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+func (c *cacheCounters) record(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
+
+func (c *cacheCounters) stats(entries, bytes int64) CacheStats {
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   entries,
+		Bytes:     bytes,
+	}
+}
+
+// isBlobSHA reports whether sha is a well-formed Git object ID.
+func isBlobSHA(sha string) bool {
+	if len(sha) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(sha)
+	return err == nil
+}
+
+// lruEntry is an entry of an LRU list.
+type lruEntry struct {
+	key   string
+	size  int64
+	value any // nil for DiskCache
+}
+
+// lru is a size-bounded least-recently-used index. It is not safe for
+// concurrent use.
+type lru struct {
+	maxBytes int64
+	bytes    int64
+	order    *list.List // front is most recently used
+	entries  map[string]*list.Element
+}
+
+func newLRU(maxBytes int64) *lru {
+	return &lru{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (l *lru) get(key string) (*lruEntry, bool) {
+	elem, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry), true
+}
+
+// add inserts an entry and returns the entries evicted to make room.
+func (l *lru) add(entry *lruEntry) []*lruEntry {
+	if elem, ok := l.entries[entry.key]; ok {
+		l.order.MoveToFront(elem)
+		return nil
+	}
+	l.entries[entry.key] = l.order.PushFront(entry)
+	l.bytes += entry.size
+
+	var evicted []*lruEntry
+	for l.maxBytes > 0 && l.bytes > l.maxBytes && l.order.Len() > 1 {
+		oldest := l.order.Back()
+		evicted = append(evicted, l.remove(oldest.Value.(*lruEntry).key))
+	}
+	return evicted
+}
+
+func (l *lru) remove(key string) *lruEntry {
+	elem, ok := l.entries[key]
+	if !ok {
+		return nil
+	}
+	l.order.Remove(elem)
+	delete(l.entries, key)
+	entry := elem.Value.(*lruEntry)
+	l.bytes -= entry.size
+	return entry
+}
+
+// MemoryCache is an in-memory LRU Cache bounded by total content size.
+type MemoryCache struct {
+	cacheCounters
+	mu  sync.Mutex
+	lru *lru
+}
+
+// NewMemoryCache creates an in-memory LRU cache holding up to maxBytes of
+// content. Blobs larger than maxBytes are not cached. A maxBytes of 0
+// means no limit.
+func NewMemoryCache(maxBytes int64) *MemoryCache {
+	return &MemoryCache{lru: newLRU(maxBytes)}
+}
+
+// Get returns the cached content of a blob.
+func (c *MemoryCache) Get(_ context.Context, sha string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.lru.get(sha)
+	c.record(ok)
+	if !ok {
+		return nil, false
+	}
+	return entry.value.([]byte), true
+}
+
+// Set caches the content of a blob.
+func (c *MemoryCache) Set(_ context.Context, sha string, data []byte) {
+	size := int64(len(data))
+	if c.lru.maxBytes > 0 && size > c.lru.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	evicted := c.lru.add(&lruEntry{key: sha, size: size, value: append([]byte(nil), data...)})
+	c.evictions.Add(int64(len(evicted)))
+}
+
+// Stats returns usage counters for the cache.
+func (c *MemoryCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats(int64(c.lru.order.Len()), c.lru.bytes)
+}
+
+// DiskCache is a Cache storing blobs as files in a directory, bounded by
+// total content size. Entries are evicted least recently used first, and
+// are verified against their SHA when read.
+type DiskCache struct {
+	cacheCounters
+	dir    string
+	mu     sync.Mutex
+	lru    *lru
+	loaded bool
+}
+
+// NewDiskCache creates a cache storing up to maxBytes of content under dir.
+// The directory is created on first write, and blobs already present in it
+// are reused. A maxBytes of 0 means no limit.
+func NewDiskCache(dir string, maxBytes int64) *DiskCache {
+	return &DiskCache{dir: dir, lru: newLRU(maxBytes)}
+}
+
+// blobPath returns the file path for a blob, fanned out by SHA prefix like
+// .git/objects.
+func (c *DiskCache) blobPath(sha string) string {
+	return filepath.Join(c.dir, sha[:2], sha[2:])
+}
+
+// load indexes blobs left in the directory by a previous process, oldest
+// first so that they are evicted first. Must be called with c.mu held.
+func (c *DiskCache) load() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+
+	type found struct {
+		sha  string
+		info os.FileInfo
+	}
+	var blobs []found
+	_ = filepath.Walk(c.dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil //nolint:nilerr // unreadable entries are skipped
+		}
+		sha := filepath.Base(filepath.Dir(p)) + info.Name()
+		if isBlobSHA(sha) {
+			blobs = append(blobs, found{sha, info})
+		}
+		return nil
+	})
+	sort.Slice(blobs, func(i, j int) bool {
+		return blobs[i].info.ModTime().Before(blobs[j].info.ModTime())
+	})
+
+	for _, blob := range blobs {
+		c.evict(c.lru.add(&lruEntry{key: blob.sha, size: blob.info.Size()}))
+	}
+}
+
+// evict removes the files of evicted entries. Must be called with c.mu held.
+func (c *DiskCache) evict(entries []*lruEntry) {
+	for _, entry := range entries {
+		_ = os.Remove(c.blobPath(entry.key))
+	}
+	c.evictions.Add(int64(len(entries)))
+}
+
+// Get returns the cached content of a blob.
+func (c *DiskCache) Get(_ context.Context, sha string) ([]byte, bool) {
+	if !isBlobSHA(sha) {
+		c.record(false)
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+
+	if _, ok := c.lru.get(sha); !ok {
+		c.record(false)
+		return nil, false
+	}
+
+	// Drop entries that were removed or corrupted on disk
+	data, err := os.ReadFile(c.blobPath(sha))
+	if err != nil || GitBlobSHA(data) != sha {
+		c.lru.remove(sha)
+		_ = os.Remove(c.blobPath(sha))
+		c.record(false)
+		return nil, false
+	}
+
+	c.record(true)
+	return data, true
+}
+
+// Set caches the content of a blob. Write errors are ignored.
+func (c *DiskCache) Set(_ context.Context, sha string, data []byte) {
+	size := int64(len(data))
+	if !isBlobSHA(sha) || (c.lru.maxBytes > 0 && size > c.lru.maxBytes) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+
+	if _, ok := c.lru.get(sha); ok {
+		return
+	}
+
+	// Write to a temporary file first so readers never see partial blobs
+	blobPath := c.blobPath(sha)
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0o700); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(blobPath), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), blobPath)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return
+	}
+
+	c.evict(c.lru.add(&lruEntry{key: sha, size: size}))
+}
+
+// Stats returns usage counters for the cache.
+func (c *DiskCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+	return c.stats(int64(c.lru.order.Len()), c.lru.bytes)
+}
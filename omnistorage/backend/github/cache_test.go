@@ -0,0 +1,161 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omni-github/omnistorage/backend/github/githubtest"
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+func TestMemoryCacheLRU(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(10)
+
+	a, b, c := GitBlobSHA([]byte("aaaa")), GitBlobSHA([]byte("bbbb")), GitBlobSHA([]byte("cccc"))
+	cache.Set(ctx, a, []byte("aaaa"))
+	cache.Set(ctx, b, []byte("bbbb"))
+
+	// Touch a so that b is the least recently used
+	if data, ok := cache.Get(ctx, a); !ok || string(data) != "aaaa" {
+		t.Fatalf("Get(a) = %q, %v", data, ok)
+	}
+	cache.Set(ctx, c, []byte("cccc"))
+
+	if _, ok := cache.Get(ctx, b); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if _, ok := cache.Get(ctx, a); !ok {
+		t.Error("Expected a to be cached")
+	}
+
+	// Blobs larger than the cache are not stored
+	big := []byte(strings.Repeat("x", 11))
+	cache.Set(ctx, GitBlobSHA(big), big)
+
+	want := CacheStats{Hits: 2, Misses: 1, Evictions: 1, Entries: 2, Bytes: 8}
+	if got := cache.Stats(); got != want {
+		t.Errorf("Stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	a, b := GitBlobSHA([]byte("aaaa")), GitBlobSHA([]byte("bbbb"))
+	cache := NewDiskCache(dir, 6)
+	cache.Set(ctx, a, []byte("aaaa"))
+
+	// A new instance reuses blobs left in the directory
+	reopened := NewDiskCache(dir, 6)
+	if data, ok := reopened.Get(ctx, a); !ok || string(data) != "aaaa" {
+		t.Fatalf("Get(a) after reopen = %q, %v", data, ok)
+	}
+
+	// Adding b exceeds the limit and evicts a from disk
+	reopened.Set(ctx, b, []byte("bbbb"))
+	if _, err := os.Stat(filepath.Join(dir, a[:2], a[2:])); !os.IsNotExist(err) {
+		t.Errorf("Expected evicted blob to be removed, got %v", err)
+	}
+
+	// Corrupted blobs are dropped
+	if err := os.WriteFile(filepath.Join(dir, b[:2], b[2:]), []byte("tampered"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reopened.Get(ctx, b); ok {
+		t.Error("Expected corrupted blob to be a miss")
+	}
+
+	// Malformed SHAs never touch the filesystem
+	reopened.Set(ctx, "../escape", []byte("x"))
+	if _, ok := reopened.Get(ctx, "../escape"); ok {
+		t.Error("Expected malformed SHA to be a miss")
+	}
+
+	stats := reopened.Stats()
+	if stats.Hits != 1 || stats.Evictions != 1 || stats.Entries != 0 {
+		t.Errorf("Stats = %+v", stats)
+	}
+}
+
+func TestCachedReads(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	cache := NewMemoryCache(0)
+	backend.config.Cache = cache
+
+	ctx := context.Background()
+	read := func(p string) string {
+		t.Helper()
+		r, err := backend.NewReader(ctx, p)
+		if err != nil {
+			t.Fatalf("NewReader(%q) failed: %v", p, err)
+		}
+		defer func() { _ = r.Close() }()
+		data, _ := io.ReadAll(r)
+		return string(data)
+	}
+	blobFetches := func() int {
+		n := 0
+		for _, req := range srv.Requests() {
+			if strings.Contains(req, "/git/blobs/") {
+				n++
+			}
+		}
+		return n
+	}
+
+	first := read("README.md")
+	second := read("README.md")
+	if first != second || !strings.Contains(first, "Omnistorage") {
+		t.Errorf("Cached read = %q, want %q", second, first)
+	}
+	if n := blobFetches(); n != 1 {
+		t.Errorf("Expected 1 blob download, got %d", n)
+	}
+
+	// Writes seed the cache, and reads see the new blob
+	mustWrite(t, ctx, backend, "README.md", []byte("# Updated"))
+	if got := read("README.md"); got != "# Updated" {
+		t.Errorf("read after write = %q, want %q", got, "# Updated")
+	}
+	if n := blobFetches(); n != 1 {
+		t.Errorf("Expected no blob download after write, got %d total", n)
+	}
+
+	if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Stats = %+v, want 2 hits and 1 miss", stats)
+	}
+}
+
+func TestCachedReadsLookup(t *testing.T) {
+	for _, treeCache := range []bool{false, true} {
+		backend, srv := fakeServerBackend(t)
+		backend.config.Cache = NewMemoryCache(0)
+		backend.config.TreeCache = treeCache
+		backend.config.TreeCacheInterval = time.Hour
+		ctx := context.Background()
+
+		// Blob SHAs come from the tree, not from directory listings
+		assertContent(t, ctx, backend, "backend/file/file.go", []byte("package file\n"))
+		if n := countRequests(srv, "/contents/"); n != 0 {
+			t.Errorf("treeCache=%v: cached read made %d Contents requests", treeCache, n)
+		}
+
+		// A file missing from an outdated tree is read through the
+		// Contents API
+		srv.CommitFiles(githubtest.DefaultBranch, "Add new", map[string][]byte{"new.txt": []byte("new")})
+		assertContent(t, ctx, backend, "new.txt", []byte("new"))
+
+		if _, err := backend.NewReader(ctx, "missing.txt"); !errors.Is(err, omnistorage.ErrNotFound) {
+			t.Errorf("treeCache=%v: NewReader(missing) = %v, want ErrNotFound", treeCache, err)
+		}
+		_ = backend.Close()
+	}
+}
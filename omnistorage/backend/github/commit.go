@@ -0,0 +1,157 @@
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// CommitEngine selects the API used to create commits.
+type CommitEngine string
+
+const (
+	// CommitEngineREST creates commits with the REST Contents and Git Data
+	// APIs. Commits are unsigned unless signed by the backend.
+	CommitEngineREST CommitEngine = "rest"
+
+	// CommitEngineGraphQL creates commits with the GraphQL
+	// createCommitOnBranch mutation. GitHub signs these commits, so they
+	// show as verified and satisfy branch protection rules requiring
+	// signed commits. The author is the authenticated user or app;
+	// Config.CommitAuthor is ignored.
+	CommitEngineGraphQL CommitEngine = "graphql"
+)
+
+// CommitResult describes the commit created by a write or batch commit.
+type CommitResult struct {
+	// CommitSHA is the SHA of the new commit.
+	CommitSHA string
+
+	// TreeSHA is the SHA of the new commit's root tree.
+	TreeSHA string
+
+	// HTMLURL is the web URL of the new commit.
+	HTMLURL string
+
+	// BlobSHAs maps each written path to the SHA of its new blob.
+	// Deleted paths are not included.
+	BlobSHAs map[string]string
+}
+
+// BlobSHA returns the blob SHA written for filePath, or "" if none.
+func (r *CommitResult) BlobSHA(filePath string) string {
+	if r == nil {
+		return ""
+	}
+	return r.BlobSHAs[filePath]
+}
+
+// CommitResultProvider is implemented by writers returned from
+// Backend.NewWriter. After a successful Close, CommitResult returns the
+// commit that was created, or nil if the content was unchanged.
+//
+//	w, _ := backend.NewWriter(ctx, "data.json")
+//	// ... write and close ...
+//	if p, ok := w.(github.CommitResultProvider); ok {
+//	    log.Println(p.CommitResult().HTMLURL)
+//	}
+type CommitResultProvider interface {
+	CommitResult() *CommitResult
+}
+
+// newCommitResult builds a CommitResult from a GitHub commit.
+func newCommitResult(commit *github.Commit) *CommitResult {
+	return &CommitResult{
+		CommitSHA: commit.GetSHA(),
+		TreeSHA:   commit.GetTree().GetSHA(),
+		HTMLURL:   commit.GetHTMLURL(),
+		BlobSHAs:  make(map[string]string),
+	}
+}
+
+// CommitResult returns the commit created by Close, or nil if Close has
+// not completed successfully or the content was unchanged.
+func (w *writer) CommitResult() *CommitResult {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.result
+}
+
+// CommitResult returns the commit created by Commit, or nil if Commit has
+// not completed successfully or the batch changed nothing.
+func (batch *Batch) CommitResult() *CommitResult {
+	batch.mu.Lock()
+	defer batch.mu.Unlock()
+	return batch.result
+}
+
+var _ CommitResultProvider = (*writer)(nil)
+
+// createCommitOnBranchMutation commits file changes on top of an expected
+// branch head.
+const createCommitOnBranchMutation = `mutation($input: CreateCommitOnBranchInput!) {
+  createCommitOnBranch(input: $input) {
+    commit { oid url tree { oid } }
+  }
+}`
+
+// commitOnBranch commits tree entries to a branch with the GraphQL
+// createCommitOnBranch mutation. Entries with a nil SHA are deletions, and
+// the others must carry their content. The commit fails if the branch head
+// is no longer expectedHeadSHA.
+func (b *Backend) commitOnBranch(ctx context.Context, branch, expectedHeadSHA, message string, entries []*github.TreeEntry) (*github.Commit, error) {
+	additions := []map[string]string{}
+	deletions := []map[string]string{}
+	for _, entry := range entries {
+		if entry.SHA == nil {
+			deletions = append(deletions, map[string]string{"path": entry.GetPath()})
+			continue
+		}
+		additions = append(additions, map[string]string{
+			"path":     entry.GetPath(),
+			"contents": base64.StdEncoding.EncodeToString([]byte(entry.GetContent())),
+		})
+	}
+
+	headline, body, _ := strings.Cut(message, "\n")
+	input := map[string]any{
+		"branch": map[string]string{
+			"repositoryNameWithOwner": b.config.Owner + "/" + b.config.Repo,
+			"branchName":              branch,
+		},
+		"message": map[string]string{
+			"headline": headline,
+			"body":     strings.TrimLeft(body, "\n"),
+		},
+		"expectedHeadOid": expectedHeadSHA,
+		"fileChanges": map[string]any{
+			"additions": additions,
+			"deletions": deletions,
+		},
+	}
+
+	var result struct {
+		CreateCommitOnBranch struct {
+			Commit struct {
+				OID  string `json:"oid"`
+				URL  string `json:"url"`
+				Tree struct {
+					OID string `json:"oid"`
+				} `json:"tree"`
+			} `json:"commit"`
+		} `json:"createCommitOnBranch"`
+	}
+	if err := b.graphQL(ctx, createCommitOnBranchMutation, map[string]any{"input": input}, &result); err != nil {
+		return nil, err
+	}
+
+	commit := result.CreateCommitOnBranch.Commit
+	return &github.Commit{
+		SHA:     github.Ptr(commit.OID),
+		HTMLURL: github.Ptr(commit.URL),
+		Tree:    &github.Tree{SHA: github.Ptr(commit.Tree.OID)},
+		Parents: []*github.Commit{{SHA: github.Ptr(expectedHeadSHA)}},
+	}, nil
+}
@@ -0,0 +1,99 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/plexusone/omni-github/omnistorage/backend/github/githubtest"
+)
+
+func TestCommitEngineGraphQL(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	defer func() { _ = backend.Close() }()
+	backend.config.CommitEngine = CommitEngineGraphQL
+	ctx := context.Background()
+
+	srv.ResetRequests()
+	mustWrite(t, ctx, backend, "docs/guide.md", []byte("# Guide\n"))
+
+	batch, err := backend.NewBatch(ctx, "Reorganize\n\nMove the memory backend.")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	if err := batch.Write("backend/mem/mem.go", []byte("package mem\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := batch.Delete("backend/memory/mem.go"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := batch.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if err := backend.Delete(ctx, "backend/file/file.go"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	for _, req := range srv.Requests() {
+		if strings.HasPrefix(req, "PUT ") || strings.HasPrefix(req, "DELETE ") ||
+			strings.HasSuffix(req, "/git/blobs") || strings.HasSuffix(req, "/git/commits") {
+			t.Errorf("unexpected REST write %q", req)
+		}
+	}
+
+	content, ok := srv.File(githubtest.DefaultBranch, "backend/mem/mem.go")
+	if !ok || string(content) != "package mem\n" {
+		t.Errorf("backend/mem/mem.go = %q, %v", content, ok)
+	}
+	for _, p := range []string{"backend/memory/mem.go", "backend/file/file.go"} {
+		if _, ok := srv.File(githubtest.DefaultBranch, p); ok {
+			t.Errorf("%s was not deleted", p)
+		}
+	}
+	assertContent(t, ctx, backend, "docs/guide.md", []byte("# Guide\n"))
+
+	commit, _, err := backend.client.Git.GetCommit(ctx, "grokify", "omnistorage", batch.CommitResult().CommitSHA)
+	if err != nil {
+		t.Fatalf("GetCommit failed: %v", err)
+	}
+	if got, want := commit.GetMessage(), "Reorganize\n\nMove the memory backend."; got != want {
+		t.Errorf("message = %q, want %q", got, want)
+	}
+	if !commit.GetVerification().GetVerified() {
+		t.Error("commit is not verified")
+	}
+}
+
+func TestCommitEngineGraphQLStaleHead(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	defer func() { _ = backend.Close() }()
+	backend.config.CommitEngine = CommitEngineGraphQL
+	ctx := context.Background()
+
+	head := srv.Head(githubtest.DefaultBranch)
+	srv.CommitFiles(githubtest.DefaultBranch, "Concurrent change", map[string][]byte{
+		"other.txt": []byte("other"),
+	})
+
+	_, err := backend.commitOnBranch(ctx, githubtest.DefaultBranch, head, "Stale", nil)
+	var gqlErr *GraphQLError
+	if !errors.As(err, &gqlErr) {
+		t.Fatalf("commitOnBranch error = %v, want *GraphQLError", err)
+	}
+	if !strings.Contains(gqlErr.Error(), "Expected branch to point to") {
+		t.Errorf("error = %v", gqlErr)
+	}
+}
+
+func TestConfigCommitEngine(t *testing.T) {
+	cfg := Config{Owner: "o", Repo: "r", Token: "t", CommitEngine: "soap"}
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidCommitEngine) {
+		t.Errorf("Validate() = %v, want ErrInvalidCommitEngine", err)
+	}
+
+	cfg = ConfigFromMap(map[string]string{"owner": "o", "repo": "r", "token": "t", "commit_engine": "graphql"})
+	if cfg.CommitEngine != CommitEngineGraphQL {
+		t.Errorf("CommitEngine = %q, want %q", cfg.CommitEngine, CommitEngineGraphQL)
+	}
+}
@@ -15,8 +15,10 @@ package github
 
 import (
 	"errors"
+	"fmt"
 	"os"
-	"strings"
+	"strconv"
+	"time"
 )
 
 // Config errors.
@@ -24,6 +26,8 @@ var (
 	ErrOwnerRequired = errors.New("github: owner is required")
 	ErrRepoRequired  = errors.New("github: repo is required")
 	ErrTokenRequired = errors.New("github: token is required")
+
+	ErrInvalidCommitEngine = errors.New("github: invalid commit engine")
 )
 
 // CommitAuthor represents the author of a commit.
@@ -55,22 +59,163 @@ type Config struct {
 	// Set this for GitHub Enterprise.
 	UploadURL string
 
-	// CommitMessage is the commit message template for write operations.
-	// Use {path} as a placeholder for the file path.
-	// Default: "Update {path} via omnistorage"
+	// CommitMessage is the commit message template for writes. It is a
+	// text/template executed with CommitMessageData, such as
+	// "Update {{.Path}} ({{.ShortSHA}})"; {path} is shorthand for
+	// {{.Path}}. Default: "Update {path} via omnistorage"
 	CommitMessage string
 
+	// CommitMessageCreate and CommitMessageUpdate replace CommitMessage
+	// for writes that create a file and writes that change one.
+	CommitMessageCreate string
+	CommitMessageUpdate string
+
+	// CommitMessageDelete is the commit message template for deletes.
+	// Default: "Delete {path} via omnistorage"
+	CommitMessageDelete string
+
+	// CommitMessageBatch is the commit message template for batches
+	// created without a message and auto-batch commits that change
+	// several files; range over .Files to list them. Commits that change
+	// a single file use the template of its operation.
+	// Default: "Update {{len .Files}} files via omnistorage"
+	CommitMessageBatch string
+
 	// CommitAuthor is the author for commits. If nil, uses the authenticated user.
 	CommitAuthor *CommitAuthor
+
+	// CommitCommitter is the committer for commits. If nil, the author is
+	// also the committer.
+	CommitCommitter *CommitAuthor
+
+	// CommitCoAuthors are added to every commit message as
+	// Co-authored-by trailers.
+	CommitCoAuthors []CommitAuthor
+
+	// CommitTrailers are added to every commit message, for example
+	// trailers required by a policy check.
+	CommitTrailers []CommitTrailer
+
+	// CommitSignOff adds a Signed-off-by trailer for the author to every
+	// commit message, as required by DCO checks. Requires an author.
+	CommitSignOff bool
+
+	// CommitEngine selects the API used by writers and batches to create
+	// commits. Use CommitEngineGraphQL for commits signed by GitHub.
+	// Default: CommitEngineREST.
+	CommitEngine CommitEngine
+
+	// CommitSigner signs commits created with the REST commit engine,
+	// typically a GPGSigner or SSHSigner. Writes and deletes then commit
+	// through the Git Data API, since the Contents API cannot carry a
+	// signature. Requires CommitAuthor. Default: nil (unsigned).
+	CommitSigner CommitSigner
+
+	// DisableLFS turns off Git LFS pointer resolution. By default, reads
+	// of LFS pointer files return the real content from the LFS server and
+	// Stat reports the real size.
+	DisableLFS bool
+
+	// LFSUpload enables uploading content to Git LFS on write. Paths marked
+	// with filter=lfs in the root .gitattributes are uploaded to the LFS
+	// server and committed as pointer files. Default: false.
+	LFSUpload bool
+
+	// LFSURL is the Git LFS server endpoint.
+	// Default: "https://<host>/<owner>/<repo>.git/info/lfs".
+	LFSURL string
+
+	// Cache caches file content by blob SHA. When set, NewReader looks up
+	// the blob SHA in the cached tree (see TreeCache), or else in the tree
+	// of the parent directory, and only downloads the content on a cache
+	// miss. Default: nil (no caching).
+	Cache Cache
+
+	// DisableETags turns off conditional requests. By default, Contents,
+	// Trees and Refs responses are remembered with their ETags and
+	// revalidated with If-None-Match; GitHub does not count the resulting
+	// 304 Not Modified responses against the rate limit.
+	DisableETags bool
+
+	// TreeCache answers Exists, Stat and List from a cached copy of the
+	// branch's recursive tree instead of one API call per path. The branch
+	// head is checked at most every TreeCacheInterval, and the tree is only
+	// fetched again when the head has moved. Writes through the backend
+	// update the cached tree. Default: false.
+	TreeCache bool
+
+	// TreeCacheInterval is how long the cached tree is used before the
+	// branch head is checked again. Changes made by other clients are seen
+	// within this interval. Default: 30s.
+	TreeCacheInterval time.Duration
+
+	// StatModTime sets the ModTime of Stat and ListInfo results to the
+	// committer date of the last commit that changed each path. This
+	// costs a request per path on Stat, while ListInfo resolves up to 50
+	// paths per GraphQL request. Times are cached for the branch head,
+	// and commits made by this backend only drop the paths they change.
+	// Default: false.
+	StatModTime bool
+
+	// StatFileMode makes Stat report MetadataFileMode for regular files and
+	// executables without the tree cache, at the cost of a request for the
+	// parent tree. Symbolic links, submodules, ListInfo and the tree cache
+	// always report it. Default: false.
+	StatFileMode bool
+
+	// StatContentSHA1 makes Stat and ListInfo report the SHA-1 of each
+	// file's content under HashSHA1, so it can be compared with other
+	// backends. Git only stores blob SHAs (see HashGitBlob), so this
+	// downloads file versions, through Cache when set; LFS objects are
+	// streamed from the LFS server. The results for the 10,000 most
+	// recently used blobs are remembered. Without it, HashSHA1 is only
+	// reported when Stat receives the content anyway. Default: false.
+	StatContentSHA1 bool
+
+	// MaxConcurrency bounds the API requests a batch commit makes at the
+	// same time to upload blobs and check deletions. The bound is shared
+	// by all batches of the backend, so concurrent commits do not multiply
+	// it. When GitHub reports a secondary rate limit, all of these requests
+	// pause for its Retry-After time (a minute without one), and the blob
+	// upload that hit it is retried up to three times. Default: 4.
+	MaxConcurrency int
+
+	// AutoBatch coalesces writes into batch commits. Closing a writer
+	// queues its content, and the queue is committed as a single commit
+	// once it holds AutoBatchMaxFiles files or AutoBatchMaxBytes bytes, or
+	// AutoBatchWindow after the first queued write. Backend.Flush and
+	// Backend.Close commit the queue immediately. Use the writer's Wait
+	// method to learn whether its content was committed. Queued writes
+	// are not visible to reads until committed. Default: false.
+	AutoBatch bool
+
+	// AutoBatchMaxFiles is the number of queued files that triggers a
+	// commit. Default: 100.
+	AutoBatchMaxFiles int
+
+	// AutoBatchMaxBytes is the queued content size that triggers a
+	// commit. Default: 10 MB.
+	AutoBatchMaxBytes int64
+
+	// AutoBatchWindow is the longest a write stays queued. Default: 5s.
+	AutoBatchWindow time.Duration
+
+	// GistID selects a single gist for the "github-gist" backend, whose
+	// files are then addressed by name. If empty, the gist backend exposes
+	// all gists of Owner (or of the authenticated user if Owner is empty)
+	// as "<gist-id>/<filename>". Ignored by the other backends.
+	GistID string
 }
 
-// FormatCommitMessage formats the commit message with the given path.
+// FormatCommitMessage formats the commit message of an update of the
+// given path. A template that fails to execute is returned as is.
 func (c *Config) FormatCommitMessage(filePath string) string {
-	msg := c.CommitMessage
-	if msg == "" {
-		msg = "Update {path} via omnistorage"
+	data := newCommitMessageData(time.Now(), newCommitMessageFile(ChangeUpdate, filePath, 0, ""))
+	msg, err := c.RenderCommitMessage(data)
+	if err != nil {
+		return c.commitMessageTemplate(ChangeUpdate)
 	}
-	return strings.ReplaceAll(msg, "{path}", filePath)
+	return msg
 }
 
 // DefaultConfig returns a Config with sensible defaults.
@@ -91,6 +236,31 @@ func (c *Config) Validate() error {
 	if c.Repo == "" {
 		return ErrRepoRequired
 	}
+	if c.Token == "" {
+		return ErrTokenRequired
+	}
+	if err := c.validateCommitMessages(); err != nil {
+		return err
+	}
+	switch c.CommitEngine {
+	case "", CommitEngineREST, CommitEngineGraphQL:
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidCommitEngine, c.CommitEngine)
+	}
+	if c.CommitSigner != nil {
+		if c.CommitAuthor == nil {
+			return ErrSigningAuthorRequired
+		}
+		if c.CommitEngine == CommitEngineGraphQL {
+			return fmt.Errorf("%w: %s commits cannot be signed by a CommitSigner", ErrInvalidCommitEngine, c.CommitEngine)
+		}
+	}
+	return nil
+}
+
+// ValidateGist checks if the configuration is valid for the gist backend,
+// which only requires a token.
+func (c *Config) ValidateGist() error {
 	if c.Token == "" {
 		return ErrTokenRequired
 	}
@@ -106,8 +276,37 @@ func (c *Config) Validate() error {
 //   - base_url: GitHub API base URL (for GitHub Enterprise)
 //   - upload_url: GitHub upload URL (for GitHub Enterprise)
 //   - commit_message: commit message template (default: "Update {path} via omnistorage")
+//   - commit_message_create: commit message template for new files
+//   - commit_message_update: commit message template for changed files
+//   - commit_message_delete: commit message template for deletes
+//   - commit_message_batch: commit message template for batches
 //   - commit_author_name: commit author name
 //   - commit_author_email: commit author email
+//   - commit_committer_name: committer name
+//   - commit_committer_email: committer email
+//   - commit_co_authors: comma-separated "Name <email>" co-authors
+//   - commit_signoff: "true" to add a Signed-off-by trailer
+//   - commit_engine: "rest" (default) or "graphql"
+//   - signing_key: GPG key ID, or SSH key file with signing_format "ssh"
+//   - signing_format: "openpgp" (default) or "ssh"
+//   - signing_program: gpg or ssh-keygen executable
+//   - disable_lfs: "true" to return LFS pointer files as-is
+//   - lfs_upload: "true" to upload filter=lfs paths to Git LFS on write
+//   - lfs_url: Git LFS server endpoint
+//   - gist_id: gist ID for the gist backend
+//   - disable_etags: "true" to turn off conditional requests
+//   - tree_cache: "true" to answer Exists, Stat and List from a cached tree
+//   - tree_cache_interval: how often the cached tree checks the branch head (default: "30s")
+//   - max_concurrency: concurrent batch requests (default: 4)
+//   - auto_batch: "true" to commit NewWriter writes together in batches
+//   - auto_batch_max_files: files that trigger an auto-batch commit (default: 100)
+//   - auto_batch_max_bytes: bytes that trigger an auto-batch commit (default: 10 MB)
+//   - auto_batch_window: delay after the first queued write before committing (default: "5s")
+//   - stat_mod_time: "true" to report the last commit time as ModTime
+//   - stat_file_mode: "true" to report the Git mode of files without the tree cache
+//   - stat_content_sha1: "true" to report the content SHA-1 of every file
+//   - cache_size: maximum bytes of content to cache in memory, or on disk with cache_dir
+//   - cache_dir: directory for an on-disk content cache
 func ConfigFromMap(m map[string]string) Config {
 	cfg := DefaultConfig()
 
@@ -132,6 +331,97 @@ func ConfigFromMap(m map[string]string) Config {
 	if v, ok := m["commit_message"]; ok && v != "" {
 		cfg.CommitMessage = v
 	}
+	if v, ok := m["commit_message_create"]; ok {
+		cfg.CommitMessageCreate = v
+	}
+	if v, ok := m["commit_message_update"]; ok {
+		cfg.CommitMessageUpdate = v
+	}
+	if v, ok := m["commit_message_delete"]; ok {
+		cfg.CommitMessageDelete = v
+	}
+	if v, ok := m["commit_message_batch"]; ok {
+		cfg.CommitMessageBatch = v
+	}
+	if v, ok := m["commit_engine"]; ok && v != "" {
+		cfg.CommitEngine = CommitEngine(v)
+	}
+	if v, ok := m["disable_lfs"]; ok {
+		cfg.DisableLFS = v == "true"
+	}
+	if v, ok := m["lfs_upload"]; ok {
+		cfg.LFSUpload = v == "true"
+	}
+	if v, ok := m["lfs_url"]; ok && v != "" {
+		cfg.LFSURL = v
+	}
+	if v, ok := m["gist_id"]; ok {
+		cfg.GistID = v
+	}
+	if v, ok := m["disable_etags"]; ok {
+		cfg.DisableETags = v == "true"
+	}
+	if v, ok := m["tree_cache"]; ok {
+		cfg.TreeCache = v == "true"
+	}
+	if v, ok := m["stat_mod_time"]; ok {
+		cfg.StatModTime = v == "true"
+	}
+	if v, ok := m["stat_file_mode"]; ok {
+		cfg.StatFileMode = v == "true"
+	}
+	if v, ok := m["stat_content_sha1"]; ok {
+		cfg.StatContentSHA1 = v == "true"
+	}
+	if v, err := time.ParseDuration(m["tree_cache_interval"]); err == nil {
+		cfg.TreeCacheInterval = v
+	}
+	if v, err := strconv.Atoi(m["max_concurrency"]); err == nil {
+		cfg.MaxConcurrency = v
+	}
+	if v, ok := m["auto_batch"]; ok {
+		cfg.AutoBatch = v == "true"
+	}
+	if v, err := strconv.Atoi(m["auto_batch_max_files"]); err == nil {
+		cfg.AutoBatchMaxFiles = v
+	}
+	if v, err := strconv.ParseInt(m["auto_batch_max_bytes"], 10, 64); err == nil {
+		cfg.AutoBatchMaxBytes = v
+	}
+	if v, err := time.ParseDuration(m["auto_batch_window"]); err == nil {
+		cfg.AutoBatchWindow = v
+	}
+
+	// Content cache
+	cacheSize, _ := strconv.ParseInt(m["cache_size"], 10, 64)
+	if dir := m["cache_dir"]; dir != "" {
+		cfg.Cache = NewDiskCache(dir, cacheSize)
+	} else if cacheSize > 0 {
+		cfg.Cache = NewMemoryCache(cacheSize)
+	}
+
+	// Committer and trailers
+	committerName := m["commit_committer_name"]
+	committerEmail := m["commit_committer_email"]
+	if committerName != "" || committerEmail != "" {
+		cfg.CommitCommitter = &CommitAuthor{
+			Name:  committerName,
+			Email: committerEmail,
+		}
+	}
+	cfg.CommitCoAuthors = parseIdentities(m["commit_co_authors"])
+	if v, ok := m["commit_signoff"]; ok {
+		cfg.CommitSignOff = v == "true"
+	}
+
+	// Commit signing
+	if key := m["signing_key"]; key != "" {
+		if m["signing_format"] == "ssh" {
+			cfg.CommitSigner = &SSHSigner{KeyFile: key, Program: m["signing_program"]}
+		} else {
+			cfg.CommitSigner = &GPGSigner{KeyID: key, Program: m["signing_program"]}
+		}
+	}
 
 	// Commit author
 	authorName := m["commit_author_name"]
@@ -157,6 +447,10 @@ func ConfigFromMap(m map[string]string) Config {
 //   - OMNISTORAGE_GITHUB_COMMIT_MESSAGE: commit message template
 //   - OMNISTORAGE_GITHUB_COMMIT_AUTHOR_NAME: commit author name
 //   - OMNISTORAGE_GITHUB_COMMIT_AUTHOR_EMAIL: commit author email
+//   - OMNISTORAGE_GITHUB_COMMIT_COMMITTER_NAME: committer name
+//   - OMNISTORAGE_GITHUB_COMMIT_COMMITTER_EMAIL: committer email
+//   - OMNISTORAGE_GITHUB_COMMIT_SIGNOFF: "true" to add a Signed-off-by trailer
+//   - OMNISTORAGE_GITHUB_GIST_ID: gist ID for the gist backend
 func ConfigFromEnv() Config {
 	cfg := DefaultConfig()
 
@@ -203,6 +497,11 @@ func ConfigFromEnv() Config {
 		cfg.CommitMessage = v
 	}
 
+	// Gist ID
+	if v := os.Getenv("OMNISTORAGE_GITHUB_GIST_ID"); v != "" {
+		cfg.GistID = v
+	}
+
 	// Commit author
 	authorName := os.Getenv("OMNISTORAGE_GITHUB_COMMIT_AUTHOR_NAME")
 	authorEmail := os.Getenv("OMNISTORAGE_GITHUB_COMMIT_AUTHOR_EMAIL")
@@ -213,5 +512,18 @@ func ConfigFromEnv() Config {
 		}
 	}
 
+	// Committer
+	committerName := os.Getenv("OMNISTORAGE_GITHUB_COMMIT_COMMITTER_NAME")
+	committerEmail := os.Getenv("OMNISTORAGE_GITHUB_COMMIT_COMMITTER_EMAIL")
+	if committerName != "" || committerEmail != "" {
+		cfg.CommitCommitter = &CommitAuthor{
+			Name:  committerName,
+			Email: committerEmail,
+		}
+	}
+
+	// Sign-off
+	cfg.CommitSignOff = os.Getenv("OMNISTORAGE_GITHUB_COMMIT_SIGNOFF") == "true"
+
 	return cfg
 }
@@ -0,0 +1,457 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"testing"
+	"time"
+
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+var _ omnistorage.ExtendedBackend = (*Backend)(nil)
+
+// conformanceCase is a check of the omnistorage.ExtendedBackend contract.
+// Each case gets a fresh backend and a unique directory to work in.
+type conformanceCase struct {
+	name string
+	run  func(t *testing.T, ctx context.Context, backend *Backend, dir string)
+}
+
+// binaryContent holds every byte value, which is not valid UTF-8.
+var binaryContent = func() []byte {
+	data := make([]byte, 256)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	return data
+}()
+
+var conformanceCases = []conformanceCase{
+	// NewWriter and NewReader
+
+	{"write then read", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/file.txt", []byte("hello"))
+		assertContent(t, ctx, backend, dir+"/file.txt", []byte("hello"))
+	}},
+	{"write binary", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/file.bin", binaryContent)
+		assertContent(t, ctx, backend, dir+"/file.bin", binaryContent)
+	}},
+	{"write empty", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/empty.txt", nil)
+		assertContent(t, ctx, backend, dir+"/empty.txt", []byte{})
+	}},
+	{"overwrite replaces content", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/file.txt", []byte("first version"))
+		mustWrite(t, ctx, backend, dir+"/file.txt", []byte("second"))
+		assertContent(t, ctx, backend, dir+"/file.txt", []byte("second"))
+	}},
+	{"write normalizes leading slash", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, "/"+dir+"/file.txt", []byte("hello"))
+		assertContent(t, ctx, backend, dir+"/file.txt", []byte("hello"))
+	}},
+	{"writer rejects write after close", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		w, err := backend.NewWriter(ctx, dir+"/file.txt")
+		if err != nil {
+			t.Fatalf("NewWriter failed: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if _, err := w.Write([]byte("late")); err != omnistorage.ErrWriterClosed {
+			t.Errorf("Write after Close = %v, want ErrWriterClosed", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Errorf("second Close = %v, want nil", err)
+		}
+	}},
+	{"writer commits only on close", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		w, err := backend.NewWriter(ctx, dir+"/file.txt")
+		if err != nil {
+			t.Fatalf("NewWriter failed: %v", err)
+		}
+		_, _ = w.Write([]byte("pending"))
+		assertExists(t, ctx, backend, dir+"/file.txt", false)
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		assertExists(t, ctx, backend, dir+"/file.txt", true)
+	}},
+	{"reader offset", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/file.txt", []byte("0123456789"))
+		assertContent(t, ctx, backend, dir+"/file.txt", []byte("3456789"), omnistorage.WithOffset(3))
+	}},
+	{"reader limit", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/file.txt", []byte("0123456789"))
+		assertContent(t, ctx, backend, dir+"/file.txt", []byte("0123"), omnistorage.WithLimit(4))
+	}},
+	{"reader offset and limit", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/file.txt", []byte("0123456789"))
+		assertContent(t, ctx, backend, dir+"/file.txt", []byte("234"), omnistorage.WithOffset(2), omnistorage.WithLimit(3))
+	}},
+	{"reader limit past end", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/file.txt", []byte("0123456789"))
+		assertContent(t, ctx, backend, dir+"/file.txt", []byte("89"), omnistorage.WithOffset(8), omnistorage.WithLimit(10))
+	}},
+	{"reader offset past end", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/file.txt", []byte("0123456789"))
+		assertContent(t, ctx, backend, dir+"/file.txt", []byte{}, omnistorage.WithOffset(10))
+	}},
+	{"reader missing path", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		if _, err := backend.NewReader(ctx, dir+"/missing.txt"); err != omnistorage.ErrNotFound {
+			t.Errorf("NewReader = %v, want ErrNotFound", err)
+		}
+	}},
+	{"reader directory", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/sub/file.txt", []byte("hello"))
+		if _, err := backend.NewReader(ctx, dir+"/sub"); err == nil {
+			t.Error("NewReader on a directory should fail")
+		}
+	}},
+
+	// Path validation
+
+	{"invalid paths", func(t *testing.T, ctx context.Context, backend *Backend, _ string) {
+		for _, p := range []string{"", "../escape.txt", "a/../../escape.txt"} {
+			if _, err := backend.NewWriter(ctx, p); err != omnistorage.ErrInvalidPath {
+				t.Errorf("NewWriter(%q) = %v, want ErrInvalidPath", p, err)
+			}
+			if err := backend.Delete(ctx, p); err != omnistorage.ErrInvalidPath {
+				t.Errorf("Delete(%q) = %v, want ErrInvalidPath", p, err)
+			}
+			if p == "" {
+				continue // the empty path is the repository root for reads
+			}
+			if _, err := backend.NewReader(ctx, p); err != omnistorage.ErrInvalidPath {
+				t.Errorf("NewReader(%q) = %v, want ErrInvalidPath", p, err)
+			}
+			if _, err := backend.Exists(ctx, p); err != omnistorage.ErrInvalidPath {
+				t.Errorf("Exists(%q) = %v, want ErrInvalidPath", p, err)
+			}
+			if _, err := backend.Stat(ctx, p); err != omnistorage.ErrInvalidPath {
+				t.Errorf("Stat(%q) = %v, want ErrInvalidPath", p, err)
+			}
+		}
+	}},
+
+	// Exists
+
+	{"exists", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/sub/file.txt", []byte("hello"))
+		assertExists(t, ctx, backend, dir+"/sub/file.txt", true)
+		assertExists(t, ctx, backend, dir+"/sub", true)
+		assertExists(t, ctx, backend, dir+"/missing.txt", false)
+	}},
+
+	// Delete
+
+	{"delete", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/file.txt", []byte("hello"))
+		mustWrite(t, ctx, backend, dir+"/keep.txt", []byte("keep"))
+		if err := backend.Delete(ctx, dir+"/file.txt"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		assertExists(t, ctx, backend, dir+"/file.txt", false)
+		assertExists(t, ctx, backend, dir+"/keep.txt", true)
+	}},
+	{"delete is idempotent", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/file.txt", []byte("hello"))
+		for i := 0; i < 2; i++ {
+			if err := backend.Delete(ctx, dir+"/file.txt"); err != nil {
+				t.Fatalf("Delete #%d failed: %v", i+1, err)
+			}
+		}
+		if err := backend.Delete(ctx, dir+"/never-existed.txt"); err != nil {
+			t.Errorf("Delete of missing path = %v, want nil", err)
+		}
+	}},
+
+	// List
+
+	{"list prefix", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/a.txt", []byte("a"))
+		mustWrite(t, ctx, backend, dir+"/sub/b.txt", []byte("b"))
+		mustWrite(t, ctx, backend, dir+"/sub/deep/c.txt", []byte("c"))
+
+		paths := mustList(t, ctx, backend, dir)
+		want := []string{dir + "/a.txt", dir + "/sub/b.txt", dir + "/sub/deep/c.txt"}
+		if fmt.Sprint(paths) != fmt.Sprint(want) {
+			t.Errorf("List(%q) = %v, want %v", dir, paths, want)
+		}
+
+		paths = mustList(t, ctx, backend, dir+"/sub/")
+		want = []string{dir + "/sub/b.txt", dir + "/sub/deep/c.txt"}
+		if fmt.Sprint(paths) != fmt.Sprint(want) {
+			t.Errorf("List(%q) = %v, want %v", dir+"/sub/", paths, want)
+		}
+	}},
+	{"list missing prefix", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		if paths := mustList(t, ctx, backend, dir+"/missing"); len(paths) != 0 {
+			t.Errorf("List = %v, want empty", paths)
+		}
+	}},
+	{"list reflects delete", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/a.txt", []byte("a"))
+		mustWrite(t, ctx, backend, dir+"/b.txt", []byte("b"))
+		if err := backend.Delete(ctx, dir+"/a.txt"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if paths := mustList(t, ctx, backend, dir); fmt.Sprint(paths) != fmt.Sprint([]string{dir + "/b.txt"}) {
+			t.Errorf("List = %v, want [%s/b.txt]", paths, dir)
+		}
+	}},
+
+	// Stat
+
+	{"stat file", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/file.txt", []byte("hello"))
+		info, err := backend.Stat(ctx, dir+"/file.txt")
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if info.Path() != dir+"/file.txt" || info.Size() != 5 || info.IsDir() {
+			t.Errorf("Stat = {path %q, size %d, dir %v}, want {%q, 5, false}", info.Path(), info.Size(), info.IsDir(), dir+"/file.txt")
+		}
+		for _, h := range backend.Features().Hashes {
+			if info.Hash(h) == "" {
+				t.Errorf("Features claims %s but Stat has no %s hash", h, h)
+			}
+		}
+	}},
+	{"stat hash changes with content", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/file.txt", []byte("first"))
+		before, err := backend.Stat(ctx, dir+"/file.txt")
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		mustWrite(t, ctx, backend, dir+"/file.txt", []byte("second"))
+		after, err := backend.Stat(ctx, dir+"/file.txt")
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if before.Hash(HashGitBlob) == after.Hash(HashGitBlob) {
+			t.Error("Expected blob hash to change with content")
+		}
+	}},
+	{"stat directory", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/sub/file.txt", []byte("hello"))
+		info, err := backend.Stat(ctx, dir+"/sub")
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if !info.IsDir() {
+			t.Error("Expected IsDir for a directory")
+		}
+	}},
+	{"stat missing path", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		if _, err := backend.Stat(ctx, dir+"/missing.txt"); err != omnistorage.ErrNotFound {
+			t.Errorf("Stat = %v, want ErrNotFound", err)
+		}
+	}},
+
+	// Features
+
+	{"features match behaviour", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/file.txt", []byte("hello"))
+		features := backend.Features()
+
+		ops := []struct {
+			name      string
+			supported bool
+			call      func() error
+		}{
+			{"Copy", features.Copy, func() error { return backend.Copy(ctx, dir+"/file.txt", dir+"/copy.txt") }},
+			{"Move", features.Move, func() error { return backend.Move(ctx, dir+"/file.txt", dir+"/moved.txt") }},
+			{"Mkdir", features.Mkdir, func() error { return backend.Mkdir(ctx, dir+"/newdir") }},
+			{"Rmdir", features.Rmdir, func() error { return backend.Rmdir(ctx, dir+"/newdir") }},
+			{"Stat", features.Stat, func() error { _, err := backend.Stat(ctx, dir+"/file.txt"); return err }},
+		}
+		for _, op := range ops {
+			err := op.call()
+			if op.supported && errors.Is(err, omnistorage.ErrNotSupported) {
+				t.Errorf("Features claims %s but it returned ErrNotSupported", op.name)
+			}
+			if !op.supported && !errors.Is(err, omnistorage.ErrNotSupported) {
+				t.Errorf("Features denies %s but it returned %v", op.name, err)
+			}
+		}
+
+		if features.RangeRead {
+			assertContent(t, ctx, backend, dir+"/file.txt", []byte("ell"), omnistorage.WithOffset(1), omnistorage.WithLimit(3))
+		}
+		if features.ListPrefix {
+			if paths := mustList(t, ctx, backend, dir+"/file"); fmt.Sprint(paths) != fmt.Sprint([]string{dir + "/file.txt"}) {
+				t.Errorf("List = %v, want [%s/file.txt]", paths, dir)
+			}
+		}
+	}},
+
+	// Lifecycle
+
+	{"closed backend", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		if err := backend.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if err := backend.Close(); err != nil {
+			t.Errorf("second Close = %v, want nil", err)
+		}
+
+		p := dir + "/file.txt"
+		calls := map[string]func() error{
+			"NewWriter": func() error { _, err := backend.NewWriter(ctx, p); return err },
+			"NewReader": func() error { _, err := backend.NewReader(ctx, p); return err },
+			"Exists":    func() error { _, err := backend.Exists(ctx, p); return err },
+			"Delete":    func() error { return backend.Delete(ctx, p) },
+			"List":      func() error { _, err := backend.List(ctx, dir); return err },
+			"Stat":      func() error { _, err := backend.Stat(ctx, p); return err },
+			"Mkdir":     func() error { return backend.Mkdir(ctx, p) },
+			"Rmdir":     func() error { return backend.Rmdir(ctx, p) },
+			"Copy":      func() error { return backend.Copy(ctx, p, p+".copy") },
+			"Move":      func() error { return backend.Move(ctx, p, p+".moved") },
+			"NewBatch":  func() error { _, err := backend.NewBatch(ctx, "closed"); return err },
+		}
+		for name, call := range calls {
+			if err := call(); err != omnistorage.ErrBackendClosed {
+				t.Errorf("%s after Close = %v, want ErrBackendClosed", name, err)
+			}
+		}
+	}},
+	{"canceled context", func(t *testing.T, _ context.Context, backend *Backend, dir string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		p := dir + "/file.txt"
+		calls := map[string]func() error{
+			"NewWriter": func() error { _, err := backend.NewWriter(ctx, p); return err },
+			"NewReader": func() error { _, err := backend.NewReader(ctx, p); return err },
+			"Exists":    func() error { _, err := backend.Exists(ctx, p); return err },
+			"Delete":    func() error { return backend.Delete(ctx, p) },
+			"List":      func() error { _, err := backend.List(ctx, dir); return err },
+			"Stat":      func() error { _, err := backend.Stat(ctx, p); return err },
+			"NewBatch":  func() error { _, err := backend.NewBatch(ctx, "canceled"); return err },
+		}
+		for name, call := range calls {
+			if err := call(); !errors.Is(err, context.Canceled) {
+				t.Errorf("%s with canceled context = %v, want context.Canceled", name, err)
+			}
+		}
+	}},
+
+	// Batch
+
+	{"batch write and delete", func(t *testing.T, ctx context.Context, backend *Backend, dir string) {
+		mustWrite(t, ctx, backend, dir+"/old.txt", []byte("old"))
+
+		batch, err := backend.NewBatch(ctx, "Conformance batch")
+		if err != nil {
+			t.Fatalf("NewBatch failed: %v", err)
+		}
+		_ = batch.Write(dir+"/text.txt", []byte("text"))
+		_ = batch.Write(dir+"/file.bin", binaryContent)
+		_ = batch.Delete(dir + "/old.txt")
+		_ = batch.Delete(dir + "/missing.txt")
+		if _, err := batch.Commit(); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+
+		assertContent(t, ctx, backend, dir+"/text.txt", []byte("text"))
+		assertContent(t, ctx, backend, dir+"/file.bin", binaryContent)
+		assertExists(t, ctx, backend, dir+"/old.txt", false)
+	}},
+}
+
+// conformanceVariants are the backend configurations the suite runs against.
+var conformanceVariants = []struct {
+	name      string
+	configure func(cfg *Config)
+}{
+	{"default", func(*Config) {}},
+	{"cache", func(cfg *Config) { cfg.Cache = NewMemoryCache(0) }},
+	{"tree cache", func(cfg *Config) { cfg.TreeCache = true }},
+	{"graphql", func(cfg *Config) { cfg.CommitEngine = CommitEngineGraphQL }},
+}
+
+// TestConformance checks the omnistorage.ExtendedBackend contract against
+// the fake GitHub server, or against the write test repository if enabled.
+func TestConformance(t *testing.T) {
+	for _, variant := range conformanceVariants {
+		t.Run(variant.name, func(t *testing.T) {
+			for _, tc := range conformanceCases {
+				t.Run(tc.name, func(t *testing.T) {
+					backend := writeTestBackend(t)
+					defer func() { _ = backend.Close() }()
+					variant.configure(&backend.config)
+
+					ctx := context.Background()
+					dir := fmt.Sprintf("test/conformance-%d", time.Now().UnixNano())
+
+					tc.run(t, ctx, backend, dir)
+
+					// Clean up anything left behind in a real repository
+					if paths, err := backend.List(ctx, dir+"/"); err == nil {
+						for _, p := range paths {
+							_ = backend.Delete(ctx, p)
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+func mustWrite(t *testing.T, ctx context.Context, backend *Backend, p string, content []byte) {
+	t.Helper()
+	w, err := backend.NewWriter(ctx, p)
+	if err != nil {
+		t.Fatalf("NewWriter(%q) failed: %v", p, err)
+	}
+	if _, err := w.Write(content); err != nil {
+		t.Fatalf("Write(%q) failed: %v", p, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close(%q) failed: %v", p, err)
+	}
+}
+
+func mustList(t *testing.T, ctx context.Context, backend *Backend, prefix string) []string {
+	t.Helper()
+	paths, err := backend.List(ctx, prefix)
+	if err != nil {
+		t.Fatalf("List(%q) failed: %v", prefix, err)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func assertContent(t *testing.T, ctx context.Context, backend *Backend, p string, want []byte, opts ...omnistorage.ReaderOption) {
+	t.Helper()
+	r, err := backend.NewReader(ctx, p, opts...)
+	if err != nil {
+		t.Fatalf("NewReader(%q) failed: %v", p, err)
+	}
+	defer func() { _ = r.Close() }()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll(%q) failed: %v", p, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("content of %q = %q, want %q", p, got, want)
+	}
+}
+
+func assertExists(t *testing.T, ctx context.Context, backend *Backend, p string, want bool) {
+	t.Helper()
+	exists, err := backend.Exists(ctx, p)
+	if err != nil {
+		t.Fatalf("Exists(%q) failed: %v", p, err)
+	}
+	if exists != want {
+		t.Errorf("Exists(%q) = %v, want %v", p, exists, want)
+	}
+}
@@ -0,0 +1,123 @@
+package github
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// etagCacheMaxBytes bounds the response bodies kept for revalidation.
+const etagCacheMaxBytes = 16 << 20
+
+// conditionalPaths are the API path fragments whose GET responses are
+// revalidated with ETags: Contents, Trees and Refs.
+var conditionalPaths = []string{"/contents/", "/git/trees/", "/git/ref/", "/git/refs", "/git/matching-refs/"}
+
+// etagResponse is a remembered response body and its validator.
+type etagResponse struct {
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+// etagTransport is an http.RoundTripper that remembers the ETags of
+// Contents, Trees and Refs responses and revalidates them with conditional
+// requests. GitHub does not count 304 Not Modified responses against the
+// rate limit, so repeated reads of unchanged paths cost no quota. A 304 is
+// returned to the caller as the remembered 200 response.
+type etagTransport struct {
+	base http.RoundTripper
+	mu   sync.Mutex
+	lru  *lru
+}
+
+func newETagTransport(base http.RoundTripper) *etagTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &etagTransport{base: base, lru: newLRU(etagCacheMaxBytes)}
+}
+
+// conditional reports whether a request is eligible for revalidation.
+func conditional(req *http.Request) bool {
+	if req.Method != http.MethodGet || req.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	for _, fragment := range conditionalPaths {
+		if strings.Contains(req.URL.Path, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *etagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !conditional(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	// The media type selects the representation, so it is part of the key
+	key := req.URL.String() + " " + req.Header.Get("Accept")
+
+	t.mu.Lock()
+	entry, cached := t.lru.get(key)
+	t.mu.Unlock()
+
+	var remembered *etagResponse
+	if cached {
+		remembered = entry.value.(*etagResponse)
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", remembered.etag)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Serve the remembered body, with fresh rate limit headers
+	if remembered != nil && resp.StatusCode == http.StatusNotModified {
+		_ = resp.Body.Close()
+		header := remembered.header.Clone()
+		for name, values := range resp.Header {
+			header[name] = values
+		}
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(remembered.body)),
+			ContentLength: int64(len(remembered.body)),
+			Request:       req,
+		}, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.mu.Lock()
+	t.lru.remove(key)
+	t.lru.add(&lruEntry{
+		key:   key,
+		size:  int64(len(body)),
+		value: &etagResponse{etag: etag, header: resp.Header.Clone(), body: body},
+	})
+	t.mu.Unlock()
+
+	return resp, nil
+}
@@ -0,0 +1,107 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	ctx := context.Background()
+
+	// Stat, List and batch ref lookups are
+	// revalidated on repeat
+	for i := 0; i < 2; i++ {
+		if _, err := backend.Stat(ctx, "README.md"); err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if _, err := backend.List(ctx, ""); err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if _, _, err := backend.client.Git.GetRef(ctx, "grokify", "omnistorage", "heads/main"); err != nil {
+			t.Fatalf("GetRef failed: %v", err)
+		}
+	}
+	if n := srv.NotModified(); n != 3 {
+		t.Errorf("Expected 3 revalidated responses, got %d", n)
+	}
+
+	// A changed file is fetched again rather than served from the ETag cache
+	mustWrite(t, ctx, backend, "README.md", []byte("# Changed"))
+	assertContent(t, ctx, backend, "README.md", []byte("# Changed"))
+
+	info, err := backend.Stat(ctx, "README.md")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Size() != int64(len("# Changed")) {
+		t.Errorf("Stat size = %d, want %d", info.Size(), len("# Changed"))
+	}
+}
+
+func TestConditionalRequestsDisabled(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	backend.config.DisableETags = true
+	client, err := newClient(&backend.config)
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	backend.client = client
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := backend.Stat(ctx, "README.md"); err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+	}
+	if n := srv.NotModified(); n != 0 {
+		t.Errorf("Expected no conditional requests, got %d", n)
+	}
+}
+
+func TestETagTransport(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-RateLimit-Remaining", fmt.Sprint(5000-requests))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"path":"a.txt"}`))
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: newETagTransport(nil)}
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		var body [64]byte
+		n, _ := resp.Body.Read(body[:])
+		return resp, string(body[:n])
+	}
+
+	get("/repos/o/r/contents/a.txt")
+	resp, body := get("/repos/o/r/contents/a.txt")
+	if resp.StatusCode != http.StatusOK || body != `{"path":"a.txt"}` {
+		t.Errorf("revalidated response = %d %q", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("X-RateLimit-Remaining"); got != "4998" {
+		t.Errorf("X-RateLimit-Remaining = %q, want the 304's value 4998", got)
+	}
+
+	// Other endpoints are passed through unconditionally
+	get("/repos/o/r/pulls/1")
+	get("/repos/o/r/pulls/1")
+	if requests != 4 {
+		t.Errorf("Expected 4 requests, got %d", requests)
+	}
+}
@@ -0,0 +1,552 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/grokify/gogithub/pathutil"
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+const gistBackendName = "github-gist"
+
+// GistMetadataRevision is the ObjectInfo metadata key holding the
+// gist revision (version SHA) reported by Stat.
+const GistMetadataRevision = "revision"
+
+// ErrBinaryContent is returned when writing content that is not valid
+// UTF-8 to a gist, which only stores text.
+var ErrBinaryContent = errors.New("github: gist content must be valid UTF-8")
+
+func init() {
+	omnistorage.Register(gistBackendName, func(config map[string]string) (omnistorage.Backend, error) {
+		cfg := ConfigFromMap(config)
+		return NewGist(cfg)
+	})
+}
+
+// GistBackend implements omnistorage.ExtendedBackend on top of GitHub gists.
+//
+// If Config.GistID is set, the backend is a single gist and paths are file
+// names within it. Otherwise the backend spans the gists of Config.Owner
+// (or of the authenticated user) and paths have the form
+// "<gist-id>/<filename>". Gists are flat, so file names cannot contain "/".
+//
+// Every write or delete creates a new gist revision; revisions are
+// available through Versions and NewVersionReader.
+type GistBackend struct {
+	client *github.Client
+	config Config
+	closed bool
+	mu     sync.RWMutex
+}
+
+// gistWriter buffers content and updates the gist on Close.
+type gistWriter struct {
+	backend *GistBackend
+	ctx     context.Context
+	gistID  string
+	name    string
+	buffer  *bytes.Buffer
+	closed  bool
+	mu      sync.Mutex
+}
+
+// GistVersion is a revision of a gist.
+type GistVersion struct {
+	// Version is the revision SHA.
+	Version string
+
+	// CommittedAt is when the revision was created.
+	CommittedAt time.Time
+
+	// User is the login of the user who created the revision.
+	User string
+}
+
+// NewGist creates a new GitHub gist backend.
+func NewGist(cfg Config) (*GistBackend, error) {
+	if err := cfg.ValidateGist(); err != nil {
+		return nil, err
+	}
+
+	client, err := newClient(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GistBackend{
+		client: client,
+		config: cfg,
+	}, nil
+}
+
+// NewWriter creates a writer for the given gist file.
+// The content is buffered and written to the gist when Close() is called,
+// creating a new gist revision. Gists only store text content, so Close
+// returns ErrBinaryContent for content that is not valid UTF-8.
+func (b *GistBackend) NewWriter(ctx context.Context, filePath string, opts ...omnistorage.WriterOption) (io.WriteCloser, error) {
+	if err := b.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	gistID, name, err := b.resolve(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gistWriter{
+		backend: b,
+		ctx:     ctx,
+		gistID:  gistID,
+		name:    name,
+		buffer:  &bytes.Buffer{},
+	}, nil
+}
+
+// Write writes data to the buffer.
+func (w *gistWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, omnistorage.ErrWriterClosed
+	}
+
+	return w.buffer.Write(p)
+}
+
+// Close writes the buffered content to the gist.
+func (w *gistWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if err := w.backend.checkClosed(); err != nil {
+		return err
+	}
+
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+
+	// JSON encoding would replace invalid UTF-8 and corrupt the content
+	if !utf8.Valid(w.buffer.Bytes()) {
+		return fmt.Errorf("%w: %s", ErrBinaryContent, w.name)
+	}
+
+	_, resp, err := w.backend.client.Gists.Edit(w.ctx, w.gistID, &github.Gist{
+		Files: map[github.GistFilename]github.GistFile{
+			github.GistFilename(w.name): {
+				Content: github.Ptr(w.buffer.String()),
+			},
+		},
+	})
+	if err != nil {
+		return translateAPIError(err, resp)
+	}
+
+	return nil
+}
+
+// NewReader creates a reader for the latest revision of a gist file.
+func (b *GistBackend) NewReader(ctx context.Context, filePath string, opts ...omnistorage.ReaderOption) (io.ReadCloser, error) {
+	return b.NewVersionReader(ctx, filePath, "", opts...)
+}
+
+// NewVersionReader creates a reader for a gist file as of the given
+// revision. An empty version reads the latest revision.
+func (b *GistBackend) NewVersionReader(ctx context.Context, filePath, version string, opts ...omnistorage.ReaderOption) (io.ReadCloser, error) {
+	if err := b.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	gistID, name, err := b.resolve(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	gist, err := b.getGist(ctx, gistID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	file, ok := gist.Files[github.GistFilename(name)]
+	if !ok {
+		return nil, omnistorage.ErrNotFound
+	}
+
+	data, err := b.fileContent(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply reader options
+	cfg := omnistorage.ApplyReaderOptions(opts...)
+
+	// Handle offset
+	if cfg.Offset > 0 {
+		if cfg.Offset >= int64(len(data)) {
+			data = []byte{}
+		} else {
+			data = data[cfg.Offset:]
+		}
+	}
+
+	// Handle limit
+	if cfg.Limit > 0 && int64(len(data)) > cfg.Limit {
+		data = data[:cfg.Limit]
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Versions lists the revisions of the gist containing filePath, newest
+// first. Gist history is tracked per gist, so the list includes revisions
+// that did not change this particular file.
+func (b *GistBackend) Versions(ctx context.Context, filePath string) ([]GistVersion, error) {
+	if err := b.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	gistID, _, err := b.resolve(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []GistVersion
+	for commit, err := range b.client.Gists.ListCommitsIter(ctx, gistID, nil) {
+		if err != nil {
+			return nil, translateAPIError(err, nil)
+		}
+		versions = append(versions, GistVersion{
+			Version:     commit.GetVersion(),
+			CommittedAt: commit.GetCommittedAt().Time,
+			User:        commit.GetUser().GetLogin(),
+		})
+	}
+
+	return versions, nil
+}
+
+// Exists checks if a gist file exists.
+func (b *GistBackend) Exists(ctx context.Context, filePath string) (bool, error) {
+	_, err := b.Stat(ctx, filePath)
+	if err == omnistorage.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete removes a file from the gist, creating a new revision.
+// Returns nil if the file does not exist (idempotent).
+func (b *GistBackend) Delete(ctx context.Context, filePath string) error {
+	if err := b.checkClosed(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	gistID, name, err := b.resolve(filePath)
+	if err != nil {
+		return err
+	}
+
+	gist, err := b.getGist(ctx, gistID, "")
+	if err == omnistorage.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if _, ok := gist.Files[github.GistFilename(name)]; !ok {
+		return nil
+	}
+
+	// A file is removed by setting it to null, which github.GistFile
+	// cannot express, so the request body is built by hand.
+	body := map[string]any{
+		"files": map[string]any{name: nil},
+	}
+	req, err := b.client.NewRequest("PATCH", "gists/"+gistID, body)
+	if err != nil {
+		return fmt.Errorf("github: creating request: %w", err)
+	}
+
+	resp, err := b.client.Do(ctx, req, nil)
+	if err != nil {
+		return translateAPIError(err, resp)
+	}
+
+	return nil
+}
+
+// List lists gist file paths with the given prefix.
+func (b *GistBackend) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := b.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	normalPrefix := pathutil.Normalize(prefix)
+
+	var paths []string
+	appendFiles := func(gist *github.Gist, withID bool) {
+		for name := range gist.Files {
+			filePath := string(name)
+			if withID {
+				filePath = gist.GetID() + "/" + filePath
+			}
+			if normalPrefix != "" && !strings.HasPrefix(filePath, normalPrefix) {
+				continue
+			}
+			paths = append(paths, filePath)
+		}
+	}
+
+	if b.config.GistID != "" {
+		gist, err := b.getGist(ctx, b.config.GistID, "")
+		if err != nil {
+			return nil, err
+		}
+		appendFiles(&gist.Gist, false)
+		return paths, nil
+	}
+
+	for gist, err := range b.client.Gists.ListIter(ctx, b.config.Owner, nil) {
+		if err != nil {
+			return nil, translateAPIError(err, nil)
+		}
+		appendFiles(gist, true)
+	}
+
+	return paths, nil
+}
+
+// Close releases any resources held by the backend.
+func (b *GistBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+// Stat returns metadata about a gist file. The current gist revision is
+// reported under the GistMetadataRevision metadata key.
+func (b *GistBackend) Stat(ctx context.Context, filePath string) (omnistorage.ObjectInfo, error) {
+	if err := b.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	gistID, name, err := b.resolve(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	gist, err := b.getGist(ctx, gistID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	file, ok := gist.Files[github.GistFilename(name)]
+	if !ok {
+		return nil, omnistorage.ErrNotFound
+	}
+
+	// The latest revision is the first entry of the gist's history,
+	// which the API includes with the gist
+	metadata := map[string]string{}
+	if len(gist.History) > 0 {
+		metadata[GistMetadataRevision] = gist.History[0].GetVersion()
+	}
+
+	return &omnistorage.BasicObjectInfo{
+		ObjectPath:        pathutil.Normalize(filePath),
+		ObjectSize:        int64(file.GetSize()),
+		ObjectModTime:     gist.GetUpdatedAt().Time,
+		ObjectContentType: file.GetType(),
+		ObjectMetadata:    metadata,
+	}, nil
+}
+
+// Mkdir returns ErrNotSupported (gists are flat).
+func (b *GistBackend) Mkdir(ctx context.Context, filePath string) error {
+	if err := b.checkClosed(); err != nil {
+		return err
+	}
+	return omnistorage.ErrNotSupported
+}
+
+// Rmdir returns ErrNotSupported (gists are flat).
+func (b *GistBackend) Rmdir(ctx context.Context, filePath string) error {
+	if err := b.checkClosed(); err != nil {
+		return err
+	}
+	return omnistorage.ErrNotSupported
+}
+
+// Copy returns ErrNotSupported.
+func (b *GistBackend) Copy(ctx context.Context, src, dst string) error {
+	if err := b.checkClosed(); err != nil {
+		return err
+	}
+	return omnistorage.ErrNotSupported
+}
+
+// Move returns ErrNotSupported.
+func (b *GistBackend) Move(ctx context.Context, src, dst string) error {
+	if err := b.checkClosed(); err != nil {
+		return err
+	}
+	return omnistorage.ErrNotSupported
+}
+
+// Features returns the capabilities of the gist backend.
+func (b *GistBackend) Features() omnistorage.Features {
+	return omnistorage.Features{
+		Copy:                 false,
+		Move:                 false,
+		Mkdir:                false,
+		Rmdir:                false,
+		Stat:                 true,
+		CanStream:            false, // Must buffer entire file
+		ServerSideEncryption: false,
+		Versioning:           true, // Gist revisions
+		RangeRead:            true, // Implemented client-side
+		ListPrefix:           true,
+	}
+}
+
+// checkClosed returns an error if the backend is closed.
+func (b *GistBackend) checkClosed() error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return omnistorage.ErrBackendClosed
+	}
+	return nil
+}
+
+// resolve maps a path to a gist ID and file name.
+func (b *GistBackend) resolve(filePath string) (gistID, name string, err error) {
+	if err := pathutil.Validate(filePath); err != nil {
+		return "", "", translatePathError(err)
+	}
+
+	normalPath := pathutil.Normalize(filePath)
+
+	if b.config.GistID != "" {
+		if normalPath == "" || strings.Contains(normalPath, "/") {
+			return "", "", omnistorage.ErrInvalidPath
+		}
+		return b.config.GistID, normalPath, nil
+	}
+
+	gistID, name, ok := strings.Cut(normalPath, "/")
+	if !ok || gistID == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", omnistorage.ErrInvalidPath
+	}
+	return gistID, name, nil
+}
+
+// gistWithHistory is a gist as returned by the API for a single gist,
+// whose revision history github.Gist does not decode.
+type gistWithHistory struct {
+	github.Gist
+
+	// History lists the gist's revisions, newest first.
+	History []*github.GistCommit `json:"history,omitempty"`
+}
+
+// getGist fetches a gist, or a specific revision of it if version is set.
+func (b *GistBackend) getGist(ctx context.Context, gistID, version string) (*gistWithHistory, error) {
+	u := "gists/" + gistID
+	if version != "" {
+		u += "/" + version
+	}
+
+	req, err := b.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("github: creating request: %w", err)
+	}
+
+	gist := new(gistWithHistory)
+	resp, err := b.client.Do(ctx, req, gist)
+	if err != nil {
+		return nil, translateAPIError(err, resp)
+	}
+	return gist, nil
+}
+
+// fileContent returns a gist file's content. The API truncates content
+// of large files, in which case it is fetched from the raw URL.
+func (b *GistBackend) fileContent(ctx context.Context, file github.GistFile) ([]byte, error) {
+	content := file.GetContent()
+	if len(content) >= file.GetSize() || file.GetRawURL() == "" {
+		return []byte(content), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, file.GetRawURL(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("github: creating raw request: %w", err)
+	}
+
+	resp, err := b.client.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github: fetching raw gist file: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := github.CheckResponse(resp); err != nil {
+		return nil, translateAPIError(err, &github.Response{Response: resp})
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("github: reading raw gist file: %w", err)
+	}
+	return data, nil
+}
+
+// Ensure GistBackend implements interfaces.
+var (
+	_ omnistorage.Backend         = (*GistBackend)(nil)
+	_ omnistorage.ExtendedBackend = (*GistBackend)(nil)
+)
@@ -0,0 +1,270 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+// fakeGist is an in-memory gist with full revision history.
+type fakeGist struct {
+	mu        sync.Mutex
+	revisions []map[string]string // oldest first
+	listed    int                 // requests listing the commits
+}
+
+func (f *fakeGist) current() map[string]string {
+	if len(f.revisions) == 0 {
+		return map[string]string{}
+	}
+	return f.revisions[len(f.revisions)-1]
+}
+
+// encode writes revision i of the gist, with its history newest first.
+func (f *fakeGist) encode(w http.ResponseWriter, i int) {
+	out := map[string]any{}
+	if i >= 0 {
+		for name, content := range f.revisions[i] {
+			out[name] = map[string]any{"filename": name, "content": content, "size": len(content), "type": "text/plain"}
+		}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"id": "abc", "files": out, "history": f.history(i)})
+}
+
+// history returns the commits up to revision i, newest first.
+func (f *fakeGist) history(i int) []map[string]any {
+	var commits []map[string]any
+	for ; i >= 0; i-- {
+		commits = append(commits, map[string]any{"version": fmt.Sprintf("rev%d", i)})
+	}
+	return commits
+}
+
+func (f *fakeGist) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	const base = "/api/v3/gists/abc"
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == base:
+		f.encode(w, len(f.revisions)-1)
+
+	case r.Method == http.MethodGet && r.URL.Path == base+"/commits":
+		f.listed++
+		commits := f.history(len(f.revisions) - 1)
+		if r.URL.Query().Get("per_page") == "1" && len(commits) > 1 {
+			commits = commits[:1]
+		}
+		_ = json.NewEncoder(w).Encode(commits)
+
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, base+"/rev"):
+		var i int
+		_, _ = fmt.Sscanf(strings.TrimPrefix(r.URL.Path, base+"/rev"), "%d", &i)
+		f.encode(w, i)
+
+	case r.Method == http.MethodPatch && r.URL.Path == base:
+		var body struct {
+			Files map[string]*struct {
+				Content string `json:"content"`
+			} `json:"files"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		next := map[string]string{}
+		for name, content := range f.current() {
+			next[name] = content
+		}
+		for name, file := range body.Files {
+			if file == nil {
+				delete(next, name)
+			} else {
+				next[name] = file.Content
+			}
+		}
+		f.revisions = append(f.revisions, next)
+		f.encode(w, len(f.revisions)-1)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestGistBackendRoundTrip(t *testing.T) {
+	fake := &fakeGist{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	backend, err := NewGist(Config{
+		Token:     "token",
+		GistID:    "abc",
+		BaseURL:   srv.URL + "/",
+		UploadURL: srv.URL + "/",
+	})
+	if err != nil {
+		t.Fatalf("NewGist failed: %v", err)
+	}
+	defer func() { _ = backend.Close() }()
+
+	ctx := context.Background()
+
+	write := func(name, content string) {
+		t.Helper()
+		w, err := backend.NewWriter(ctx, name)
+		if err != nil {
+			t.Fatalf("NewWriter failed: %v", err)
+		}
+		_, _ = io.WriteString(w, content)
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+
+	write("settings.json", `{"v":1}`)
+	write("settings.json", `{"v":2}`)
+	write("notes.md", "hello")
+
+	r, err := backend.NewReader(ctx, "settings.json")
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	_ = r.Close()
+	if string(data) != `{"v":2}` {
+		t.Errorf("NewReader content = %q, want %q", data, `{"v":2}`)
+	}
+
+	info, err := backend.Stat(ctx, "settings.json")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Size() != 7 {
+		t.Errorf("Size = %d, want 7", info.Size())
+	}
+	if rev := info.Metadata()[GistMetadataRevision]; rev != "rev2" {
+		t.Errorf("revision = %q, want %q", rev, "rev2")
+	}
+	if fake.listed != 0 {
+		t.Errorf("Expected Stat to take the revision from the gist, listed commits %d times", fake.listed)
+	}
+
+	versions, err := backend.Versions(ctx, "settings.json")
+	if err != nil {
+		t.Fatalf("Versions failed: %v", err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("Expected 3 versions, got %d", len(versions))
+	}
+
+	r, err = backend.NewVersionReader(ctx, "settings.json", versions[2].Version)
+	if err != nil {
+		t.Fatalf("NewVersionReader failed: %v", err)
+	}
+	data, _ = io.ReadAll(r)
+	_ = r.Close()
+	if string(data) != `{"v":1}` {
+		t.Errorf("NewVersionReader content = %q, want %q", data, `{"v":1}`)
+	}
+
+	paths, err := backend.List(ctx, "")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	sort.Strings(paths)
+	if fmt.Sprint(paths) != "[notes.md settings.json]" {
+		t.Errorf("List = %v, want [notes.md settings.json]", paths)
+	}
+
+	if err := backend.Delete(ctx, "notes.md"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := backend.Delete(ctx, "notes.md"); err != nil {
+		t.Errorf("Delete should be idempotent, got: %v", err)
+	}
+	if _, err := backend.Stat(ctx, "notes.md"); err != omnistorage.ErrNotFound {
+		t.Errorf("Expected ErrNotFound after Delete, got: %v", err)
+	}
+}
+
+func TestGistBackendBinaryContent(t *testing.T) {
+	fake := &fakeGist{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	backend, err := NewGist(Config{
+		Token:     "token",
+		GistID:    "abc",
+		BaseURL:   srv.URL + "/",
+		UploadURL: srv.URL + "/",
+	})
+	if err != nil {
+		t.Fatalf("NewGist failed: %v", err)
+	}
+	defer func() { _ = backend.Close() }()
+
+	w, err := backend.NewWriter(context.Background(), "image.png")
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	_, _ = w.Write([]byte{0x89, 'P', 'N', 'G', 0xff, 0xfe})
+	if err := w.Close(); !errors.Is(err, ErrBinaryContent) {
+		t.Errorf("Expected ErrBinaryContent, got: %v", err)
+	}
+	if len(fake.revisions) != 0 {
+		t.Errorf("Expected no gist revision, got %d", len(fake.revisions))
+	}
+}
+
+func TestGistBackendPaths(t *testing.T) {
+	single, err := NewGist(Config{Token: "token", GistID: "abc"})
+	if err != nil {
+		t.Fatalf("NewGist failed: %v", err)
+	}
+	multi, err := NewGist(Config{Token: "token", Owner: "octocat"})
+	if err != nil {
+		t.Fatalf("NewGist failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		backend *GistBackend
+		path    string
+		gistID  string
+		file    string
+		wantErr bool
+	}{
+		{"single file", single, "config.yaml", "abc", "config.yaml", false},
+		{"single nested", single, "dir/config.yaml", "", "", true},
+		{"multi file", multi, "def/config.yaml", "def", "config.yaml", false},
+		{"multi missing gist", multi, "config.yaml", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gistID, file, err := tt.backend.resolve(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolve(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if gistID != tt.gistID || file != tt.file {
+				t.Errorf("resolve(%q) = %q, %q, want %q, %q", tt.path, gistID, file, tt.gistID, tt.file)
+			}
+		})
+	}
+}
+
+func TestNewGistRequiresToken(t *testing.T) {
+	if _, err := NewGist(Config{GistID: "abc"}); err != ErrTokenRequired {
+		t.Errorf("Expected ErrTokenRequired, got: %v", err)
+	}
+}
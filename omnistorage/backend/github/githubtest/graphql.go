@@ -0,0 +1,185 @@
+package githubtest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// webFlowIdentity is the committer of commits GitHub creates and signs.
+var webFlowIdentity = identity{name: "GitHub", email: "noreply@github.com"}
+
+// webFlowSignature stands in for the signature GitHub adds to commits made
+// through createCommitOnBranch.
+const webFlowSignature = "-----BEGIN PGP SIGNATURE-----\n\ngithubtest web-flow signature\n-----END PGP SIGNATURE-----\n"
+
+// graphQL serves the GraphQL API. Only the createCommitOnBranch mutation
+// and history queries are supported.
+func (s *Server) graphQL(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Query     string                     `json:"query"`
+		Variables map[string]json.RawMessage `json:"variables"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	switch {
+	case strings.Contains(body.Query, "createCommitOnBranch"):
+	case strings.Contains(body.Query, "history("):
+		s.historyQuery(w, body.Query, body.Variables)
+		return
+	default:
+		writeGraphQLError(w, "", "githubtest: unsupported GraphQL query")
+		return
+	}
+
+	var input createCommitOnBranchInput
+	if err := json.Unmarshal(body.Variables["input"], &input); err != nil {
+		writeGraphQLError(w, "", "githubtest: invalid input: "+err.Error())
+		return
+	}
+	sha, errType, err := s.createCommitOnBranch(input)
+	if err != nil {
+		writeGraphQLError(w, errType, err.Error())
+		return
+	}
+
+	c := s.store.commits[sha]
+	writeJSON(w, http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"createCommitOnBranch": map[string]any{
+				"commit": map[string]any{
+					"oid":  c.sha,
+					"url":  s.htmlURL("commit/%s", c.sha),
+					"tree": map[string]string{"oid": c.tree},
+				},
+			},
+		},
+	})
+}
+
+// createCommitOnBranchInput is the input of the createCommitOnBranch
+// mutation.
+type createCommitOnBranchInput struct {
+	Branch struct {
+		RepositoryNameWithOwner string `json:"repositoryNameWithOwner"`
+		BranchName              string `json:"branchName"`
+	} `json:"branch"`
+	Message struct {
+		Headline string `json:"headline"`
+		Body     string `json:"body"`
+	} `json:"message"`
+	ExpectedHeadOid string `json:"expectedHeadOid"`
+	FileChanges     struct {
+		Additions []struct {
+			Path     string `json:"path"`
+			Contents string `json:"contents"`
+		} `json:"additions"`
+		Deletions []struct {
+			Path string `json:"path"`
+		} `json:"deletions"`
+	} `json:"fileChanges"`
+}
+
+// createCommitOnBranch applies the mutation and returns the new commit SHA,
+// or the GraphQL error type and message.
+func (s *Server) createCommitOnBranch(input createCommitOnBranchInput) (string, string, error) {
+	if !strings.EqualFold(input.Branch.RepositoryNameWithOwner, s.owner+"/"+s.repo) {
+		return "", "NOT_FOUND", fmt.Errorf("Could not resolve to a Repository with the name '%s'.", input.Branch.RepositoryNameWithOwner)
+	}
+
+	ref := "refs/heads/" + input.Branch.BranchName
+	head, ok := s.store.refs[ref]
+	if !ok {
+		return "", "NOT_FOUND", fmt.Errorf("Could not resolve to a Ref with the name '%s'.", ref)
+	}
+	if head != input.ExpectedHeadOid {
+		return "", "STALE_DATA", fmt.Errorf("Expected branch to point to %q but it did not. Pull and try again.", input.ExpectedHeadOid)
+	}
+
+	files := s.store.flatten(s.store.commits[head].tree)
+	for _, deletion := range input.FileChanges.Deletions {
+		if _, ok := files[deletion.Path]; !ok {
+			return "", "UNPROCESSABLE", fmt.Errorf("A path was requested for deletion which does not exist as of commit oid `%s`", head)
+		}
+		delete(files, deletion.Path)
+	}
+	for _, addition := range input.FileChanges.Additions {
+		content, err := base64.StdEncoding.DecodeString(addition.Contents)
+		if err != nil {
+			return "", "UNPROCESSABLE", fmt.Errorf("contents of %s are not valid base64", addition.Path)
+		}
+		files[addition.Path] = treeEntry{name: addition.Path, mode: modeFile, typ: "blob", sha: s.store.putBlob(content)}
+	}
+
+	message := input.Message.Headline
+	if input.Message.Body != "" {
+		message += "\n\n" + input.Message.Body
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	author, committer := defaultIdentity, webFlowIdentity
+	author.date, committer.date = now, now
+
+	sha := s.store.putCommit(&commitObject{
+		tree:      s.store.buildTree(files),
+		parents:   []string{head},
+		author:    author,
+		committer: committer,
+		message:   message,
+		signature: webFlowSignature,
+	})
+	s.store.refs[ref] = sha
+	return sha, "", nil
+}
+
+// historyField matches an aliased history field of a query, such as
+// `p0: history(first: 1, path: $p0)`.
+var historyField = regexp.MustCompile(`(\w+): history\(first: (\d+), path: \$(\w+)\)`)
+
+// historyQuery answers a query for the last commits of paths, with one
+// aliased history field per path on the commit named by the $expression
+// variable.
+func (s *Server) historyQuery(w http.ResponseWriter, query string, variables map[string]json.RawMessage) {
+	var expression string
+	_ = json.Unmarshal(variables["expression"], &expression)
+	c, ok := s.store.resolveCommit(expression)
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]any{"data": map[string]any{"repository": map[string]any{"object": nil}}})
+		return
+	}
+
+	object := map[string]any{}
+	for _, m := range historyField.FindAllStringSubmatch(query, -1) {
+		alias, variable := m[1], m[3]
+		first, _ := strconv.Atoi(m[2])
+		var filePath string
+		_ = json.Unmarshal(variables[variable], &filePath)
+
+		nodes := []map[string]any{}
+		for _, commit := range s.store.history(c.sha, filePath) {
+			if len(nodes) == first {
+				break
+			}
+			nodes = append(nodes, map[string]any{
+				"oid":           commit.sha,
+				"committedDate": commit.committer.date.Format(time.RFC3339),
+			})
+		}
+		object[alias] = map[string]any{"nodes": nodes}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"data": map[string]any{"repository": map[string]any{"object": object}}})
+}
+
+func writeGraphQLError(w http.ResponseWriter, errType, message string) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"data":   nil,
+		"errors": []map[string]string{{"type": errType, "message": message}},
+	})
+}
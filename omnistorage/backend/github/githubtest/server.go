@@ -0,0 +1,1111 @@
+// Package githubtest provides an in-memory fake of the GitHub REST API for
+// hermetic tests of the github backend and of code built on top of it.
+//
+// The fake serves a single repository and implements the Contents API, the
+// Git Data API (refs, commits, trees, blobs and tags), the commit list and
+// compare endpoints, release creation, and the GraphQL createCommitOnBranch mutation and
+// history queries. Objects are stored in a content-addressed store that
+// computes the same SHAs as Git, so blob SHAs returned by the fake match
+// the ones GitHub would return for the same content. As on GitHub, GET responses
+// carry an ETag and matching conditional requests get 304 Not Modified.
+//
+//	srv := githubtest.NewServer("owner", "repo")
+//	defer srv.Close()
+//
+//	backend, err := github.New(github.Config{
+//		Owner:   "owner",
+//		Repo:    "repo",
+//		Token:   "token",
+//		BaseURL: srv.BaseURL(),
+//	})
+package githubtest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// DefaultBranch is the branch created by NewServer.
+const DefaultBranch = "main"
+
+// apiPrefix is the path prefix used by GitHub Enterprise Server. Requests
+// are served with and without it.
+const apiPrefix = "/api/v3"
+
+// defaultIdentity is used when a request does not set an author or committer.
+var defaultIdentity = identity{name: "githubtest", email: "githubtest@example.com"}
+
+// Server is an in-memory fake of the GitHub REST API for a single repository.
+type Server struct {
+	*httptest.Server
+
+	owner string
+	repo  string
+
+	mu          sync.Mutex
+	store       *store
+	mux         *http.ServeMux
+	requests    []string
+	notModified int
+	treeLimit   int
+	releases    []*github.RepositoryRelease
+}
+
+// NewServer starts a fake GitHub server for owner/repo. The repository is
+// initialized with an empty commit on DefaultBranch.
+// The caller should call Close when finished, to shut it down.
+func NewServer(owner, repo string) *Server {
+	s := &Server{
+		owner: owner,
+		repo:  repo,
+		store: newStore(),
+		mux:   http.NewServeMux(),
+	}
+	s.routes()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	initial := &commitObject{
+		tree:      s.store.putTree(nil),
+		author:    identity{name: defaultIdentity.name, email: defaultIdentity.email, date: now},
+		committer: identity{name: defaultIdentity.name, email: defaultIdentity.email, date: now},
+		message:   "Initial commit",
+	}
+	s.store.refs["refs/heads/"+DefaultBranch] = s.store.putCommit(initial)
+
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// BaseURL returns the API base URL to use as Config.BaseURL and
+// Config.UploadURL.
+func (s *Server) BaseURL() string {
+	return s.URL + apiPrefix + "/"
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := strings.CutPrefix(r.URL.Path, apiPrefix); ok {
+		u := *r.URL
+		u.Path, u.RawPath = p, ""
+		r = r.Clone(r.Context())
+		r.URL = &u
+	}
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	if r.Method != http.MethodGet {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+
+	// Like GitHub, tag responses with an ETag and answer matching
+	// conditional requests with 304 Not Modified
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, r)
+	for name, values := range rec.Header() {
+		w.Header()[name] = values
+	}
+	if rec.Code == http.StatusOK {
+		sum := sha256.Sum256(rec.Body.Bytes())
+		etag := `W/"` + hex.EncodeToString(sum[:]) + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			s.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.WriteHeader(rec.Code)
+	_, _ = w.Write(rec.Body.Bytes())
+}
+
+// Requests returns the requests served so far as "METHOD /path" strings,
+// without the /api/v3 prefix or query.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// ResetRequests clears the request log and the NotModified counter.
+func (s *Server) ResetRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+	s.notModified = 0
+}
+
+// NotModified returns the number of 304 Not Modified responses served to
+// conditional requests.
+func (s *Server) NotModified() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.notModified
+}
+
+// SetTreeLimit makes recursive tree responses with more than n entries
+// truncated to n, like the limits of the GitHub API. Zero disables the
+// limit.
+func (s *Server) SetTreeLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.treeLimit = n
+}
+
+// CommitFiles commits files to a branch and returns the new commit SHA.
+// A nil content deletes the file. The branch is created from
+// DefaultBranch if it does not exist.
+func (s *Server) CommitFiles(branch, message string, files map[string][]byte) string {
+	return s.commitTree(branch, message, func(tree map[string]treeEntry) {
+		for filePath, content := range files {
+			if content == nil {
+				removePath(tree, filePath)
+				continue
+			}
+			tree[filePath] = treeEntry{name: filePath, mode: modeFile, typ: "blob", sha: s.store.putBlob(content)}
+		}
+	})
+}
+
+// CommitEntry commits a single entry with a Git mode, such as "100755" or
+// "120000", to a branch and returns the new commit SHA. For submodules
+// ("160000") the content is the commit SHA the submodule points to.
+func (s *Server) CommitEntry(branch, message, filePath, mode string, content []byte) string {
+	return s.commitTree(branch, message, func(tree map[string]treeEntry) {
+		if mode == modeSubmodule {
+			tree[filePath] = treeEntry{name: filePath, mode: mode, typ: "commit", sha: string(content)}
+			return
+		}
+		tree[filePath] = treeEntry{name: filePath, mode: mode, typ: "blob", sha: s.store.putBlob(content)}
+	})
+}
+
+// commitTree commits a change of the flattened tree of a branch and
+// returns the new commit SHA. The branch is created from DefaultBranch if
+// it does not exist.
+func (s *Server) commitTree(branch, message string, change func(tree map[string]treeEntry)) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ref := "refs/heads/" + branch
+	if _, ok := s.store.refs[ref]; !ok {
+		s.store.refs[ref] = s.store.refs["refs/heads/"+DefaultBranch]
+	}
+
+	head := s.store.commits[s.store.refs[ref]]
+	tree := s.store.flatten(head.tree)
+	change(tree)
+
+	sha := s.commit(head.sha, s.store.buildTree(tree), message, defaultIdentity, defaultIdentity)
+	s.store.refs[ref] = sha
+	return sha
+}
+
+// File returns the content of a file at a branch, tag or commit SHA.
+func (s *Server) File(ref, filePath string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.store.resolveCommit(ref)
+	if !ok {
+		return nil, false
+	}
+	e, ok := s.store.lookup(c.tree, filePath)
+	if !ok || e.typ != "blob" {
+		return nil, false
+	}
+	return append([]byte(nil), s.store.blobs[e.sha]...), true
+}
+
+// Releases returns the releases created so far, as requested.
+func (s *Server) Releases() []*github.RepositoryRelease {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*github.RepositoryRelease(nil), s.releases...)
+}
+
+// Head returns the commit SHA a branch points at, or "" if it does not exist.
+func (s *Server) Head(branch string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store.refs["refs/heads/"+branch]
+}
+
+// commit stores a commit on top of parent and returns its SHA.
+func (s *Server) commit(parent, tree, message string, author, committer identity) string {
+	now := time.Now().UTC().Truncate(time.Second)
+	if author.date.IsZero() {
+		author.date = now
+	}
+	if committer.date.IsZero() {
+		committer.date = now
+	}
+
+	c := &commitObject{tree: tree, author: author, committer: committer, message: message}
+	if parent != "" {
+		c.parents = []string{parent}
+	}
+	return s.store.putCommit(c)
+}
+
+func (s *Server) routes() {
+	s.handle("GET /repos/{owner}/{repo}", s.getRepository)
+
+	s.handle("GET /repos/{owner}/{repo}/contents/{path...}", s.getContents)
+	s.handle("PUT /repos/{owner}/{repo}/contents/{path...}", s.putContents)
+	s.handle("DELETE /repos/{owner}/{repo}/contents/{path...}", s.deleteContents)
+
+	s.handle("GET /repos/{owner}/{repo}/git/ref/{ref...}", s.getRef)
+	s.handle("GET /repos/{owner}/{repo}/git/matching-refs/{ref...}", s.matchingRefs)
+	s.handle("POST /repos/{owner}/{repo}/git/refs", s.createRef)
+	s.handle("PATCH /repos/{owner}/{repo}/git/refs/{ref...}", s.updateRef)
+	s.handle("DELETE /repos/{owner}/{repo}/git/refs/{ref...}", s.deleteRef)
+
+	s.handle("GET /repos/{owner}/{repo}/commits", s.listCommits)
+
+	s.handle("GET /repos/{owner}/{repo}/git/commits/{sha}", s.getCommit)
+	s.handle("POST /repos/{owner}/{repo}/git/commits", s.createCommit)
+	s.handle("GET /repos/{owner}/{repo}/git/trees/{sha...}", s.getTree)
+	s.handle("POST /repos/{owner}/{repo}/git/trees", s.createTree)
+	s.handle("GET /repos/{owner}/{repo}/git/blobs/{sha}", s.getBlob)
+	s.handle("POST /repos/{owner}/{repo}/git/blobs", s.createBlob)
+	s.handle("GET /repos/{owner}/{repo}/git/tags/{sha}", s.getTag)
+	s.handle("POST /repos/{owner}/{repo}/git/tags", s.createTag)
+
+	s.handle("POST /repos/{owner}/{repo}/releases", s.createRelease)
+
+	s.handle("GET /repos/{owner}/{repo}/compare/{basehead...}", s.compare)
+
+	s.mux.HandleFunc("POST /api/graphql", s.graphQL)
+
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, http.StatusNotFound, "Not Found")
+	})
+}
+
+// handle registers a handler for a repository route. Requests for any
+// other repository get a 404.
+func (s *Server) handle(pattern string, fn http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.EqualFold(r.PathValue("owner"), s.owner) || !strings.EqualFold(r.PathValue("repo"), s.repo) {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		fn(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{
+		"message":           message,
+		"documentation_url": "https://docs.github.com/rest",
+	})
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return false
+	}
+	return true
+}
+
+// URLs
+
+func (s *Server) apiURL(format string, args ...any) string {
+	return s.URL + apiPrefix + fmt.Sprintf("/repos/%s/%s/", s.owner, s.repo) + fmt.Sprintf(format, args...)
+}
+
+func (s *Server) htmlURL(format string, args ...any) string {
+	return s.URL + fmt.Sprintf("/%s/%s/", s.owner, s.repo) + fmt.Sprintf(format, args...)
+}
+
+// Conversions to API types
+
+func toCommitAuthor(id identity) *github.CommitAuthor {
+	return &github.CommitAuthor{
+		Name:  github.Ptr(id.name),
+		Email: github.Ptr(id.email),
+		Date:  &github.Timestamp{Time: id.date},
+	}
+}
+
+func fromCommitAuthor(author *github.CommitAuthor) identity {
+	if author == nil {
+		return defaultIdentity
+	}
+	id := identity{name: author.GetName(), email: author.GetEmail()}
+	if author.Date != nil {
+		id.date = author.Date.UTC()
+	}
+	return id
+}
+
+func (s *Server) commitJSON(c *commitObject) *github.Commit {
+	out := &github.Commit{
+		SHA:       github.Ptr(c.sha),
+		Tree:      &github.Tree{SHA: github.Ptr(c.tree)},
+		Author:    toCommitAuthor(c.author),
+		Committer: toCommitAuthor(c.committer),
+		Message:   github.Ptr(c.message),
+		URL:       github.Ptr(s.apiURL("git/commits/%s", c.sha)),
+		HTMLURL:   github.Ptr(s.htmlURL("commit/%s", c.sha)),
+	}
+	if c.signature != "" {
+		out.Verification = &github.SignatureVerification{
+			Verified:  github.Ptr(true),
+			Reason:    github.Ptr("valid"),
+			Signature: github.Ptr(c.signature),
+		}
+	}
+	for _, parent := range c.parents {
+		out.Parents = append(out.Parents, &github.Commit{
+			SHA: github.Ptr(parent),
+			URL: github.Ptr(s.apiURL("git/commits/%s", parent)),
+		})
+	}
+	return out
+}
+
+func (s *Server) repositoryCommitJSON(c *commitObject) *github.RepositoryCommit {
+	return &github.RepositoryCommit{
+		SHA:     github.Ptr(c.sha),
+		Commit:  s.commitJSON(c),
+		HTMLURL: github.Ptr(s.htmlURL("commit/%s", c.sha)),
+	}
+}
+
+func (s *Server) treeEntryJSON(filePath string, e treeEntry) *github.TreeEntry {
+	out := &github.TreeEntry{
+		Path: github.Ptr(filePath),
+		Mode: github.Ptr(e.mode),
+		Type: github.Ptr(e.typ),
+		SHA:  github.Ptr(e.sha),
+	}
+	switch e.typ {
+	case "blob":
+		out.Size = github.Ptr(len(s.store.blobs[e.sha]))
+		out.URL = github.Ptr(s.apiURL("git/blobs/%s", e.sha))
+	case "tree":
+		out.URL = github.Ptr(s.apiURL("git/trees/%s", e.sha))
+	}
+	return out
+}
+
+// contentJSON describes an entry the way the Contents API does. File
+// content is only included when withContent is set.
+func (s *Server) contentJSON(filePath string, e treeEntry, withContent bool) *github.RepositoryContent {
+	out := &github.RepositoryContent{
+		Name:    github.Ptr(path.Base(filePath)),
+		Path:    github.Ptr(filePath),
+		SHA:     github.Ptr(e.sha),
+		URL:     github.Ptr(s.apiURL("contents/%s", filePath)),
+		HTMLURL: github.Ptr(s.htmlURL("blob/%s/%s", DefaultBranch, filePath)),
+	}
+
+	switch e.mode {
+	case modeTree:
+		out.Type = github.Ptr("dir")
+		out.Size = github.Ptr(0)
+	case modeSubmodule:
+		out.Type = github.Ptr("submodule")
+		out.Size = github.Ptr(0)
+	case modeSymlink:
+		out.Type = github.Ptr("symlink")
+		out.Size = github.Ptr(len(s.store.blobs[e.sha]))
+		out.Target = github.Ptr(string(s.store.blobs[e.sha]))
+	default:
+		content := s.store.blobs[e.sha]
+		out.Type = github.Ptr("file")
+		out.Size = github.Ptr(len(content))
+		if withContent {
+			out.Encoding = github.Ptr("base64")
+			out.Content = github.Ptr(base64.StdEncoding.EncodeToString(content))
+		}
+	}
+	return out
+}
+
+func (s *Server) referenceJSON(ref string) *github.Reference {
+	sha := s.store.refs[ref]
+	return &github.Reference{
+		Ref: github.Ptr(ref),
+		URL: github.Ptr(s.apiURL("git/%s", ref)),
+		Object: &github.GitObject{
+			Type: github.Ptr(s.store.objectType(sha)),
+			SHA:  github.Ptr(sha),
+		},
+	}
+}
+
+// Repository
+
+func (s *Server) getRepository(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, &github.Repository{
+		Name:          github.Ptr(s.repo),
+		FullName:      github.Ptr(s.owner + "/" + s.repo),
+		Owner:         &github.User{Login: github.Ptr(s.owner)},
+		DefaultBranch: github.Ptr(DefaultBranch),
+		HTMLURL:       github.Ptr(s.URL + "/" + s.owner + "/" + s.repo),
+	})
+}
+
+// Contents API
+
+func (s *Server) getContents(w http.ResponseWriter, r *http.Request) {
+	ref := r.URL.Query().Get("ref")
+	if ref == "" {
+		ref = DefaultBranch
+	}
+	c, ok := s.store.resolveCommit(ref)
+	if !ok {
+		writeError(w, http.StatusNotFound, "No commit found for the ref "+ref)
+		return
+	}
+
+	filePath := strings.Trim(r.PathValue("path"), "/")
+	e, ok := s.store.lookup(c.tree, filePath)
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	// Like GitHub, follow symbolic links to files in the repository
+	if e.mode == modeSymlink {
+		target := path.Join(path.Dir(filePath), string(s.store.blobs[e.sha]))
+		if te, ok := s.store.lookup(c.tree, target); ok && te.typ == "blob" && te.mode != modeSymlink {
+			filePath, e = target, te
+		}
+	}
+
+	if e.typ != "tree" {
+		writeJSON(w, http.StatusOK, s.contentJSON(filePath, e, true))
+		return
+	}
+
+	out := []*github.RepositoryContent{}
+	for _, child := range s.store.trees[e.sha] {
+		out = append(out, s.contentJSON(path.Join(filePath, child.name), child, false))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// branchHead returns the head commit of the branch named by a Contents API
+// request, writing a 404 if it does not exist.
+func (s *Server) branchHead(w http.ResponseWriter, branch string) (string, *commitObject, bool) {
+	if branch == "" {
+		branch = DefaultBranch
+	}
+	ref := "refs/heads/" + branch
+	c, ok := s.store.commits[s.store.refs[ref]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Branch "+branch+" not found")
+		return "", nil, false
+	}
+	return ref, c, true
+}
+
+// checkSHA enforces the blob SHA precondition of the Contents API.
+func checkSHA(w http.ResponseWriter, filePath string, existing treeEntry, exists bool, sha string) bool {
+	switch {
+	case exists && sha == "":
+		writeError(w, http.StatusUnprocessableEntity, "Invalid request.\n\n\"sha\" wasn't supplied.")
+		return false
+	case exists && sha != existing.sha:
+		writeError(w, http.StatusConflict, fmt.Sprintf("%s does not match %s", filePath, sha))
+		return false
+	}
+	return true
+}
+
+func (s *Server) putContents(w http.ResponseWriter, r *http.Request) {
+	var body github.RepositoryContentFileOptions
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	ref, head, ok := s.branchHead(w, body.GetBranch())
+	if !ok {
+		return
+	}
+
+	filePath := strings.Trim(r.PathValue("path"), "/")
+	tree := s.store.flatten(head.tree)
+	existing, exists := tree[filePath]
+	if !checkSHA(w, filePath, existing, exists, body.GetSHA()) {
+		return
+	}
+
+	entry := treeEntry{name: filePath, mode: modeFile, typ: "blob", sha: s.store.putBlob(body.Content)}
+	if exists {
+		entry.mode = existing.mode
+	}
+	tree[filePath] = entry
+
+	author := fromCommitAuthor(body.Author)
+	committer := author
+	if body.Committer != nil {
+		committer = fromCommitAuthor(body.Committer)
+	}
+	sha := s.commit(head.sha, s.store.buildTree(tree), body.GetMessage(), author, committer)
+	s.store.refs[ref] = sha
+
+	status := http.StatusCreated
+	if exists {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, map[string]any{
+		"content": s.contentJSON(filePath, entry, false),
+		"commit":  s.commitJSON(s.store.commits[sha]),
+	})
+}
+
+func (s *Server) deleteContents(w http.ResponseWriter, r *http.Request) {
+	var body github.RepositoryContentFileOptions
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	ref, head, ok := s.branchHead(w, body.GetBranch())
+	if !ok {
+		return
+	}
+
+	filePath := strings.Trim(r.PathValue("path"), "/")
+	tree := s.store.flatten(head.tree)
+	existing, exists := tree[filePath]
+	if !exists {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	if !checkSHA(w, filePath, existing, exists, body.GetSHA()) {
+		return
+	}
+	delete(tree, filePath)
+
+	author := fromCommitAuthor(body.Author)
+	committer := author
+	if body.Committer != nil {
+		committer = fromCommitAuthor(body.Committer)
+	}
+	sha := s.commit(head.sha, s.store.buildTree(tree), body.GetMessage(), author, committer)
+	s.store.refs[ref] = sha
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"content": nil,
+		"commit":  s.commitJSON(s.store.commits[sha]),
+	})
+}
+
+// Git Data API: refs
+
+func (s *Server) getRef(w http.ResponseWriter, r *http.Request) {
+	ref := "refs/" + r.PathValue("ref")
+	if _, ok := s.store.refs[ref]; !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.referenceJSON(ref))
+}
+
+func (s *Server) matchingRefs(w http.ResponseWriter, r *http.Request) {
+	prefix := "refs/" + r.PathValue("ref")
+
+	var names []string
+	for ref := range s.store.refs {
+		if strings.HasPrefix(ref, prefix) {
+			names = append(names, ref)
+		}
+	}
+	sort.Strings(names)
+
+	out := []*github.Reference{}
+	for _, ref := range names {
+		out = append(out, s.referenceJSON(ref))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) createRef(w http.ResponseWriter, r *http.Request) {
+	var body github.CreateRef
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	switch {
+	case !strings.HasPrefix(body.Ref, "refs/") || strings.Count(body.Ref, "/") < 2:
+		writeError(w, http.StatusUnprocessableEntity, "Reference name invalid")
+	case s.store.objectType(body.SHA) == "":
+		writeError(w, http.StatusUnprocessableEntity, "Object does not exist")
+	case s.store.refs[body.Ref] != "":
+		writeError(w, http.StatusUnprocessableEntity, "Reference already exists")
+	default:
+		s.store.refs[body.Ref] = body.SHA
+		writeJSON(w, http.StatusCreated, s.referenceJSON(body.Ref))
+	}
+}
+
+func (s *Server) updateRef(w http.ResponseWriter, r *http.Request) {
+	var body github.UpdateRef
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	ref := "refs/" + r.PathValue("ref")
+	current, exists := s.store.refs[ref]
+	switch {
+	case !exists:
+		writeError(w, http.StatusUnprocessableEntity, "Reference does not exist")
+	case s.store.objectType(body.SHA) == "":
+		writeError(w, http.StatusUnprocessableEntity, "Object does not exist")
+	case strings.HasPrefix(ref, "refs/heads/") && !body.GetForce() && !s.store.ancestors(body.SHA)[current]:
+		writeError(w, http.StatusUnprocessableEntity, "Update is not a fast forward")
+	default:
+		s.store.refs[ref] = body.SHA
+		writeJSON(w, http.StatusOK, s.referenceJSON(ref))
+	}
+}
+
+func (s *Server) deleteRef(w http.ResponseWriter, r *http.Request) {
+	ref := "refs/" + r.PathValue("ref")
+	if _, ok := s.store.refs[ref]; !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Reference does not exist")
+		return
+	}
+	delete(s.store.refs, ref)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Git Data API: commits
+
+func (s *Server) getCommit(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.store.commits[r.PathValue("sha")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.commitJSON(c))
+}
+
+func (s *Server) createCommit(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Message   string               `json:"message"`
+		Tree      string               `json:"tree"`
+		Parents   []string             `json:"parents"`
+		Author    *github.CommitAuthor `json:"author"`
+		Committer *github.CommitAuthor `json:"committer"`
+		Signature string               `json:"signature"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	if _, ok := s.store.trees[body.Tree]; !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Tree SHA does not exist")
+		return
+	}
+	for _, parent := range body.Parents {
+		if _, ok := s.store.commits[parent]; !ok {
+			writeError(w, http.StatusUnprocessableEntity, "Parent SHA does not exist or is not a commit object")
+			return
+		}
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	author := fromCommitAuthor(body.Author)
+	if author.date.IsZero() {
+		author.date = now
+	}
+	committer := author
+	if body.Committer != nil {
+		committer = fromCommitAuthor(body.Committer)
+		if committer.date.IsZero() {
+			committer.date = now
+		}
+	}
+
+	sha := s.store.putCommit(&commitObject{
+		tree:      body.Tree,
+		parents:   body.Parents,
+		author:    author,
+		committer: committer,
+		message:   body.Message,
+		signature: body.Signature,
+	})
+	writeJSON(w, http.StatusCreated, s.commitJSON(s.store.commits[sha]))
+}
+
+// Git Data API: trees
+
+func (s *Server) getTree(w http.ResponseWriter, r *http.Request) {
+	treeSHA, ok := s.store.resolveTree(r.PathValue("sha"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	entries := []*github.TreeEntry{}
+	truncated := false
+	if r.URL.Query().Get("recursive") != "" {
+		s.store.walk(treeSHA, "", func(filePath string, e treeEntry) {
+			entries = append(entries, s.treeEntryJSON(filePath, e))
+		})
+		if s.treeLimit > 0 && len(entries) > s.treeLimit {
+			entries, truncated = entries[:s.treeLimit], true
+		}
+	} else {
+		for _, e := range s.store.trees[treeSHA] {
+			entries = append(entries, s.treeEntryJSON(e.name, e))
+		}
+	}
+
+	writeJSON(w, http.StatusOK, &github.Tree{
+		SHA:       github.Ptr(treeSHA),
+		Entries:   entries,
+		Truncated: github.Ptr(truncated),
+	})
+}
+
+func (s *Server) createTree(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		BaseTree string              `json:"base_tree"`
+		Tree     []*github.TreeEntry `json:"tree"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	tree := make(map[string]treeEntry)
+	if body.BaseTree != "" {
+		if _, ok := s.store.trees[body.BaseTree]; !ok {
+			writeError(w, http.StatusUnprocessableEntity, "base_tree is not a valid tree oid")
+			return
+		}
+		tree = s.store.flatten(body.BaseTree)
+	}
+
+	for _, e := range body.Tree {
+		filePath := strings.Trim(e.GetPath(), "/")
+		mode := e.GetMode()
+		if mode == "" {
+			mode = modeFile
+		}
+
+		switch {
+		case e.SHA == nil && e.Content == nil:
+			removePath(tree, filePath)
+
+		case e.Content != nil:
+			sha := s.store.putBlob([]byte(e.GetContent()))
+			tree[filePath] = treeEntry{name: filePath, mode: mode, typ: "blob", sha: sha}
+
+		case mode == modeTree || e.GetType() == "tree":
+			if _, ok := s.store.trees[e.GetSHA()]; !ok {
+				writeError(w, http.StatusUnprocessableEntity, "tree.sha "+e.GetSHA()+" is not a valid tree")
+				return
+			}
+			removePath(tree, filePath)
+			for p, sub := range s.store.flatten(e.GetSHA()) {
+				sub.name = filePath + "/" + p
+				tree[sub.name] = sub
+			}
+
+		case mode == modeSubmodule:
+			tree[filePath] = treeEntry{name: filePath, mode: mode, typ: "commit", sha: e.GetSHA()}
+
+		default:
+			if _, ok := s.store.blobs[e.GetSHA()]; !ok {
+				writeError(w, http.StatusUnprocessableEntity, "tree.sha "+e.GetSHA()+" is not a valid blob")
+				return
+			}
+			tree[filePath] = treeEntry{name: filePath, mode: mode, typ: "blob", sha: e.GetSHA()}
+		}
+	}
+
+	treeSHA := s.store.buildTree(tree)
+	entries := []*github.TreeEntry{}
+	for _, e := range s.store.trees[treeSHA] {
+		entries = append(entries, s.treeEntryJSON(e.name, e))
+	}
+	writeJSON(w, http.StatusCreated, &github.Tree{
+		SHA:       github.Ptr(treeSHA),
+		Entries:   entries,
+		Truncated: github.Ptr(false),
+	})
+}
+
+// Git Data API: blobs
+
+func (s *Server) getBlob(w http.ResponseWriter, r *http.Request) {
+	sha := r.PathValue("sha")
+	content, ok := s.store.blobs[sha]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "raw") {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(content)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &github.Blob{
+		SHA:      github.Ptr(sha),
+		Size:     github.Ptr(len(content)),
+		Content:  github.Ptr(base64.StdEncoding.EncodeToString(content)),
+		Encoding: github.Ptr("base64"),
+		URL:      github.Ptr(s.apiURL("git/blobs/%s", sha)),
+	})
+}
+
+func (s *Server) createBlob(w http.ResponseWriter, r *http.Request) {
+	var body github.Blob
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	content := []byte(body.GetContent())
+	if body.GetEncoding() == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(body.GetContent())
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "Invalid base64 content")
+			return
+		}
+		content = decoded
+	}
+
+	sha := s.store.putBlob(content)
+	writeJSON(w, http.StatusCreated, &github.Blob{
+		SHA: github.Ptr(sha),
+		URL: github.Ptr(s.apiURL("git/blobs/%s", sha)),
+	})
+}
+
+// Git Data API: tags
+
+func (s *Server) tagJSON(t *tagObject) *github.Tag {
+	return &github.Tag{
+		SHA:     github.Ptr(t.sha),
+		Tag:     github.Ptr(t.name),
+		Message: github.Ptr(t.message),
+		Tagger:  toCommitAuthor(t.tagger),
+		URL:     github.Ptr(s.apiURL("git/tags/%s", t.sha)),
+		Object: &github.GitObject{
+			Type: github.Ptr(t.typ),
+			SHA:  github.Ptr(t.object),
+		},
+	}
+}
+
+func (s *Server) getTag(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.store.tags[r.PathValue("sha")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.tagJSON(t))
+}
+
+func (s *Server) createTag(w http.ResponseWriter, r *http.Request) {
+	var body github.CreateTag
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+
+	typ := s.store.objectType(body.Object)
+	if typ == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Object does not exist")
+		return
+	}
+
+	tagger := fromCommitAuthor(body.Tagger)
+	if tagger.date.IsZero() {
+		tagger.date = time.Now().UTC().Truncate(time.Second)
+	}
+	sha := s.store.putTag(&tagObject{
+		name:    body.Tag,
+		object:  body.Object,
+		typ:     typ,
+		tagger:  tagger,
+		message: body.Message,
+	})
+	writeJSON(w, http.StatusCreated, s.tagJSON(s.store.tags[sha]))
+}
+
+// Releases API
+
+func (s *Server) createRelease(w http.ResponseWriter, r *http.Request) {
+	var body github.RepositoryRelease
+	if !decodeJSON(w, r, &body) {
+		return
+	}
+	if body.GetTagName() == "" {
+		writeError(w, http.StatusUnprocessableEntity, "tag_name is required")
+		return
+	}
+
+	// Like GitHub, create the tag at target_commitish if it is missing
+	ref := "refs/tags/" + body.GetTagName()
+	if _, ok := s.store.refs[ref]; !ok {
+		target := body.GetTargetCommitish()
+		if target == "" {
+			target = DefaultBranch
+		}
+		c, ok := s.store.resolveCommit(target)
+		if !ok {
+			writeError(w, http.StatusUnprocessableEntity, "Invalid target_commitish")
+			return
+		}
+		s.store.refs[ref] = c.sha
+	}
+
+	body.ID = github.Ptr(int64(len(s.releases) + 1))
+	s.releases = append(s.releases, &body)
+	writeJSON(w, http.StatusCreated, &body)
+}
+
+// Commits API
+
+func (s *Server) listCommits(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	ref := query.Get("sha")
+	if ref == "" {
+		ref = DefaultBranch
+	}
+	c, ok := s.store.resolveCommit(ref)
+	if !ok {
+		writeError(w, http.StatusNotFound, "No commit found for SHA: "+ref)
+		return
+	}
+
+	commits := s.store.history(c.sha, strings.Trim(query.Get("path"), "/"))
+	if perPage, err := strconv.Atoi(query.Get("per_page")); err == nil && perPage < len(commits) {
+		commits = commits[:perPage]
+	}
+
+	out := []*github.RepositoryCommit{}
+	for _, commit := range commits {
+		out = append(out, s.repositoryCommitJSON(commit))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// Compare API
+
+func (s *Server) compare(w http.ResponseWriter, r *http.Request) {
+	baseRef, headRef, ok := strings.Cut(r.PathValue("basehead"), "...")
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	// Cross-repository comparisons use owner:ref
+	if _, ref, ok := strings.Cut(baseRef, ":"); ok {
+		baseRef = ref
+	}
+	if _, ref, ok := strings.Cut(headRef, ":"); ok {
+		headRef = ref
+	}
+
+	base, ok := s.store.resolveCommit(baseRef)
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	head, ok := s.store.resolveCommit(headRef)
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	ahead := s.store.commitsBetween(base.sha, head.sha)
+	behind := s.store.commitsBetween(head.sha, base.sha)
+
+	status := "diverged"
+	switch {
+	case len(ahead) == 0 && len(behind) == 0:
+		status = "identical"
+	case len(behind) == 0:
+		status = "ahead"
+	case len(ahead) == 0:
+		status = "behind"
+	}
+
+	out := &github.CommitsComparison{
+		BaseCommit:   s.repositoryCommitJSON(base),
+		Status:       github.Ptr(status),
+		AheadBy:      github.Ptr(len(ahead)),
+		BehindBy:     github.Ptr(len(behind)),
+		TotalCommits: github.Ptr(len(ahead)),
+		Commits:      []*github.RepositoryCommit{},
+		Files:        []*github.CommitFile{},
+		HTMLURL:      github.Ptr(s.htmlURL("compare/%s...%s", baseRef, headRef)),
+		URL:          github.Ptr(s.apiURL("compare/%s...%s", baseRef, headRef)),
+	}
+	for _, c := range ahead {
+		out.Commits = append(out.Commits, s.repositoryCommitJSON(c))
+	}
+
+	// Files are diffed against the merge base, like GitHub's three-dot compare
+	var before map[string]treeEntry
+	if mergeBase := s.store.commits[s.store.mergeBase(base.sha, head.sha)]; mergeBase != nil {
+		out.MergeBaseCommit = s.repositoryCommitJSON(mergeBase)
+		before = s.store.flatten(mergeBase.tree)
+	}
+	after := s.store.flatten(head.tree)
+
+	var names []string
+	for p := range before {
+		names = append(names, p)
+	}
+	for p := range after {
+		if _, ok := before[p]; !ok {
+			names = append(names, p)
+		}
+	}
+	sort.Strings(names)
+
+	for _, p := range names {
+		old, hadOld := before[p]
+		cur, hasCur := after[p]
+		file := &github.CommitFile{Filename: github.Ptr(p)}
+		switch {
+		case !hadOld:
+			file.Status = github.Ptr("added")
+			file.SHA = github.Ptr(cur.sha)
+		case !hasCur:
+			file.Status = github.Ptr("removed")
+			file.SHA = github.Ptr(old.sha)
+		case old.sha != cur.sha || old.mode != cur.mode:
+			file.Status = github.Ptr("modified")
+			file.SHA = github.Ptr(cur.sha)
+		default:
+			continue
+		}
+		out.Files = append(out.Files, file)
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
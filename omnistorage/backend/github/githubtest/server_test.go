@@ -0,0 +1,189 @@
+package githubtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+func TestObjectSHAsMatchGit(t *testing.T) {
+	// Expected values computed with git hash-object, write-tree and commit-tree
+	if got := BlobSHA([]byte("hello\n")); got != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("BlobSHA = %s", got)
+	}
+
+	s := newStore()
+	if got := s.putTree(nil); got != "4b825dc642cb6eb9a060e54bf8d69288fbee4904" {
+		t.Errorf("empty tree = %s", got)
+	}
+
+	tree := s.buildTree(map[string]treeEntry{
+		"a/b.txt": {mode: modeFile, typ: "blob", sha: s.putBlob([]byte("hello\n"))},
+		"a.txt":   {mode: modeFile, typ: "blob", sha: s.putBlob([]byte("x"))},
+		"ab":      {mode: modeFile, typ: "blob", sha: s.putBlob([]byte("y"))},
+	})
+	if tree != "b898ccc578cf68a892d4895948098317c5809b25" {
+		t.Errorf("tree = %s", tree)
+	}
+
+	id := identity{name: "n", email: "e@x", date: time.Unix(1700000000, 0).UTC()}
+	commit := s.putCommit(&commitObject{tree: tree, author: id, committer: id, message: "msg\n"})
+	if commit != "6f16593635d35b2ecdcc6f329c4f89a47a89204f" {
+		t.Errorf("commit = %s", commit)
+	}
+}
+
+func TestGitDataRoundTrip(t *testing.T) {
+	srv := NewServer("owner", "repo")
+	defer srv.Close()
+
+	client, err := github.NewClient(nil).WithEnterpriseURLs(srv.BaseURL(), srv.BaseURL())
+	if err != nil {
+		t.Fatalf("WithEnterpriseURLs failed: %v", err)
+	}
+
+	ctx := context.Background()
+	base := srv.CommitFiles(DefaultBranch, "Seed", map[string][]byte{
+		"docs/a.md": []byte("a"),
+		"docs/b.md": []byte("b"),
+	})
+
+	blob, _, err := client.Git.CreateBlob(ctx, "owner", "repo", github.Blob{
+		Content:  github.Ptr("Yw=="),
+		Encoding: github.Ptr("base64"),
+	})
+	if err != nil {
+		t.Fatalf("CreateBlob failed: %v", err)
+	}
+	if blob.GetSHA() != BlobSHA([]byte("c")) {
+		t.Errorf("blob SHA = %s, want %s", blob.GetSHA(), BlobSHA([]byte("c")))
+	}
+
+	baseCommit, _, err := client.Git.GetCommit(ctx, "owner", "repo", base)
+	if err != nil {
+		t.Fatalf("GetCommit failed: %v", err)
+	}
+	tree, _, err := client.Git.CreateTree(ctx, "owner", "repo", baseCommit.GetTree().GetSHA(), []*github.TreeEntry{
+		{Path: github.Ptr("docs/c.md"), Mode: github.Ptr("100644"), Type: github.Ptr("blob"), SHA: blob.SHA},
+		{Path: github.Ptr("docs/a.md"), Mode: github.Ptr("100644"), Type: github.Ptr("blob")},
+	})
+	if err != nil {
+		t.Fatalf("CreateTree failed: %v", err)
+	}
+	commit, _, err := client.Git.CreateCommit(ctx, "owner", "repo", github.Commit{
+		Message: github.Ptr("Add c, remove a"),
+		Tree:    &github.Tree{SHA: tree.SHA},
+		Parents: []*github.Commit{{SHA: github.Ptr(base)}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("CreateCommit failed: %v", err)
+	}
+	if _, _, err := client.Git.UpdateRef(ctx, "owner", "repo", "heads/"+DefaultBranch, github.UpdateRef{SHA: commit.GetSHA()}); err != nil {
+		t.Fatalf("UpdateRef failed: %v", err)
+	}
+
+	// Moving the branch back is not a fast forward
+	if _, _, err := client.Git.UpdateRef(ctx, "owner", "repo", "heads/"+DefaultBranch, github.UpdateRef{SHA: base}); err == nil {
+		t.Error("Expected non-fast-forward update to fail")
+	}
+
+	if _, ok := srv.File(DefaultBranch, "docs/a.md"); ok {
+		t.Error("Expected docs/a.md to be deleted")
+	}
+	if data, _ := srv.File(DefaultBranch, "docs/c.md"); string(data) != "c" {
+		t.Errorf("docs/c.md = %q, want %q", data, "c")
+	}
+
+	comparison, _, err := client.Repositories.CompareCommits(ctx, "owner", "repo", base, DefaultBranch, nil)
+	if err != nil {
+		t.Fatalf("CompareCommits failed: %v", err)
+	}
+	if comparison.GetStatus() != "ahead" || comparison.GetAheadBy() != 1 {
+		t.Errorf("compare = %s ahead by %d, want ahead by 1", comparison.GetStatus(), comparison.GetAheadBy())
+	}
+	changes := map[string]string{}
+	for _, f := range comparison.Files {
+		changes[f.GetFilename()] = f.GetStatus()
+	}
+	if len(changes) != 2 || changes["docs/a.md"] != "removed" || changes["docs/c.md"] != "added" {
+		t.Errorf("compare files = %v", changes)
+	}
+}
+
+func TestContentsPreconditions(t *testing.T) {
+	srv := NewServer("owner", "repo")
+	defer srv.Close()
+
+	client, err := github.NewClient(nil).WithEnterpriseURLs(srv.BaseURL(), srv.BaseURL())
+	if err != nil {
+		t.Fatalf("WithEnterpriseURLs failed: %v", err)
+	}
+
+	ctx := context.Background()
+	created, _, err := client.Repositories.CreateFile(ctx, "owner", "repo", "file.txt", &github.RepositoryContentFileOptions{
+		Message: github.Ptr("Create"),
+		Content: []byte("v1"),
+	})
+	if err != nil {
+		t.Fatalf("CreateFile failed: %v", err)
+	}
+
+	// Updating without the current blob SHA is rejected
+	_, resp, err := client.Repositories.CreateFile(ctx, "owner", "repo", "file.txt", &github.RepositoryContentFileOptions{
+		Message: github.Ptr("Update"),
+		Content: []byte("v2"),
+	})
+	if err == nil || resp.StatusCode != 422 {
+		t.Errorf("Expected 422 without sha, got %v", err)
+	}
+
+	// A stale blob SHA is a conflict
+	_, resp, err = client.Repositories.DeleteFile(ctx, "owner", "repo", "file.txt", &github.RepositoryContentFileOptions{
+		Message: github.Ptr("Delete"),
+		SHA:     github.Ptr(BlobSHA([]byte("stale"))),
+	})
+	if err == nil || resp.StatusCode != 409 {
+		t.Errorf("Expected 409 with stale sha, got %v", err)
+	}
+
+	_, _, err = client.Repositories.DeleteFile(ctx, "owner", "repo", "file.txt", &github.RepositoryContentFileOptions{
+		Message: github.Ptr("Delete"),
+		SHA:     created.GetContent().SHA,
+	})
+	if err != nil {
+		t.Fatalf("DeleteFile failed: %v", err)
+	}
+
+	_, _, resp, err = client.Repositories.GetContents(ctx, "owner", "repo", "file.txt", nil)
+	if err == nil || resp.StatusCode != 404 {
+		t.Errorf("Expected 404 after delete, got %v", err)
+	}
+}
+
+func TestTreeExpressions(t *testing.T) {
+	srv := NewServer("owner", "repo")
+	defer srv.Close()
+
+	client, err := github.NewClient(nil).WithEnterpriseURLs(srv.BaseURL(), srv.BaseURL())
+	if err != nil {
+		t.Fatalf("WithEnterpriseURLs failed: %v", err)
+	}
+
+	ctx := context.Background()
+	srv.CommitFiles(DefaultBranch, "Seed", map[string][]byte{"docs/a.md": []byte("a")})
+	srv.CommitEntry(DefaultBranch, "Add script", "docs/tools/run.sh", "100755", []byte("#!/bin/sh\n"))
+
+	tree, _, err := client.Git.GetTree(ctx, "owner", "repo", DefaultBranch+":docs/tools", false)
+	if err != nil {
+		t.Fatalf("GetTree failed: %v", err)
+	}
+	if len(tree.Entries) != 1 || tree.Entries[0].GetPath() != "run.sh" || tree.Entries[0].GetMode() != "100755" {
+		t.Errorf("entries = %v, want run.sh with mode 100755", tree.Entries)
+	}
+
+	if _, _, err := client.Git.GetTree(ctx, "owner", "repo", DefaultBranch+":docs/a.md", false); err == nil {
+		t.Error("GetTree of a file succeeded")
+	}
+}
@@ -0,0 +1,380 @@
+package githubtest
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec // Git object IDs are SHA-1
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Git file modes.
+const (
+	modeFile       = "100644"
+	modeExecutable = "100755"
+	modeSymlink    = "120000"
+	modeSubmodule  = "160000"
+	modeTree       = "040000"
+)
+
+// treeEntry is a single entry of a tree object. In flattened trees the
+// name holds the full path.
+type treeEntry struct {
+	name string
+	mode string
+	typ  string
+	sha  string
+}
+
+// identity is the author, committer or tagger of an object.
+type identity struct {
+	name  string
+	email string
+	date  time.Time
+}
+
+func (id identity) String() string {
+	return fmt.Sprintf("%s <%s> %d %s", id.name, id.email, id.date.Unix(), id.date.Format("-0700"))
+}
+
+type commitObject struct {
+	sha       string
+	tree      string
+	parents   []string
+	author    identity
+	committer identity
+	message   string
+	signature string
+}
+
+type tagObject struct {
+	sha     string
+	name    string
+	object  string
+	typ     string
+	tagger  identity
+	message string
+}
+
+// store is a content-addressed Git object database with refs.
+type store struct {
+	blobs   map[string][]byte
+	trees   map[string][]treeEntry
+	commits map[string]*commitObject
+	tags    map[string]*tagObject
+	refs    map[string]string // full ref name -> object SHA
+}
+
+func newStore() *store {
+	return &store{
+		blobs:   make(map[string][]byte),
+		trees:   make(map[string][]treeEntry),
+		commits: make(map[string]*commitObject),
+		tags:    make(map[string]*tagObject),
+		refs:    make(map[string]string),
+	}
+}
+
+// BlobSHA returns the Git object ID of a blob with the given content,
+// as computed by "git hash-object".
+func BlobSHA(content []byte) string {
+	return hashObject("blob", content)
+}
+
+func hashObject(kind string, body []byte) string {
+	h := sha1.New() //nolint:gosec // Git object IDs are SHA-1
+	fmt.Fprintf(h, "%s %d\x00", kind, len(body))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *store) putBlob(content []byte) string {
+	sha := BlobSHA(content)
+	s.blobs[sha] = append([]byte(nil), content...)
+	return sha
+}
+
+// putTree stores a tree object. Entries are sorted the way Git sorts them,
+// with directories compared as if their name had a trailing slash.
+func (s *store) putTree(entries []treeEntry) string {
+	sortKey := func(e treeEntry) string {
+		if e.typ == "tree" {
+			return e.name + "/"
+		}
+		return e.name
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return sortKey(entries[i]) < sortKey(entries[j])
+	})
+
+	var body bytes.Buffer
+	for _, e := range entries {
+		raw, _ := hex.DecodeString(e.sha)
+		fmt.Fprintf(&body, "%s %s\x00", strings.TrimPrefix(e.mode, "0"), e.name)
+		body.Write(raw)
+	}
+
+	sha := hashObject("tree", body.Bytes())
+	s.trees[sha] = entries
+	return sha
+}
+
+func (s *store) putCommit(c *commitObject) string {
+	var body strings.Builder
+	fmt.Fprintf(&body, "tree %s\n", c.tree)
+	for _, parent := range c.parents {
+		fmt.Fprintf(&body, "parent %s\n", parent)
+	}
+	fmt.Fprintf(&body, "author %s\n", c.author)
+	fmt.Fprintf(&body, "committer %s\n", c.committer)
+	if c.signature != "" {
+		fmt.Fprintf(&body, "gpgsig %s\n", strings.ReplaceAll(strings.TrimSuffix(c.signature, "\n"), "\n", "\n "))
+	}
+	fmt.Fprintf(&body, "\n%s", c.message)
+
+	c.sha = hashObject("commit", []byte(body.String()))
+	s.commits[c.sha] = c
+	return c.sha
+}
+
+func (s *store) putTag(t *tagObject) string {
+	body := fmt.Sprintf("object %s\ntype %s\ntag %s\ntagger %s\n\n%s", t.object, t.typ, t.name, t.tagger, t.message)
+	t.sha = hashObject("tag", []byte(body))
+	s.tags[t.sha] = t
+	return t.sha
+}
+
+// objectType returns the Git type of the object with the given SHA.
+func (s *store) objectType(sha string) string {
+	switch {
+	case s.commits[sha] != nil:
+		return "commit"
+	case s.tags[sha] != nil:
+		return "tag"
+	case s.trees[sha] != nil:
+		return "tree"
+	case s.blobs[sha] != nil:
+		return "blob"
+	}
+	return ""
+}
+
+// resolveCommit resolves a branch, tag, full ref or commit SHA to a commit.
+func (s *store) resolveCommit(ref string) (*commitObject, bool) {
+	sha, ok := "", false
+	for _, name := range []string{ref, "refs/heads/" + ref, "refs/tags/" + ref} {
+		if sha, ok = s.refs[name]; ok {
+			break
+		}
+	}
+	if !ok {
+		sha = ref
+	}
+
+	// Peel annotated tags
+	for s.tags[sha] != nil {
+		sha = s.tags[sha].object
+	}
+
+	c, ok := s.commits[sha]
+	return c, ok
+}
+
+// resolveTree resolves a tree SHA, anything resolveCommit accepts, or a
+// "<rev>:<path>" expression naming a directory to a tree.
+func (s *store) resolveTree(treeish string) (string, bool) {
+	if rev, dir, ok := strings.Cut(treeish, ":"); ok {
+		treeSHA, ok := s.resolveTree(rev)
+		if !ok {
+			return "", false
+		}
+		e, ok := s.lookup(treeSHA, dir)
+		if !ok || e.typ != "tree" {
+			return "", false
+		}
+		return e.sha, true
+	}
+	if _, ok := s.trees[treeish]; ok {
+		return treeish, true
+	}
+	if c, ok := s.resolveCommit(treeish); ok {
+		return c.tree, true
+	}
+	return "", false
+}
+
+// lookup finds the entry at filePath within a tree. The root is returned
+// as a tree entry with an empty name.
+func (s *store) lookup(treeSHA, filePath string) (treeEntry, bool) {
+	entry := treeEntry{mode: modeTree, typ: "tree", sha: treeSHA}
+	if filePath == "" {
+		return entry, true
+	}
+
+	for _, name := range strings.Split(filePath, "/") {
+		if entry.typ != "tree" {
+			return treeEntry{}, false
+		}
+		found := false
+		for _, e := range s.trees[entry.sha] {
+			if e.name == name {
+				entry, found = e, true
+				break
+			}
+		}
+		if !found {
+			return treeEntry{}, false
+		}
+	}
+	return entry, true
+}
+
+// walk calls fn for every entry below a tree in pre-order, with full paths.
+func (s *store) walk(treeSHA, prefix string, fn func(filePath string, e treeEntry)) {
+	for _, e := range s.trees[treeSHA] {
+		filePath := prefix + e.name
+		fn(filePath, e)
+		if e.typ == "tree" {
+			s.walk(e.sha, filePath+"/", fn)
+		}
+	}
+}
+
+// flatten returns all non-tree entries below a tree keyed by full path.
+func (s *store) flatten(treeSHA string) map[string]treeEntry {
+	files := make(map[string]treeEntry)
+	s.walk(treeSHA, "", func(filePath string, e treeEntry) {
+		if e.typ != "tree" {
+			e.name = filePath
+			files[filePath] = e
+		}
+	})
+	return files
+}
+
+// buildTree stores the nested trees for a flattened tree and returns the
+// root tree SHA.
+func (s *store) buildTree(files map[string]treeEntry) string {
+	var entries []treeEntry
+	dirs := make(map[string]map[string]treeEntry)
+
+	for filePath, e := range files {
+		if dir, rest, ok := strings.Cut(filePath, "/"); ok {
+			if dirs[dir] == nil {
+				dirs[dir] = make(map[string]treeEntry)
+			}
+			dirs[dir][rest] = e
+			continue
+		}
+		e.name = filePath
+		entries = append(entries, e)
+	}
+
+	for dir, sub := range dirs {
+		entries = append(entries, treeEntry{name: dir, mode: modeTree, typ: "tree", sha: s.buildTree(sub)})
+	}
+
+	return s.putTree(entries)
+}
+
+// removePath removes a file or a whole directory from a flattened tree.
+func removePath(files map[string]treeEntry, filePath string) {
+	delete(files, filePath)
+	for p := range files {
+		if strings.HasPrefix(p, filePath+"/") {
+			delete(files, p)
+		}
+	}
+}
+
+// history returns the commits that changed a path, or all commits if
+// filePath is "", newest first. Merge commits are compared with their first
+// parent only.
+func (s *store) history(commitSHA, filePath string) []*commitObject {
+	var out []*commitObject
+	for c := s.commits[commitSHA]; c != nil; {
+		var parent *commitObject
+		if len(c.parents) > 0 {
+			parent = s.commits[c.parents[0]]
+		}
+
+		changed := filePath == ""
+		if !changed {
+			cur, inCur := s.lookup(c.tree, filePath)
+			var old treeEntry
+			inOld := false
+			if parent != nil {
+				old, inOld = s.lookup(parent.tree, filePath)
+			}
+			changed = inCur != inOld || cur != old
+		}
+		if changed {
+			out = append(out, c)
+		}
+		c = parent
+	}
+	return out
+}
+
+// ancestors returns the SHAs of a commit and all commits reachable from it.
+func (s *store) ancestors(sha string) map[string]bool {
+	seen := make(map[string]bool)
+	queue := []string{sha}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if seen[next] || s.commits[next] == nil {
+			continue
+		}
+		seen[next] = true
+		queue = append(queue, s.commits[next].parents...)
+	}
+	return seen
+}
+
+// commitsBetween returns the commits reachable from head but not from base,
+// oldest first.
+func (s *store) commitsBetween(base, head string) []*commitObject {
+	exclude := s.ancestors(base)
+	var out []*commitObject
+	seen := make(map[string]bool)
+	queue := []string{head}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		c := s.commits[next]
+		if c == nil || seen[next] || exclude[next] {
+			continue
+		}
+		seen[next] = true
+		out = append(out, c)
+		queue = append(queue, c.parents...)
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// mergeBase returns the nearest commit reachable from both a and b.
+func (s *store) mergeBase(a, b string) string {
+	reachable := s.ancestors(a)
+	seen := make(map[string]bool)
+	queue := []string{b}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if seen[next] || s.commits[next] == nil {
+			continue
+		}
+		if reachable[next] {
+			return next
+		}
+		seen[next] = true
+		queue = append(queue, s.commits[next].parents...)
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// graphQLRequest is the JSON body of a GraphQL API request.
+type graphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+// graphQLResponse is the JSON body of a GraphQL API response.
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []graphQLError  `json:"errors"`
+}
+
+// graphQLError is a single error returned by the GraphQL API.
+type graphQLError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// GraphQLError is returned when the GitHub GraphQL API reports errors.
+type GraphQLError struct {
+	Messages []string
+}
+
+func (e *GraphQLError) Error() string {
+	return "github: graphql: " + strings.Join(e.Messages, "; ")
+}
+
+// graphQL executes a GraphQL query or mutation and decodes the "data"
+// field of the response into result (if non-nil).
+// The request shares the REST client's transport and authentication.
+func (b *Backend) graphQL(ctx context.Context, query string, variables map[string]any, result any) error {
+	req, err := b.client.NewRequest("POST", b.graphQLURL(), graphQLRequest{
+		Query:     query,
+		Variables: variables,
+	})
+	if err != nil {
+		return fmt.Errorf("github: creating graphql request: %w", err)
+	}
+
+	var out graphQLResponse
+	resp, err := b.client.Do(ctx, req, &out)
+	if err != nil {
+		return b.translateError(err, resp)
+	}
+
+	if len(out.Errors) > 0 {
+		gqlErr := &GraphQLError{}
+		for _, e := range out.Errors {
+			gqlErr.Messages = append(gqlErr.Messages, e.Message)
+		}
+		return gqlErr
+	}
+
+	if result != nil && len(out.Data) > 0 {
+		if err := json.Unmarshal(out.Data, result); err != nil {
+			return fmt.Errorf("github: decoding graphql response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// graphQLURL returns the GraphQL endpoint for the configured API.
+// For github.com this is https://api.github.com/graphql; for GitHub
+// Enterprise (https://host/api/v3/) it is https://host/api/graphql.
+func (b *Backend) graphQLURL() string {
+	u := *b.client.BaseURL
+	if strings.HasSuffix(u.Path, "/api/v3/") {
+		u.Path = strings.TrimSuffix(u.Path, "v3/")
+	}
+	u.Path += "graphql"
+	return u.String()
+}
@@ -0,0 +1,142 @@
+package github
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec // Git object IDs are SHA-1
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+// HashGitBlob is the Git blob object ID of a file: the SHA-1 of a
+// "blob <size>\x00" header followed by the content. It changes whenever
+// the content does, but is not comparable with the HashSHA1 of other
+// backends. For Git LFS files it identifies the pointer blob.
+const HashGitBlob omnistorage.HashType = "git-blob"
+
+// GitBlobSHA returns the Git blob object ID of content, as reported by
+// Stat under HashGitBlob.
+func GitBlobSHA(content []byte) string {
+	h := sha1.New() //nolint:gosec // Git object IDs are SHA-1
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ContentSHA1 returns the SHA-1 of content, as reported by Stat under
+// HashSHA1.
+func ContentSHA1(content []byte) string {
+	sum := sha1.Sum(content) //nolint:gosec // compared with other backends
+	return hex.EncodeToString(sum[:])
+}
+
+// HashReader reads size bytes from r and returns their HashSHA1 and
+// HashGitBlob, without holding the content in memory. Git hashes the size
+// before the content, so it must be known up front.
+func HashReader(r io.Reader, size int64) (map[omnistorage.HashType]string, error) {
+	content := sha1.New() //nolint:gosec // compared with other backends
+	blob := sha1.New()    //nolint:gosec // Git object IDs are SHA-1
+	fmt.Fprintf(blob, "blob %d\x00", size)
+
+	n, err := io.Copy(io.MultiWriter(content, blob), r)
+	if err != nil {
+		return nil, err
+	}
+	if n != size {
+		return nil, fmt.Errorf("github: hashing content: read %d bytes, want %d", n, size)
+	}
+
+	return map[omnistorage.HashType]string{
+		omnistorage.HashSHA1: hexSum(content),
+		HashGitBlob:          hexSum(blob),
+	}, nil
+}
+
+func hexSum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// contentSHA1CacheSize is how many content SHA-1s are remembered.
+const contentSHA1CacheSize = 10000
+
+// contentSHA1Cache remembers the content SHA-1 of recently seen Git blobs,
+// up to contentSHA1CacheSize of them. Blobs are content-addressed, so an
+// entry never goes stale.
+type contentSHA1Cache struct {
+	mu  sync.Mutex
+	lru *lru // entries have size 1
+}
+
+func (c *contentSHA1Cache) get(blobSHA string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lru == nil {
+		return "", false
+	}
+	entry, ok := c.lru.get(blobSHA)
+	if !ok {
+		return "", false
+	}
+	return entry.value.(string), true
+}
+
+func (c *contentSHA1Cache) set(blobSHA, sha string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lru == nil {
+		c.lru = newLRU(contentSHA1CacheSize)
+	}
+	c.lru.add(&lruEntry{key: blobSHA, size: 1, value: sha})
+}
+
+// contentSHA1 returns the SHA-1 of the content of a blob, downloading it
+// (through Config.Cache) unless it is known. For a Git LFS pointer it is
+// the SHA-1 of the LFS object, which is streamed from the LFS server.
+func (b *Backend) contentSHA1(ctx context.Context, blobSHA string) (string, error) {
+	if sha, ok := b.sha1s.get(blobSHA); ok {
+		return sha, nil
+	}
+
+	data, err := b.readBlob(ctx, blobSHA)
+	if err != nil {
+		return "", err
+	}
+
+	sha := ContentSHA1(data)
+	if pointer, ok := b.detectLFSPointer(data); ok {
+		body, err := b.lfsDownload(ctx, pointer)
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = body.Close() }()
+
+		hashes, err := HashReader(body, pointer.Size)
+		if err != nil {
+			return "", err
+		}
+		sha = hashes[omnistorage.HashSHA1]
+	}
+
+	b.sha1s.set(blobSHA, sha)
+	return sha, nil
+}
+
+// setContentSHA1 sets the HashSHA1 of a file unless it is already known.
+// Directories and submodules have no content and are left as is.
+func (b *Backend) setContentSHA1(ctx context.Context, info *omnistorage.BasicObjectInfo) error {
+	blobSHA := info.ObjectHashes[HashGitBlob]
+	if blobSHA == "" || info.ObjectHashes[omnistorage.HashSHA1] != "" {
+		return nil
+	}
+
+	sha, err := b.contentSHA1(ctx, blobSHA)
+	if err != nil {
+		return err
+	}
+	info.ObjectHashes[omnistorage.HashSHA1] = sha
+	return nil
+}
@@ -0,0 +1,122 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+func TestHashHelpers(t *testing.T) {
+	content := []byte("hello\n")
+	const (
+		blobSHA    = "ce013625030ba8dba906f756967f9e9ca394464a"
+		contentSHA = "f572d396fae9206628714fb2ce00f72e94f2258f"
+	)
+
+	if got := GitBlobSHA(content); got != blobSHA {
+		t.Errorf("GitBlobSHA = %s, want %s", got, blobSHA)
+	}
+	if got := ContentSHA1(content); got != contentSHA {
+		t.Errorf("ContentSHA1 = %s, want %s", got, contentSHA)
+	}
+
+	hashes, err := HashReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("HashReader failed: %v", err)
+	}
+	if hashes[omnistorage.HashSHA1] != contentSHA || hashes[HashGitBlob] != blobSHA {
+		t.Errorf("HashReader = %v", hashes)
+	}
+
+	if _, err := HashReader(strings.NewReader("hello"), 6); err == nil {
+		t.Error("HashReader with a wrong size succeeded")
+	}
+}
+
+func TestContentSHA1CacheBounded(t *testing.T) {
+	var c contentSHA1Cache
+	for i := 0; i <= contentSHA1CacheSize; i++ {
+		c.set(fmt.Sprint(i), "sha")
+	}
+
+	if _, ok := c.get("0"); ok {
+		t.Error("Expected the oldest entry to be evicted")
+	}
+	if sha, ok := c.get(fmt.Sprint(contentSHA1CacheSize)); !ok || sha != "sha" {
+		t.Errorf("get = %q, %v, want the newest entry", sha, ok)
+	}
+	if n := c.lru.order.Len(); n != contentSHA1CacheSize {
+		t.Errorf("Expected %d entries, got %d", contentSHA1CacheSize, n)
+	}
+}
+
+func TestStatHashes(t *testing.T) {
+	for _, treeCache := range []bool{false, true} {
+		backend, srv := fakeServerBackend(t)
+		backend.config.TreeCache = treeCache
+		ctx := context.Background()
+
+		r, err := backend.NewReader(ctx, "README.md")
+		if err != nil {
+			t.Fatalf("NewReader failed: %v", err)
+		}
+		content, _ := io.ReadAll(r)
+		_ = r.Close()
+
+		// The blob hash needs no download
+		srv.ResetRequests()
+		info, err := backend.Stat(ctx, "README.md")
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if got, want := info.Hash(HashGitBlob), GitBlobSHA(content); got != want {
+			t.Errorf("treeCache=%v: HashGitBlob = %s, want %s", treeCache, got, want)
+		}
+		if n := countRequests(srv, "/git/blobs/"); n != 0 {
+			t.Errorf("treeCache=%v: Stat downloaded the blob %d times", treeCache, n)
+		}
+
+		// The content SHA-1 is computed once per blob
+		backend.config.StatContentSHA1 = true
+		srv.ResetRequests()
+		for range 2 {
+			info, err := backend.Stat(ctx, "README.md")
+			if err != nil {
+				t.Fatalf("Stat failed: %v", err)
+			}
+			if got, want := info.Hash(omnistorage.HashSHA1), ContentSHA1(content); got != want {
+				t.Errorf("treeCache=%v: HashSHA1 = %s, want %s", treeCache, got, want)
+			}
+		}
+		infos, err := backend.ListInfo(ctx, "README.md")
+		if err != nil || len(infos) != 1 {
+			t.Fatalf("treeCache=%v: ListInfo = %v, %v", treeCache, infos, err)
+		}
+		if got, want := infos[0].Hash(omnistorage.HashSHA1), ContentSHA1(content); got != want {
+			t.Errorf("treeCache=%v: ListInfo HashSHA1 = %s, want %s", treeCache, got, want)
+		}
+		if n := countRequests(srv, "/git/blobs/"); n > 1 {
+			t.Errorf("treeCache=%v: downloaded the blob %d times", treeCache, n)
+		}
+
+		// A changed file has new hashes
+		mustWrite(t, ctx, backend, "README.md", []byte("v2"))
+		info, err = backend.Stat(ctx, "README.md")
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if info.Hash(HashGitBlob) != GitBlobSHA([]byte("v2")) || info.Hash(omnistorage.HashSHA1) != ContentSHA1([]byte("v2")) {
+			t.Errorf("treeCache=%v: hashes after write = %s, %s", treeCache, info.Hash(HashGitBlob), info.Hash(omnistorage.HashSHA1))
+		}
+
+		if info, err := backend.Stat(ctx, "backend"); err != nil || info.Hash(omnistorage.HashSHA1) != "" {
+			t.Errorf("treeCache=%v: directory Stat = %v, %v", treeCache, info, err)
+		}
+		_ = backend.Close()
+	}
+}
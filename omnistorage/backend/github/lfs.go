@@ -0,0 +1,473 @@
+package github
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v84/github"
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+const (
+	// lfsPointerVersion is the first line of every Git LFS pointer.
+	lfsPointerVersion = "version https://git-lfs.github.com/spec/v1"
+
+	// lfsPointerMaxSize is the largest file treated as a possible pointer.
+	lfsPointerMaxSize = 1024
+
+	// lfsMediaType is the media type of the LFS batch API.
+	lfsMediaType = "application/vnd.git-lfs+json"
+)
+
+// lfsPointer is a parsed Git LFS pointer file.
+type lfsPointer struct {
+	OID  string // hex SHA-256 of the content
+	Size int64
+}
+
+// String returns the canonical pointer file content.
+func (p *lfsPointer) String() string {
+	return fmt.Sprintf("%s\noid sha256:%s\nsize %d\n", lfsPointerVersion, p.OID, p.Size)
+}
+
+// parseLFSPointer parses data as a Git LFS pointer.
+// It reports false if data is not a pointer.
+func parseLFSPointer(data []byte) (*lfsPointer, bool) {
+	if len(data) > lfsPointerMaxSize || !bytes.HasPrefix(data, []byte(lfsPointerVersion+"\n")) {
+		return nil, false
+	}
+
+	p := &lfsPointer{Size: -1}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		switch key {
+		case "oid":
+			oid, ok := strings.CutPrefix(value, "sha256:")
+			if !ok || len(oid) != sha256.Size*2 {
+				return nil, false
+			}
+			p.OID = oid
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size < 0 {
+				return nil, false
+			}
+			p.Size = size
+		}
+	}
+
+	if p.OID == "" || p.Size < 0 {
+		return nil, false
+	}
+	return p, true
+}
+
+// newLFSPointer computes the pointer for content.
+func newLFSPointer(content []byte) *lfsPointer {
+	sum := sha256.Sum256(content)
+	return &lfsPointer{
+		OID:  hex.EncodeToString(sum[:]),
+		Size: int64(len(content)),
+	}
+}
+
+// detectLFSPointer parses data as a Git LFS pointer unless LFS is disabled.
+func (b *Backend) detectLFSPointer(data []byte) (*lfsPointer, bool) {
+	if b.config.DisableLFS {
+		return nil, false
+	}
+	return parseLFSPointer(data)
+}
+
+// newLFSReader streams an LFS object, applying reader offset and limit.
+func (b *Backend) newLFSReader(ctx context.Context, p *lfsPointer, cfg *omnistorage.ReaderConfig) (io.ReadCloser, error) {
+	rc, err := b.lfsDownload(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle offset
+	if cfg.Offset > 0 {
+		if _, err := io.CopyN(io.Discard, rc, cfg.Offset); err != nil && err != io.EOF {
+			_ = rc.Close()
+			return nil, fmt.Errorf("github: skipping to offset: %w", err)
+		}
+	}
+
+	// Handle limit
+	if cfg.Limit > 0 {
+		return &limitedReadCloser{Reader: io.LimitReader(rc, cfg.Limit), Closer: rc}, nil
+	}
+
+	return rc, nil
+}
+
+// lfsBatchRequest is the body of an LFS batch API request.
+type lfsBatchRequest struct {
+	Operation string         `json:"operation"`
+	Transfers []string       `json:"transfers"`
+	Objects   []lfsObjectRef `json:"objects"`
+}
+
+// lfsObjectRef identifies an LFS object.
+type lfsObjectRef struct {
+	OID  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// lfsBatchResponse is the body of an LFS batch API response.
+type lfsBatchResponse struct {
+	Objects []struct {
+		lfsObjectRef
+		Actions map[string]lfsAction `json:"actions"`
+		Error   *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"objects"`
+	Message string `json:"message"`
+}
+
+// lfsAction is a transfer action returned by the batch API.
+type lfsAction struct {
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header"`
+}
+
+// lfsEndpoint returns the LFS server URL for the repository.
+// Defaults to https://<host>/<owner>/<repo>.git/info/lfs on the web host
+// that belongs to the configured API.
+func (b *Backend) lfsEndpoint() string {
+	if b.config.LFSURL != "" {
+		return strings.TrimSuffix(b.config.LFSURL, "/")
+	}
+
+	u := url.URL{Scheme: b.client.BaseURL.Scheme, Host: b.client.BaseURL.Host}
+	if u.Host == "api.github.com" {
+		u.Host = "github.com"
+	}
+	u.Path = fmt.Sprintf("/%s/%s.git/info/lfs", b.config.Owner, b.config.Repo)
+	return u.String()
+}
+
+// lfsBatch performs an LFS batch API request for a single object and
+// returns the requested action, or nil if the server needs no transfer.
+func (b *Backend) lfsBatch(ctx context.Context, operation string, p *lfsPointer) (*lfsAction, map[string]lfsAction, error) {
+	body, err := json.Marshal(lfsBatchRequest{
+		Operation: operation,
+		Transfers: []string{"basic"},
+		Objects:   []lfsObjectRef{{OID: p.OID, Size: p.Size}},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("github: encoding lfs batch request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.lfsEndpoint()+"/objects/batch", bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("github: creating lfs batch request: %w", err)
+	}
+	req.Header.Set("Accept", lfsMediaType)
+	req.Header.Set("Content-Type", lfsMediaType)
+	req.SetBasicAuth("x-access-token", b.config.Token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("github: lfs batch request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var out lfsBatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, nil, fmt.Errorf("github: decoding lfs batch response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, translateAPIError(
+			fmt.Errorf("lfs batch: %s: %s", resp.Status, out.Message),
+			&github.Response{Response: resp},
+		)
+	}
+
+	for _, obj := range out.Objects {
+		if obj.OID != p.OID {
+			continue
+		}
+		if obj.Error != nil {
+			return nil, nil, translateAPIError(
+				fmt.Errorf("lfs object %s: %s", p.OID, obj.Error.Message),
+				&github.Response{Response: &http.Response{StatusCode: obj.Error.Code}},
+			)
+		}
+		if action, ok := obj.Actions[operation]; ok {
+			return &action, obj.Actions, nil
+		}
+		return nil, obj.Actions, nil
+	}
+
+	return nil, nil, fmt.Errorf("github: lfs object %s missing from batch response", p.OID)
+}
+
+// lfsDo performs a transfer action request.
+func lfsDo(ctx context.Context, method string, action *lfsAction, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, action.Href, body)
+	if err != nil {
+		return nil, fmt.Errorf("github: creating lfs transfer request: %w", err)
+	}
+	for k, v := range action.Header {
+		req.Header.Set(k, v)
+	}
+	if body != nil {
+		req.ContentLength = size
+		if req.Header.Get("Content-Type") == "" {
+			req.Header.Set("Content-Type", "application/octet-stream")
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github: lfs transfer: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		_ = resp.Body.Close()
+		return nil, translateAPIError(
+			fmt.Errorf("lfs transfer: %s", resp.Status),
+			&github.Response{Response: resp},
+		)
+	}
+	return resp, nil
+}
+
+// lfsDownload streams the LFS object described by p.
+func (b *Backend) lfsDownload(ctx context.Context, p *lfsPointer) (io.ReadCloser, error) {
+	action, _, err := b.lfsBatch(ctx, "download", p)
+	if err != nil {
+		return nil, err
+	}
+	if action == nil {
+		return nil, fmt.Errorf("github: lfs server returned no download action for %s", p.OID)
+	}
+
+	resp, err := lfsDo(ctx, http.MethodGet, action, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// lfsUpload uploads content to the LFS server and returns its pointer.
+// Objects already present on the server are not uploaded again.
+func (b *Backend) lfsUpload(ctx context.Context, content []byte) (*lfsPointer, error) {
+	p := newLFSPointer(content)
+
+	action, actions, err := b.lfsBatch(ctx, "upload", p)
+	if err != nil {
+		return nil, err
+	}
+	if action == nil {
+		return p, nil // Already stored
+	}
+
+	resp, err := lfsDo(ctx, http.MethodPut, action, bytes.NewReader(content), p.Size)
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+
+	if verify, ok := actions["verify"]; ok {
+		body, err := json.Marshal(lfsObjectRef{OID: p.OID, Size: p.Size})
+		if err != nil {
+			return nil, fmt.Errorf("github: encoding lfs verify request: %w", err)
+		}
+		if verify.Header == nil {
+			verify.Header = map[string]string{}
+		}
+		verify.Header["Content-Type"] = lfsMediaType
+		resp, err := lfsDo(ctx, http.MethodPost, &verify, bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			return nil, err
+		}
+		_ = resp.Body.Close()
+	}
+
+	return p, nil
+}
+
+// lfsContent returns the content to commit for filePath. If LFS uploads
+// are enabled and .gitattributes marks the path with filter=lfs, the
+// content is uploaded to LFS and its pointer is returned instead.
+func (b *Backend) lfsContent(ctx context.Context, filePath string, content []byte) ([]byte, error) {
+	if !b.config.LFSUpload {
+		return content, nil
+	}
+
+	patterns, err := b.lfsWritePatterns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !matchLFSPatterns(patterns, filePath) {
+		return content, nil
+	}
+
+	p, err := b.lfsUpload(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(p.String()), nil
+}
+
+// lfsWritePatterns returns the filter=lfs patterns that apply to a write.
+// The .gitattributes blob is taken from the tree cache when it is current,
+// so writes do not fetch the file again until it changes.
+func (b *Backend) lfsWritePatterns(ctx context.Context) ([]lfsPattern, error) {
+	snapshot, err := b.treeSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return b.lfsPatterns(ctx)
+	}
+
+	entry, ok := snapshot.entries[".gitattributes"]
+	if !ok || entry.typ != "blob" {
+		return nil, nil
+	}
+	return b.lfsPatternsAt(ctx, entry.sha)
+}
+
+// lfsPatternCache remembers the filter=lfs patterns of the last
+// .gitattributes blob read, by its SHA.
+type lfsPatternCache struct {
+	mu       sync.Mutex
+	sha      string
+	patterns []lfsPattern
+}
+
+// lfsPatternsAt returns the filter=lfs patterns of the .gitattributes blob
+// with the given SHA.
+func (b *Backend) lfsPatternsAt(ctx context.Context, blobSHA string) ([]lfsPattern, error) {
+	b.lfsAttrs.mu.Lock()
+	if b.lfsAttrs.sha == blobSHA {
+		patterns := b.lfsAttrs.patterns
+		b.lfsAttrs.mu.Unlock()
+		return patterns, nil
+	}
+	b.lfsAttrs.mu.Unlock()
+
+	data, err := b.readBlob(ctx, blobSHA)
+	if err != nil {
+		return nil, err
+	}
+	patterns := parseLFSPatterns(string(data))
+
+	b.lfsAttrs.mu.Lock()
+	b.lfsAttrs.sha, b.lfsAttrs.patterns = blobSHA, patterns
+	b.lfsAttrs.mu.Unlock()
+	return patterns, nil
+}
+
+// lfsPatterns reads the root .gitattributes file at the head of the branch
+// and returns its filter=lfs patterns. A missing file yields no patterns.
+func (b *Backend) lfsPatterns(ctx context.Context) ([]lfsPattern, error) {
+	fileContent, _, resp, err := b.client.Repositories.GetContents(
+		ctx,
+		b.config.Owner,
+		b.config.Repo,
+		".gitattributes",
+		&github.RepositoryContentGetOptions{
+			Ref: b.config.Branch,
+		},
+	)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, b.translateError(err, resp)
+	}
+
+	content, err := fileContent.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("github: decoding .gitattributes: %w", err)
+	}
+
+	return parseLFSPatterns(content), nil
+}
+
+// lfsPattern is a .gitattributes pattern that sets or unsets filter=lfs.
+type lfsPattern struct {
+	Pattern string
+	LFS     bool
+}
+
+// parseLFSPatterns extracts filter=lfs patterns from .gitattributes content.
+func parseLFSPatterns(content string) []lfsPattern {
+	var patterns []lfsPattern
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			switch attr {
+			case "filter=lfs":
+				patterns = append(patterns, lfsPattern{Pattern: fields[0], LFS: true})
+			case "-filter", "!filter":
+				patterns = append(patterns, lfsPattern{Pattern: fields[0], LFS: false})
+			}
+		}
+	}
+	return patterns
+}
+
+// matchLFSPatterns reports whether filePath is tracked by LFS.
+// As in Git, later patterns override earlier ones.
+func matchLFSPatterns(patterns []lfsPattern, filePath string) bool {
+	tracked := false
+	for _, p := range patterns {
+		if matchGitAttributesPattern(p.Pattern, filePath) {
+			tracked = p.LFS
+		}
+	}
+	return tracked
+}
+
+// matchGitAttributesPattern matches a .gitattributes pattern against a
+// repository path. Patterns without a slash match the base name at any
+// depth; other patterns match the full path, with "**/" and "/**"
+// matching any number of directories.
+func matchGitAttributesPattern(pattern, filePath string) bool {
+	if !strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+		ok, _ := path.Match(pattern, path.Base(filePath))
+		return ok
+	}
+
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	if rest, ok := strings.CutPrefix(pattern, "**/"); ok {
+		segments := strings.Split(filePath, "/")
+		for i := range segments {
+			if matchGitAttributesPattern("/"+rest, strings.Join(segments[i:], "/")) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return strings.HasPrefix(filePath, dir+"/")
+	}
+
+	ok, _ := path.Match(pattern, filePath)
+	return ok
+}
@@ -0,0 +1,334 @@
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/plexusone/omni-github/omnistorage/backend/github/githubtest"
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+// fakeLFSRepo serves a minimal Contents API and a Git LFS server.
+type fakeLFSRepo struct {
+	mu      sync.Mutex
+	srv     *httptest.Server
+	files   map[string]string // path -> content in the repo
+	objects map[string][]byte // oid -> LFS object
+}
+
+func newFakeLFSRepo(t *testing.T) *fakeLFSRepo {
+	t.Helper()
+	f := &fakeLFSRepo{
+		files:   make(map[string]string),
+		objects: make(map[string][]byte),
+	}
+	f.srv = httptest.NewServer(f)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeLFSRepo) backend(t *testing.T, lfsUpload bool) *Backend {
+	t.Helper()
+	backend, err := New(Config{
+		Owner:     "owner",
+		Repo:      "repo",
+		Token:     "token",
+		BaseURL:   f.srv.URL + "/",
+		UploadURL: f.srv.URL + "/",
+		LFSURL:    f.srv.URL + "/lfs",
+		LFSUpload: lfsUpload,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	t.Cleanup(func() { _ = backend.Close() })
+	return backend
+}
+
+func (f *fakeLFSRepo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	const (
+		contents = "/api/v3/repos/owner/repo/contents/"
+		blobs    = "/api/v3/repos/owner/repo/git/blobs/"
+	)
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, contents):
+		p := strings.TrimPrefix(r.URL.Path, contents)
+		content, ok := f.files[p]
+		if !ok {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"type":     "file",
+			"path":     p,
+			"sha":      GitBlobSHA([]byte(content)),
+			"size":     len(content),
+			"encoding": "base64",
+			"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, blobs):
+		for _, content := range f.files {
+			if GitBlobSHA([]byte(content)) == strings.TrimPrefix(r.URL.Path, blobs) {
+				_, _ = io.WriteString(w, content)
+				return
+			}
+		}
+		http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, contents):
+		var body struct {
+			Content []byte `json:"content"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.files[strings.TrimPrefix(r.URL.Path, contents)] = string(body.Content)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"content":{"sha":"newblob"},"commit":{"sha":"newcommit"}}`))
+
+	case r.Method == http.MethodPost && r.URL.Path == "/lfs/objects/batch":
+		if user, pass, ok := r.BasicAuth(); !ok || user == "" || pass != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req lfsBatchRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		obj := req.Objects[0]
+		actions := map[string]any{}
+		href := f.srv.URL + "/lfs/objects/" + obj.OID
+		switch req.Operation {
+		case "download":
+			actions["download"] = map[string]any{"href": href}
+		case "upload":
+			if _, exists := f.objects[obj.OID]; !exists {
+				actions["upload"] = map[string]any{"href": href}
+				actions["verify"] = map[string]any{"href": f.srv.URL + "/lfs/verify"}
+			}
+		}
+		w.Header().Set("Content-Type", lfsMediaType)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"objects": []any{map[string]any{"oid": obj.OID, "size": obj.Size, "actions": actions}},
+		})
+
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/lfs/objects/"):
+		data, ok := f.objects[strings.TrimPrefix(r.URL.Path, "/lfs/objects/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/lfs/objects/"):
+		data, _ := io.ReadAll(r.Body)
+		f.objects[strings.TrimPrefix(r.URL.Path, "/lfs/objects/")] = data
+
+	case r.Method == http.MethodPost && r.URL.Path == "/lfs/verify":
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestLFSPointerRead(t *testing.T) {
+	fake := newFakeLFSRepo(t)
+
+	content := []byte("a large binary payload that lives in LFS")
+	pointer := newLFSPointer(content)
+	fake.objects[pointer.OID] = content
+	fake.files["assets/model.bin"] = pointer.String()
+
+	backend := fake.backend(t, false)
+	ctx := context.Background()
+
+	r, err := backend.NewReader(ctx, "assets/model.bin", omnistorage.WithOffset(2), omnistorage.WithLimit(5))
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	_ = r.Close()
+	if string(data) != string(content[2:7]) {
+		t.Errorf("NewReader content = %q, want %q", data, content[2:7])
+	}
+
+	backend.config.StatContentSHA1 = true
+	info, err := backend.Stat(ctx, "assets/model.bin")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Size() != int64(len(content)) {
+		t.Errorf("Size = %d, want %d", info.Size(), len(content))
+	}
+	if info.Hash(omnistorage.HashSHA256) != pointer.OID {
+		t.Errorf("SHA256 = %q, want %q", info.Hash(omnistorage.HashSHA256), pointer.OID)
+	}
+	if info.Hash(omnistorage.HashSHA1) != ContentSHA1(content) {
+		t.Errorf("SHA1 = %q, want %q", info.Hash(omnistorage.HashSHA1), ContentSHA1(content))
+	}
+}
+
+func TestLFSPointerReadDisabled(t *testing.T) {
+	fake := newFakeLFSRepo(t)
+	fake.files["model.bin"] = newLFSPointer([]byte("payload")).String()
+
+	backend := fake.backend(t, false)
+	backend.config.DisableLFS = true
+
+	r, err := backend.NewReader(context.Background(), "model.bin")
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	_ = r.Close()
+	if string(data) != fake.files["model.bin"] {
+		t.Errorf("Expected pointer text with LFS disabled, got %q", data)
+	}
+}
+
+func TestLFSUploadOnWrite(t *testing.T) {
+	fake := newFakeLFSRepo(t)
+	fake.files[".gitattributes"] = "*.bin filter=lfs diff=lfs merge=lfs -text\n"
+
+	backend := fake.backend(t, true)
+	ctx := context.Background()
+
+	write := func(p, content string) {
+		t.Helper()
+		w, err := backend.NewWriter(ctx, p)
+		if err != nil {
+			t.Fatalf("NewWriter failed: %v", err)
+		}
+		_, _ = io.WriteString(w, content)
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+
+	write("data/weights.bin", "binary weights")
+	write("README.md", "plain text")
+
+	pointer, ok := parseLFSPointer([]byte(fake.files["data/weights.bin"]))
+	if !ok {
+		t.Fatalf("Expected a pointer to be committed, got %q", fake.files["data/weights.bin"])
+	}
+	if string(fake.objects[pointer.OID]) != "binary weights" {
+		t.Errorf("LFS object = %q, want %q", fake.objects[pointer.OID], "binary weights")
+	}
+	if fake.files["README.md"] != "plain text" {
+		t.Errorf("Untracked file should be committed as-is, got %q", fake.files["README.md"])
+	}
+}
+
+func TestParseLFSPointer(t *testing.T) {
+	valid := newLFSPointer([]byte("hello")).String()
+
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"valid", valid, true},
+		{"plain text", "hello world", false},
+		{"missing oid", lfsPointerVersion + "\nsize 5\n", false},
+		{"bad oid", lfsPointerVersion + "\noid sha256:abc\nsize 5\n", false},
+		{"too large", valid + strings.Repeat("x", lfsPointerMaxSize), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := parseLFSPointer([]byte(tt.data)); ok != tt.want {
+				t.Errorf("parseLFSPointer() ok = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchLFSPatterns(t *testing.T) {
+	patterns := parseLFSPatterns(`# LFS
+*.psd filter=lfs diff=lfs merge=lfs -text
+assets/** filter=lfs -text
+assets/*.txt -filter
+**/models/*.onnx filter=lfs
+`)
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"design.psd", true},
+		{"deep/dir/design.psd", true},
+		{"assets/image.png", true},
+		{"assets/notes.txt", false},
+		{"src/models/net.onnx", true},
+		{"models/net.onnx", true},
+		{"README.md", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := matchLFSPatterns(patterns, tt.path); got != tt.want {
+				t.Errorf("matchLFSPatterns(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLFSEndpoint(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"https://api.github.com/", "https://github.com/owner/repo.git/info/lfs"},
+		{"https://github.example.com/api/v3/", "https://github.example.com/owner/repo.git/info/lfs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.baseURL, func(t *testing.T) {
+			backend, err := New(Config{Owner: "owner", Repo: "repo", Token: "token", BaseURL: tt.baseURL})
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			if got := backend.lfsEndpoint(); got != tt.want {
+				t.Errorf("lfsEndpoint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLFSUploadPatternsCached(t *testing.T) {
+	lfs := newFakeLFSRepo(t)
+	backend, srv := fakeServerBackend(t)
+	srv.CommitFiles(githubtest.DefaultBranch, "Track binaries", map[string][]byte{
+		".gitattributes": []byte("*.bin filter=lfs diff=lfs merge=lfs -text\n"),
+	})
+	backend.config.LFSURL = lfs.srv.URL + "/lfs"
+	backend.config.LFSUpload = true
+	backend.config.TreeCache = true
+	ctx := context.Background()
+
+	for _, p := range []string{"a.bin", "b.bin", "c.txt"} {
+		mustWrite(t, ctx, backend, p, []byte("content of "+p))
+	}
+
+	if n := countRequests(srv, ".gitattributes"); n != 0 {
+		t.Errorf("Expected .gitattributes to come from the tree cache, got %d Contents requests", n)
+	}
+	if n := countRequests(srv, "/git/blobs/"+GitBlobSHA([]byte("*.bin filter=lfs diff=lfs merge=lfs -text\n"))); n != 1 {
+		t.Errorf("Expected the .gitattributes blob to be read once, got %d", n)
+	}
+	if len(lfs.objects) != 2 {
+		t.Errorf("Expected 2 LFS objects, got %d", len(lfs.objects))
+	}
+}
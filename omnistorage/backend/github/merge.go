@@ -0,0 +1,282 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// MergeMethod is the strategy used to merge a pull request.
+type MergeMethod string
+
+const (
+	// MergeMethodMerge creates a merge commit.
+	MergeMethodMerge MergeMethod = "merge"
+	// MergeMethodSquash squashes all commits into one.
+	MergeMethodSquash MergeMethod = "squash"
+	// MergeMethodRebase rebases the commits onto the base branch.
+	MergeMethodRebase MergeMethod = "rebase"
+)
+
+// defaultMergePollInterval is how often Wait checks the pull request state.
+const defaultMergePollInterval = 10 * time.Second
+
+// Merge errors.
+var (
+	ErrInvalidMergeMethod  = errors.New("github: invalid merge method")
+	ErrPullRequestClosed   = errors.New("github: pull request closed without merging")
+	ErrPullRequestConflict = errors.New("github: pull request has merge conflicts")
+
+	// ErrBranchNotDeleted is returned by Wait, along with the merge commit
+	// SHA, when the pull request was merged but its head branch could not
+	// be deleted.
+	ErrBranchNotDeleted = errors.New("github: pull request merged but head branch not deleted")
+)
+
+// MergeOptions configures how a pull request is merged.
+type MergeOptions struct {
+	// Method is the merge strategy. Default: MergeMethodMerge.
+	Method MergeMethod
+
+	// AutoMerge enables GitHub's native auto-merge on the pull request
+	// instead of polling and merging client-side. Wait then only polls
+	// until GitHub has merged it. Requires auto-merge to be allowed in
+	// the repository settings.
+	AutoMerge bool
+
+	// DeleteBranch deletes the head branch after the merge.
+	// Branches from forks are never deleted.
+	DeleteBranch bool
+
+	// PollInterval is how often Wait checks the pull request. Default: 10s.
+	PollInterval time.Duration
+
+	// CommitTitle is the title of the merge commit. Optional.
+	CommitTitle string
+
+	// CommitMessage is the body of the merge commit. Optional.
+	CommitMessage string
+}
+
+// PullRequestMerge tracks a pull request that is being merged.
+// Use Wait to block until the merge has completed.
+type PullRequestMerge struct {
+	backend *Backend
+	number  int
+	opts    MergeOptions
+
+	sha  string
+	done bool
+	mu   sync.Mutex
+}
+
+// MergePullRequest starts merging the pull request with the given number
+// in the configured repository.
+//
+// If opts.AutoMerge is set, GitHub's auto-merge is enabled immediately and
+// GitHub merges the pull request once all requirements are met. Otherwise
+// Wait polls the pull request and merges it as soon as required checks
+// have passed.
+func (b *Backend) MergePullRequest(ctx context.Context, number int, opts MergeOptions) (*PullRequestMerge, error) {
+	if err := b.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	switch opts.Method {
+	case "":
+		opts.Method = MergeMethodMerge
+	case MergeMethodMerge, MergeMethodSquash, MergeMethodRebase:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMergeMethod, opts.Method)
+	}
+
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultMergePollInterval
+	}
+
+	m := &PullRequestMerge{
+		backend: b,
+		number:  number,
+		opts:    opts,
+	}
+
+	if opts.AutoMerge {
+		if err := m.enableAutoMerge(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Number returns the pull request number.
+func (m *PullRequestMerge) Number() int {
+	return m.number
+}
+
+// Wait blocks until the pull request is merged and returns the SHA of the
+// resulting merge commit. It returns ErrPullRequestClosed if the pull
+// request is closed without merging, ErrPullRequestConflict if it has
+// conflicts with the base branch, or the context error if ctx is done first.
+// If the merge succeeded but MergeOptions.DeleteBranch failed, it returns
+// the SHA with an error wrapping ErrBranchNotDeleted; later calls return the
+// SHA alone.
+func (m *PullRequestMerge) Wait(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.done {
+		return m.sha, nil
+	}
+
+	for {
+		if err := m.backend.checkClosed(); err != nil {
+			return "", err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		sha, merged, err := m.poll(ctx)
+		if merged {
+			m.sha = sha
+			m.done = true
+			return sha, err
+		}
+		if err != nil {
+			return "", err
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(m.opts.PollInterval):
+		}
+	}
+}
+
+// poll checks the pull request once, merging it if it is ready.
+// It reports whether the pull request has been merged. A merged pull
+// request whose branch could not be deleted is reported with the merge
+// SHA and an error wrapping ErrBranchNotDeleted.
+func (m *PullRequestMerge) poll(ctx context.Context) (string, bool, error) {
+	b := m.backend
+
+	pr, resp, err := b.client.PullRequests.Get(ctx, b.config.Owner, b.config.Repo, m.number)
+	if err != nil {
+		return "", false, b.translateError(err, resp)
+	}
+
+	if pr.GetMerged() {
+		return pr.GetMergeCommitSHA(), true, m.deleteBranch(ctx, pr)
+	}
+
+	if pr.GetState() == "closed" {
+		return "", false, ErrPullRequestClosed
+	}
+
+	switch pr.GetMergeableState() {
+	case "dirty":
+		return "", false, ErrPullRequestConflict
+	case "clean", "unstable", "has_hooks":
+		// Required checks have passed.
+	default:
+		// blocked, behind, draft or unknown: keep waiting.
+		return "", false, nil
+	}
+
+	if m.opts.AutoMerge {
+		// GitHub performs the merge itself.
+		return "", false, nil
+	}
+
+	result, resp, err := b.client.PullRequests.Merge(
+		ctx,
+		b.config.Owner,
+		b.config.Repo,
+		m.number,
+		m.opts.CommitMessage,
+		&github.PullRequestOptions{
+			CommitTitle: m.opts.CommitTitle,
+			SHA:         pr.GetHead().GetSHA(),
+			MergeMethod: string(m.opts.Method),
+		},
+	)
+	if err != nil {
+		// 405: not mergeable yet, 409: head moved since we looked.
+		if resp != nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusConflict) {
+			return "", false, nil
+		}
+		return "", false, b.translateError(err, resp)
+	}
+
+	return result.GetSHA(), true, m.deleteBranch(ctx, pr)
+}
+
+// enableAutoMerge turns on GitHub's native auto-merge via GraphQL.
+func (m *PullRequestMerge) enableAutoMerge(ctx context.Context) error {
+	b := m.backend
+
+	pr, resp, err := b.client.PullRequests.Get(ctx, b.config.Owner, b.config.Repo, m.number)
+	if err != nil {
+		return b.translateError(err, resp)
+	}
+
+	const mutation = `mutation($id: ID!, $method: PullRequestMergeMethod!, $title: String, $body: String) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: $method, commitHeadline: $title, commitBody: $body}) {
+    clientMutationId
+  }
+}`
+
+	vars := map[string]any{
+		"id":     pr.GetNodeID(),
+		"method": strings.ToUpper(string(m.opts.Method)),
+	}
+	if m.opts.CommitTitle != "" {
+		vars["title"] = m.opts.CommitTitle
+	}
+	if m.opts.CommitMessage != "" {
+		vars["body"] = m.opts.CommitMessage
+	}
+
+	return b.graphQL(ctx, mutation, vars, nil)
+}
+
+// deleteBranch deletes the pull request's head branch if requested.
+// A branch that is already gone is not an error. Other errors wrap
+// ErrBranchNotDeleted.
+func (m *PullRequestMerge) deleteBranch(ctx context.Context, pr *github.PullRequest) error {
+	if !m.opts.DeleteBranch {
+		return nil
+	}
+
+	b := m.backend
+	head := pr.GetHead()
+
+	// Never delete branches that live in a fork.
+	if !strings.EqualFold(head.GetRepo().GetFullName(), b.config.Owner+"/"+b.config.Repo) {
+		return nil
+	}
+
+	resp, err := b.client.Git.DeleteRef(ctx, b.config.Owner, b.config.Repo, "heads/"+head.GetRef())
+	if err != nil {
+		// 404/422: already deleted (e.g. by the repository's auto-delete setting).
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnprocessableEntity) {
+			return nil
+		}
+		return fmt.Errorf("%w: %s: %w", ErrBranchNotDeleted, head.GetRef(), b.translateError(err, resp))
+	}
+
+	return nil
+}
@@ -0,0 +1,281 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// serverBackend creates a backend that talks to the given test server.
+func serverBackend(t *testing.T, srv *httptest.Server) *Backend {
+	t.Helper()
+
+	backend, err := New(Config{
+		Owner:     "owner",
+		Repo:      "repo",
+		Branch:    "main",
+		Token:     "token",
+		BaseURL:   srv.URL + "/",
+		UploadURL: srv.URL + "/",
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return backend
+}
+
+// fakePullRequest serves a single pull request whose mergeable state
+// advances through states on each GET.
+type fakePullRequest struct {
+	mu           sync.Mutex
+	states       []string
+	gets         int
+	merged       bool
+	mergeMethod  string
+	deletedRef   string
+	deleteStatus int // response to the branch deletion if set
+	graphQLQuery string
+}
+
+func (f *fakePullRequest) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/owner/repo/pulls/7":
+		state := f.states[len(f.states)-1]
+		if f.gets < len(f.states) {
+			state = f.states[f.gets]
+		}
+		f.gets++
+		pr := map[string]any{
+			"number":          7,
+			"node_id":         "PR_node",
+			"state":           "open",
+			"merged":          f.merged,
+			"mergeable_state": state,
+			"head": map[string]any{
+				"ref":  "feature",
+				"sha":  "headsha",
+				"repo": map[string]any{"full_name": "owner/repo"},
+			},
+		}
+		if f.merged {
+			pr["state"] = "closed"
+			pr["merge_commit_sha"] = "mergesha"
+		}
+		_ = json.NewEncoder(w).Encode(pr)
+
+	case r.Method == http.MethodPut && r.URL.Path == "/api/v3/repos/owner/repo/pulls/7/merge":
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.mergeMethod, _ = body["merge_method"].(string)
+		f.merged = true
+		_ = json.NewEncoder(w).Encode(map[string]any{"sha": "mergesha", "merged": true})
+
+	case r.Method == http.MethodDelete && r.URL.Path == "/api/v3/repos/owner/repo/git/refs/heads/feature":
+		if f.deleteStatus != 0 {
+			w.WriteHeader(f.deleteStatus)
+			_, _ = w.Write([]byte(`{"message":"Protected branch"}`))
+			return
+		}
+		f.deletedRef = "heads/feature"
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodPost && r.URL.Path == "/api/graphql":
+		var body graphQLRequest
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.graphQLQuery = body.Query
+		_, _ = w.Write([]byte(`{"data":{"enablePullRequestAutoMerge":{"clientMutationId":null}}}`))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestMergePullRequestWaitsForChecks(t *testing.T) {
+	fake := &fakePullRequest{states: []string{"blocked", "blocked", "clean"}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	backend := serverBackend(t, srv)
+	defer func() { _ = backend.Close() }()
+
+	ctx := context.Background()
+
+	m, err := backend.MergePullRequest(ctx, 7, MergeOptions{
+		Method:       MergeMethodSquash,
+		DeleteBranch: true,
+		PollInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("MergePullRequest failed: %v", err)
+	}
+
+	sha, err := m.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if sha != "mergesha" {
+		t.Errorf("Wait() = %q, want %q", sha, "mergesha")
+	}
+	if fake.gets != 3 {
+		t.Errorf("Expected 3 polls, got %d", fake.gets)
+	}
+	if fake.mergeMethod != "squash" {
+		t.Errorf("merge_method = %q, want %q", fake.mergeMethod, "squash")
+	}
+	if fake.deletedRef != "heads/feature" {
+		t.Errorf("Expected head branch to be deleted, got %q", fake.deletedRef)
+	}
+}
+
+func TestMergePullRequestDeleteBranchFails(t *testing.T) {
+	fake := &fakePullRequest{states: []string{"clean"}, deleteStatus: http.StatusForbidden}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	backend := serverBackend(t, srv)
+	defer func() { _ = backend.Close() }()
+
+	ctx := context.Background()
+	m, err := backend.MergePullRequest(ctx, 7, MergeOptions{DeleteBranch: true, PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("MergePullRequest failed: %v", err)
+	}
+
+	// The merge is reported even though the branch remains
+	sha, err := m.Wait(ctx)
+	if !errors.Is(err, ErrBranchNotDeleted) {
+		t.Errorf("Wait error = %v, want ErrBranchNotDeleted", err)
+	}
+	if sha != "mergesha" {
+		t.Errorf("Wait() = %q, want %q", sha, "mergesha")
+	}
+	if sha, err := m.Wait(ctx); sha != "mergesha" || err != nil {
+		t.Errorf("second Wait() = %q, %v, want %q, nil", sha, err, "mergesha")
+	}
+}
+
+func TestMergePullRequestAutoMerge(t *testing.T) {
+	fake := &fakePullRequest{states: []string{"blocked"}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	backend := serverBackend(t, srv)
+	defer func() { _ = backend.Close() }()
+
+	ctx := context.Background()
+
+	m, err := backend.MergePullRequest(ctx, 7, MergeOptions{
+		Method:       MergeMethodRebase,
+		AutoMerge:    true,
+		PollInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("MergePullRequest failed: %v", err)
+	}
+	if fake.graphQLQuery == "" {
+		t.Fatal("Expected auto-merge to be enabled via GraphQL")
+	}
+
+	// Simulate GitHub merging the pull request.
+	fake.mu.Lock()
+	fake.merged = true
+	fake.mu.Unlock()
+
+	sha, err := m.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if sha != "mergesha" {
+		t.Errorf("Wait() = %q, want %q", sha, "mergesha")
+	}
+	if fake.deletedRef != "" {
+		t.Errorf("Expected head branch to be kept, got %q deleted", fake.deletedRef)
+	}
+}
+
+func TestMergePullRequestConflict(t *testing.T) {
+	fake := &fakePullRequest{states: []string{"dirty"}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	backend := serverBackend(t, srv)
+	defer func() { _ = backend.Close() }()
+
+	ctx := context.Background()
+
+	m, err := backend.MergePullRequest(ctx, 7, MergeOptions{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("MergePullRequest failed: %v", err)
+	}
+
+	if _, err := m.Wait(ctx); !errors.Is(err, ErrPullRequestConflict) {
+		t.Errorf("Expected ErrPullRequestConflict, got: %v", err)
+	}
+}
+
+func TestMergePullRequestWaitDeadline(t *testing.T) {
+	fake := &fakePullRequest{states: []string{"blocked"}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	backend := serverBackend(t, srv)
+	defer func() { _ = backend.Close() }()
+
+	m, err := backend.MergePullRequest(context.Background(), 7, MergeOptions{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("MergePullRequest failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := m.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+}
+
+func TestMergePullRequestInvalidMethod(t *testing.T) {
+	backend, err := New(Config{Owner: "owner", Repo: "repo", Token: "token"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer func() { _ = backend.Close() }()
+
+	_, err = backend.MergePullRequest(context.Background(), 1, MergeOptions{Method: "fast-forward"})
+	if !errors.Is(err, ErrInvalidMergeMethod) {
+		t.Errorf("Expected ErrInvalidMergeMethod, got: %v", err)
+	}
+}
+
+func TestGraphQLURL(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"https://api.github.com/", "https://api.github.com/graphql"},
+		{"https://github.example.com/api/v3/", "https://github.example.com/api/graphql"},
+		{"https://github.example.com/", "https://github.example.com/api/graphql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.baseURL, func(t *testing.T) {
+			backend, err := New(Config{Owner: "owner", Repo: "repo", Token: "token", BaseURL: tt.baseURL})
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			if got := backend.graphQLURL(); got != tt.want {
+				t.Errorf("graphQLURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ErrInvalidCommitMessage is returned by Config.Validate for a commit
+// message template that does not parse.
+var ErrInvalidCommitMessage = errors.New("github: invalid commit message template")
+
+// Default commit message templates.
+const (
+	defaultCommitMessage       = "Update {path} via omnistorage"
+	defaultCommitMessageDelete = "Delete {path} via omnistorage"
+	defaultCommitMessageBatch  = "Update {{len .Files}} files via omnistorage"
+)
+
+// CommitMessageFile describes a file changed by a commit, for commit
+// message templates.
+type CommitMessageFile struct {
+	// Operation is ChangeCreate, ChangeUpdate or ChangeDelete.
+	Operation ChangeType
+
+	// Path is the path of the file, and Base its last element.
+	Path string
+	Base string
+
+	// Size is the size of the written content, or 0 for deletes.
+	Size int64
+
+	// SHA is the blob SHA of the written content, or of the deleted
+	// content for deletes. ShortSHA is its first 7 characters.
+	SHA      string
+	ShortSHA string
+}
+
+// CommitMessageData is the data of commit message templates. For a commit
+// that changes a single file, the fields of CommitMessageFile describe
+// that file; they are empty for batch commits of several files.
+type CommitMessageData struct {
+	CommitMessageFile
+
+	// Time is the author date of the commit.
+	Time time.Time
+
+	// Files are the files changed by the commit, in the order they were
+	// written or deleted.
+	Files []CommitMessageFile
+}
+
+// newCommitMessageFile describes a file for commit message templates.
+func newCommitMessageFile(op ChangeType, filePath string, size int64, sha string) CommitMessageFile {
+	return CommitMessageFile{
+		Operation: op,
+		Path:      filePath,
+		Base:      path.Base(filePath),
+		Size:      size,
+		SHA:       sha,
+		ShortSHA:  sha[:min(len(sha), 7)],
+	}
+}
+
+// newCommitMessageData returns the template data of a commit that changes
+// files.
+func newCommitMessageData(date time.Time, files ...CommitMessageFile) CommitMessageData {
+	data := CommitMessageData{Time: date, Files: files}
+	if len(files) == 1 {
+		data.CommitMessageFile = files[0]
+	}
+	return data
+}
+
+// commitMessageTemplate returns the template for an operation, or for a
+// batch commit of several files if op is "".
+func (c *Config) commitMessageTemplate(op ChangeType) string {
+	var candidates []string
+	switch op {
+	case ChangeCreate:
+		candidates = []string{c.CommitMessageCreate, c.CommitMessage, defaultCommitMessage}
+	case ChangeUpdate:
+		candidates = []string{c.CommitMessageUpdate, c.CommitMessage, defaultCommitMessage}
+	case ChangeDelete:
+		candidates = []string{c.CommitMessageDelete, defaultCommitMessageDelete}
+	default:
+		candidates = []string{c.CommitMessageBatch, defaultCommitMessageBatch}
+	}
+	for _, tmpl := range candidates {
+		if tmpl != "" {
+			return tmpl
+		}
+	}
+	return ""
+}
+
+// RenderCommitMessage renders the commit message template for data: the
+// template of data.Operation for single-file commits, or
+// CommitMessageBatch for batch commits of several files.
+func (c *Config) RenderCommitMessage(data CommitMessageData) (string, error) {
+	op := data.Operation
+	if len(data.Files) > 1 {
+		op = ""
+	}
+	return renderCommitMessage(c.commitMessageTemplate(op), data)
+}
+
+// renderCommitMessage executes a commit message template.
+func renderCommitMessage(tmpl string, data CommitMessageData) (string, error) {
+	t, err := parseCommitMessage(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidCommitMessage, err)
+	}
+	return buf.String(), nil
+}
+
+// parseCommitMessage parses a commit message template. The {path}
+// placeholder of earlier versions is shorthand for {{.Path}}.
+func parseCommitMessage(tmpl string) (*template.Template, error) {
+	t, err := template.New("message").Parse(strings.ReplaceAll(tmpl, "{path}", "{{.Path}}"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCommitMessage, err)
+	}
+	return t, nil
+}
+
+// validateCommitMessages checks that the configured templates parse.
+func (c *Config) validateCommitMessages() error {
+	for _, tmpl := range []string{c.CommitMessage, c.CommitMessageCreate, c.CommitMessageUpdate, c.CommitMessageDelete, c.CommitMessageBatch} {
+		if _, err := parseCommitMessage(tmpl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
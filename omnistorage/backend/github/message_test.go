@@ -0,0 +1,133 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRenderCommitMessage(t *testing.T) {
+	date := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	create := newCommitMessageFile(ChangeCreate, "docs/guide.md", 12, "0123456789abcdef")
+	remove := newCommitMessageFile(ChangeDelete, "old.txt", 0, "fedcba9876543210")
+
+	tests := []struct {
+		name string
+		cfg  Config
+		data CommitMessageData
+		want string
+	}{
+		{"default", Config{}, newCommitMessageData(date, create), "Update docs/guide.md via omnistorage"},
+		{"default delete", Config{}, newCommitMessageData(date, remove), "Delete old.txt via omnistorage"},
+		{"default batch", Config{}, newCommitMessageData(date, create, remove), "Update 2 files via omnistorage"},
+		{"legacy placeholder", Config{CommitMessage: "[bot] {path}"}, newCommitMessageData(date, create), "[bot] docs/guide.md"},
+		{
+			"fields",
+			Config{CommitMessage: `{{.Operation}} {{.Base}} ({{.Size}} bytes, {{.ShortSHA}}) at {{.Time.Format "2006-01-02"}}`},
+			newCommitMessageData(date, create),
+			"create guide.md (12 bytes, 0123456) at 2024-03-01",
+		},
+		{"create template", Config{CommitMessage: "Write {path}", CommitMessageCreate: "Add {path}"}, newCommitMessageData(date, create), "Add docs/guide.md"},
+		{"delete ignores write template", Config{CommitMessage: "Write {path}"}, newCommitMessageData(date, remove), "Delete old.txt via omnistorage"},
+		{
+			"batch files",
+			Config{CommitMessageBatch: "Sync\n{{range .Files}}\n{{.Operation}} {{.Path}}{{end}}"},
+			newCommitMessageData(date, create, remove),
+			"Sync\n\ncreate docs/guide.md\ndelete old.txt",
+		},
+		{"batch of one file", Config{CommitMessageBatch: "Sync"}, newCommitMessageData(date, remove), "Delete old.txt via omnistorage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.RenderCommitMessage(tt.data)
+			if err != nil {
+				t.Fatalf("RenderCommitMessage failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderCommitMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderCommitMessageInvalid(t *testing.T) {
+	cfg := Config{Owner: "o", Repo: "r", Token: "t", CommitMessageDelete: "Delete {{.Path"}
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidCommitMessage) {
+		t.Errorf("Validate() = %v, want ErrInvalidCommitMessage", err)
+	}
+
+	cfg = Config{CommitMessage: "{{.Missing}}"}
+	_, err := cfg.RenderCommitMessage(newCommitMessageData(time.Now(), newCommitMessageFile(ChangeUpdate, "a.txt", 1, "")))
+	if !errors.Is(err, ErrInvalidCommitMessage) {
+		t.Errorf("RenderCommitMessage() = %v, want ErrInvalidCommitMessage", err)
+	}
+}
+
+func TestCommitMessageTemplates(t *testing.T) {
+	backend, _ := fakeServerBackend(t)
+	defer func() { _ = backend.Close() }()
+	backend.config.CommitMessageCreate = "Add {{.Base}}"
+	backend.config.CommitMessageUpdate = "Change {{.Path}} to {{.ShortSHA}}"
+	backend.config.CommitMessageDelete = "Remove {{.Path}}"
+	backend.config.CommitMessageBatch = "Sync {{range $i, $f := .Files}}{{if $i}}, {{end}}{{$f.Path}}{{end}}"
+	ctx := context.Background()
+
+	headMessage := func() string {
+		t.Helper()
+		ref, _, err := backend.client.Git.GetRef(ctx, "grokify", "omnistorage", "heads/main")
+		if err != nil {
+			t.Fatalf("GetRef failed: %v", err)
+		}
+		c, _, err := backend.client.Git.GetCommit(ctx, "grokify", "omnistorage", ref.GetObject().GetSHA())
+		if err != nil {
+			t.Fatalf("GetCommit failed: %v", err)
+		}
+		return c.GetMessage()
+	}
+
+	mustWrite(t, ctx, backend, "docs/guide.md", []byte("v1"))
+	if got, want := headMessage(), "Add guide.md"; got != want {
+		t.Errorf("create message = %q, want %q", got, want)
+	}
+
+	mustWrite(t, ctx, backend, "docs/guide.md", []byte("v2"))
+	if got, want := headMessage(), "Change docs/guide.md to "+GitBlobSHA([]byte("v2"))[:7]; got != want {
+		t.Errorf("update message = %q, want %q", got, want)
+	}
+
+	if err := backend.Delete(ctx, "docs/guide.md"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if got, want := headMessage(), "Remove docs/guide.md"; got != want {
+		t.Errorf("delete message = %q, want %q", got, want)
+	}
+
+	batch, err := backend.NewBatch(ctx, "")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	_ = batch.Write("a.txt", []byte("a"))
+	_ = batch.Delete("backend/file/file.go")
+	_ = batch.Write("README.md", []byte("# Omnistorage\n\nA unified storage abstraction for Go.\n")) // unchanged
+	if _, err := batch.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if got, want := headMessage(), "Sync a.txt, backend/file/file.go"; got != want {
+		t.Errorf("batch message = %q, want %q", got, want)
+	}
+}
+
+func TestConfigCommitMessageTemplates(t *testing.T) {
+	cfg := ConfigFromMap(map[string]string{
+		"commit_message_create": "Add {path}",
+		"commit_message_update": "Change {path}",
+		"commit_message_delete": "Remove {path}",
+		"commit_message_batch":  "Sync {{len .Files}} files",
+	})
+	if cfg.CommitMessageCreate != "Add {path}" || cfg.CommitMessageUpdate != "Change {path}" ||
+		cfg.CommitMessageDelete != "Remove {path}" || cfg.CommitMessageBatch != "Sync {{len .Files}} files" {
+		t.Errorf("ConfigFromMap() templates = %q, %q, %q, %q",
+			cfg.CommitMessageCreate, cfg.CommitMessageUpdate, cfg.CommitMessageDelete, cfg.CommitMessageBatch)
+	}
+}
@@ -0,0 +1,236 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// ErrSignOffAuthorRequired is returned by commits with SignOff but no
+// author to sign off as.
+var ErrSignOffAuthorRequired = errors.New("github: commit author is required to sign off commits")
+
+// CommitTrailer is a "Key: Value" line at the end of a commit message,
+// such as "Reviewed-by: Jane Doe <jane@example.com>".
+type CommitTrailer struct {
+	Key   string
+	Value string
+}
+
+// CommitOptions sets the metadata of commits. Zero fields fall back to
+// the Config.
+type CommitOptions struct {
+	// Author overrides Config.CommitAuthor.
+	Author *CommitAuthor
+
+	// Committer overrides Config.CommitCommitter.
+	Committer *CommitAuthor
+
+	// AuthorDate and CommitDate set the commit dates. Default: the time
+	// the commit is created.
+	AuthorDate time.Time
+	CommitDate time.Time
+
+	// CoAuthors are added as Co-authored-by trailers, after those of
+	// Config.CommitCoAuthors.
+	CoAuthors []CommitAuthor
+
+	// Trailers are added after those of Config.CommitTrailers.
+	Trailers []CommitTrailer
+
+	// SignOff adds a Signed-off-by trailer for the author, like
+	// git commit --signoff. Config.CommitSignOff turns it on for all
+	// commits.
+	SignOff bool
+}
+
+type commitOptionsKey struct{}
+
+// WithCommitOptions returns a context that applies opts to the commits
+// made with it: by writers and batches created with the context, and by
+// Delete. Like writer options, it gives a write a commit of its own in
+// auto-batch mode.
+func WithCommitOptions(ctx context.Context, opts CommitOptions) context.Context {
+	return context.WithValue(ctx, commitOptionsKey{}, opts)
+}
+
+// SetCommitOptions sets the metadata of the commit created by Commit,
+// replacing the options of the batch's context.
+func (batch *Batch) SetCommitOptions(opts CommitOptions) error {
+	batch.mu.Lock()
+	defer batch.mu.Unlock()
+
+	if batch.committed {
+		return fmt.Errorf("github: batch already committed")
+	}
+
+	batch.options = &opts
+	return nil
+}
+
+// hasCommitOptions reports whether ctx carries options set with
+// WithCommitOptions.
+func hasCommitOptions(ctx context.Context) bool {
+	_, ok := ctx.Value(commitOptionsKey{}).(CommitOptions)
+	return ok
+}
+
+// contextCommitOptions returns the options set with WithCommitOptions.
+func contextCommitOptions(ctx context.Context) CommitOptions {
+	opts, _ := ctx.Value(commitOptionsKey{}).(CommitOptions)
+	return opts
+}
+
+// commitOptions returns the options of ctx merged with the Config.
+func (b *Backend) commitOptions(ctx context.Context) (CommitOptions, error) {
+	return b.resolveCommitOptions(contextCommitOptions(ctx))
+}
+
+// resolveCommitOptions merges opts with the Config.
+func (b *Backend) resolveCommitOptions(opts CommitOptions) (CommitOptions, error) {
+	cfg := b.config
+	if opts.Author == nil {
+		opts.Author = cfg.CommitAuthor
+	}
+	if opts.Committer == nil {
+		opts.Committer = cfg.CommitCommitter
+	}
+	opts.CoAuthors = append(append([]CommitAuthor(nil), cfg.CommitCoAuthors...), opts.CoAuthors...)
+	opts.Trailers = append(append([]CommitTrailer(nil), cfg.CommitTrailers...), opts.Trailers...)
+	opts.SignOff = opts.SignOff || cfg.CommitSignOff
+
+	if opts.SignOff && opts.Author == nil {
+		return CommitOptions{}, ErrSignOffAuthorRequired
+	}
+	return opts, nil
+}
+
+// date returns the author date of a commit made now.
+func (o CommitOptions) date() time.Time {
+	if !o.AuthorDate.IsZero() {
+		return o.AuthorDate
+	}
+	return time.Now()
+}
+
+// author returns the commit author, or nil to let GitHub use the
+// authenticated user.
+func (o CommitOptions) author() *github.CommitAuthor {
+	return commitIdentity(o.Author, o.AuthorDate)
+}
+
+// committer returns the committer, or nil to let GitHub use the author.
+// A commit date without a committer applies to the author acting as
+// committer.
+func (o CommitOptions) committer() *github.CommitAuthor {
+	switch {
+	case o.Committer != nil:
+		return commitIdentity(o.Committer, o.CommitDate)
+	case o.Author != nil && !o.CommitDate.IsZero():
+		return commitIdentity(o.Author, o.CommitDate)
+	}
+	return nil
+}
+
+// commitIdentity converts an identity for the API.
+func commitIdentity(id *CommitAuthor, date time.Time) *github.CommitAuthor {
+	if id == nil {
+		return nil
+	}
+	out := &github.CommitAuthor{
+		Name:  github.Ptr(id.Name),
+		Email: github.Ptr(id.Email),
+	}
+	if !date.IsZero() {
+		out.Date = &github.Timestamp{Time: date}
+	}
+	return out
+}
+
+// message appends the trailers of the options to a commit message:
+// co-authors, then other trailers, then the sign-off.
+func (o CommitOptions) message(message string) string {
+	var trailers []CommitTrailer
+	for _, coAuthor := range o.CoAuthors {
+		trailers = append(trailers, CommitTrailer{Key: "Co-authored-by", Value: coAuthor.String()})
+	}
+	trailers = append(trailers, o.Trailers...)
+	if o.SignOff {
+		trailers = append(trailers, CommitTrailer{Key: "Signed-off-by", Value: o.Author.String()})
+	}
+	return appendTrailers(message, trailers)
+}
+
+// String formats the identity as "Name <email>".
+func (a CommitAuthor) String() string {
+	return a.Name + " <" + a.Email + ">"
+}
+
+// trailerLine matches a line of a commit message trailer block.
+var trailerLine = regexp.MustCompile(`^[A-Za-z0-9-]+: `)
+
+// appendTrailers adds trailers to the end of a commit message, joining an
+// existing trailer block like git interpret-trailers. Trailers already in
+// the message are not repeated.
+func appendTrailers(message string, trailers []CommitTrailer) string {
+	if len(trailers) == 0 {
+		return message
+	}
+
+	message = strings.TrimRight(message, "\n")
+	existing := make(map[string]bool)
+	for _, line := range strings.Split(message, "\n") {
+		existing[line] = true
+	}
+
+	var lines []string
+	for _, t := range trailers {
+		line := t.Key + ": " + t.Value
+		if !existing[line] {
+			existing[line] = true
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return message
+	}
+
+	// The subject line is never a trailer block
+	separator := "\n\n"
+	if i := strings.LastIndex(message, "\n\n"); i >= 0 && isTrailerBlock(message[i+2:]) {
+		separator = "\n"
+	}
+	return message + separator + strings.Join(lines, "\n")
+}
+
+// isTrailerBlock reports whether every line of a paragraph is a trailer.
+func isTrailerBlock(paragraph string) bool {
+	for _, line := range strings.Split(paragraph, "\n") {
+		if !trailerLine.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseIdentities parses a comma-separated list of "Name <email>"
+// identities, skipping malformed entries.
+func parseIdentities(s string) []CommitAuthor {
+	var ids []CommitAuthor
+	for _, part := range strings.Split(s, ",") {
+		name, email, ok := strings.Cut(strings.TrimSpace(part), "<")
+		if !ok || !strings.HasSuffix(email, ">") {
+			continue
+		}
+		ids = append(ids, CommitAuthor{
+			Name:  strings.TrimSpace(name),
+			Email: strings.TrimSuffix(email, ">"),
+		})
+	}
+	return ids
+}
@@ -0,0 +1,134 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAppendTrailers(t *testing.T) {
+	signOff := []CommitTrailer{{Key: "Signed-off-by", Value: "Jane Doe <jane@example.com>"}}
+
+	tests := []struct {
+		name     string
+		message  string
+		trailers []CommitTrailer
+		want     string
+	}{
+		{"none", "Update a.txt\n", nil, "Update a.txt\n"},
+		{"subject only", "Update a.txt", signOff, "Update a.txt\n\nSigned-off-by: Jane Doe <jane@example.com>"},
+		{"trailing newline", "Update a.txt\n\n", signOff, "Update a.txt\n\nSigned-off-by: Jane Doe <jane@example.com>"},
+		{"body", "Update a.txt\n\nFix: the typo.\nMore text.", signOff, "Update a.txt\n\nFix: the typo.\nMore text.\n\nSigned-off-by: Jane Doe <jane@example.com>"},
+		{"existing block", "Update a.txt\n\nRefs: #12", signOff, "Update a.txt\n\nRefs: #12\nSigned-off-by: Jane Doe <jane@example.com>"},
+		{"duplicate", "Update a.txt\n\nSigned-off-by: Jane Doe <jane@example.com>", signOff, "Update a.txt\n\nSigned-off-by: Jane Doe <jane@example.com>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := appendTrailers(tt.message, tt.trailers); got != tt.want {
+				t.Errorf("appendTrailers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommitOptions(t *testing.T) {
+	backend, _ := fakeServerBackend(t)
+	defer func() { _ = backend.Close() }()
+	backend.config.CommitAuthor = &CommitAuthor{Name: "Jane Doe", Email: "jane@example.com"}
+	backend.config.CommitCommitter = &CommitAuthor{Name: "Deploy Bot", Email: "bot@example.com"}
+	backend.config.CommitSignOff = true
+
+	authorDate := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ctx := WithCommitOptions(context.Background(), CommitOptions{
+		AuthorDate: authorDate,
+		CoAuthors:  []CommitAuthor{{Name: "John Roe", Email: "john@example.com"}},
+		Trailers:   []CommitTrailer{{Key: "Refs", Value: "#42"}},
+	})
+
+	headCommit := func() (message, author, committer string, date time.Time) {
+		t.Helper()
+		ref, _, err := backend.client.Git.GetRef(context.Background(), "grokify", "omnistorage", "heads/main")
+		if err != nil {
+			t.Fatalf("GetRef failed: %v", err)
+		}
+		c, _, err := backend.client.Git.GetCommit(context.Background(), "grokify", "omnistorage", ref.GetObject().GetSHA())
+		if err != nil {
+			t.Fatalf("GetCommit failed: %v", err)
+		}
+		return c.GetMessage(), c.GetAuthor().GetName(), c.GetCommitter().GetName(), c.GetAuthor().GetDate().Time
+	}
+
+	const trailers = "\n\nCo-authored-by: John Roe <john@example.com>\nRefs: #42\nSigned-off-by: Jane Doe <jane@example.com>"
+
+	mustWrite(t, ctx, backend, "docs/a.md", []byte("a"))
+	message, author, committer, date := headCommit()
+	if want := "Update docs/a.md via omnistorage" + trailers; message != want {
+		t.Errorf("write message = %q, want %q", message, want)
+	}
+	if author != "Jane Doe" || committer != "Deploy Bot" || !date.Equal(authorDate) {
+		t.Errorf("write author, committer, date = %q, %q, %v", author, committer, date)
+	}
+
+	if err := backend.Delete(ctx, "docs/a.md"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if message, _, committer, _ = headCommit(); message != "Delete docs/a.md via omnistorage"+trailers || committer != "Deploy Bot" {
+		t.Errorf("delete message, committer = %q, %q", message, committer)
+	}
+
+	// Batch options replace those of the context
+	batch, err := backend.NewBatch(ctx, "Add b")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	if err := batch.SetCommitOptions(CommitOptions{Author: &CommitAuthor{Name: "John Roe", Email: "john@example.com"}}); err != nil {
+		t.Fatalf("SetCommitOptions failed: %v", err)
+	}
+	if err := batch.Write("docs/b.md", []byte("b")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, err := batch.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	message, author, committer, _ = headCommit()
+	if want := "Add b\n\nSigned-off-by: John Roe <john@example.com>"; message != want {
+		t.Errorf("batch message = %q, want %q", message, want)
+	}
+	if author != "John Roe" || committer != "Deploy Bot" {
+		t.Errorf("batch author, committer = %q, %q", author, committer)
+	}
+}
+
+func TestCommitOptionsSignOffAuthorRequired(t *testing.T) {
+	backend, _ := fakeServerBackend(t)
+	defer func() { _ = backend.Close() }()
+
+	ctx := WithCommitOptions(context.Background(), CommitOptions{SignOff: true})
+	w, err := backend.NewWriter(ctx, "docs/a.md")
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	_, _ = w.Write([]byte("a"))
+	if err := w.Close(); !errors.Is(err, ErrSignOffAuthorRequired) {
+		t.Errorf("Close() = %v, want ErrSignOffAuthorRequired", err)
+	}
+}
+
+func TestConfigCommitMetadata(t *testing.T) {
+	cfg := ConfigFromMap(map[string]string{
+		"commit_committer_name":  "Deploy Bot",
+		"commit_committer_email": "bot@example.com",
+		"commit_co_authors":      "Jane Doe <jane@example.com>, John Roe <john@example.com>, invalid",
+		"commit_signoff":         "true",
+	})
+	if cfg.CommitCommitter == nil || cfg.CommitCommitter.String() != "Deploy Bot <bot@example.com>" {
+		t.Errorf("CommitCommitter = %v", cfg.CommitCommitter)
+	}
+	if len(cfg.CommitCoAuthors) != 2 || cfg.CommitCoAuthors[1].String() != "John Roe <john@example.com>" {
+		t.Errorf("CommitCoAuthors = %v", cfg.CommitCoAuthors)
+	}
+	if !cfg.CommitSignOff {
+		t.Error("CommitSignOff = false")
+	}
+}
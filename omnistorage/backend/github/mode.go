@@ -0,0 +1,205 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/grokify/gogithub/pathutil"
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+// ErrSubmodule is returned when reading a submodule, whose content is in
+// another repository.
+var ErrSubmodule = errors.New("github: path is a submodule")
+
+// FileMode is the Git mode of a file.
+type FileMode string
+
+const (
+	// FileModeRegular is a regular file.
+	FileModeRegular FileMode = "100644"
+	// FileModeExecutable is an executable file.
+	FileModeExecutable FileMode = "100755"
+	// FileModeSymlink is a symbolic link whose content is the target path.
+	FileModeSymlink FileMode = "120000"
+	// FileModeSubmodule is a submodule. It is reported by Stat and
+	// ListInfo, but cannot be written.
+	FileModeSubmodule FileMode = "160000"
+)
+
+// ObjectInfo metadata keys reported by Stat and ListInfo.
+const (
+	// MetadataFileMode is the Git mode of a file, such as "100755".
+	MetadataFileMode = "mode"
+
+	// MetadataSymlinkTarget is the target path of a symbolic link.
+	MetadataSymlinkTarget = "symlink_target"
+
+	// MetadataSubmoduleCommit is the commit SHA a submodule points to.
+	MetadataSubmoduleCommit = "submodule_commit"
+)
+
+// checkFileMode checks that a file can be written with a mode.
+func checkFileMode(mode FileMode) error {
+	switch mode {
+	case FileModeRegular, FileModeExecutable, FileModeSymlink:
+		return nil
+	}
+	return fmt.Errorf("github: invalid file mode %q", mode)
+}
+
+// fileInfo describes a file with the given blob SHA and mode. An empty
+// mode is unknown and not reported.
+func fileInfo(normalPath string, size int64, sha string, mode FileMode) *omnistorage.BasicObjectInfo {
+	info := &omnistorage.BasicObjectInfo{
+		ObjectPath:  normalPath,
+		ObjectSize:  size,
+		ObjectIsDir: false,
+		ObjectHashes: map[omnistorage.HashType]string{
+			HashGitBlob: sha,
+		},
+		ObjectMetadata: map[string]string{},
+	}
+	if mode != "" {
+		info.ObjectMetadata[MetadataFileMode] = string(mode)
+	}
+	return info
+}
+
+// symlinkInfo describes a symbolic link. Its content, and so its size, is
+// the target path.
+func symlinkInfo(normalPath, sha, target string) *omnistorage.BasicObjectInfo {
+	info := fileInfo(normalPath, int64(len(target)), sha, FileModeSymlink)
+	info.ObjectMetadata[MetadataSymlinkTarget] = target
+	return info
+}
+
+// submoduleInfo describes a submodule. It has no content in the
+// repository, so no size or hash.
+func submoduleInfo(normalPath, commitSHA string) *omnistorage.BasicObjectInfo {
+	return &omnistorage.BasicObjectInfo{
+		ObjectPath:  normalPath,
+		ObjectIsDir: false,
+		ObjectMetadata: map[string]string{
+			MetadataFileMode:        string(FileModeSubmodule),
+			MetadataSubmoduleCommit: commitSHA,
+		},
+	}
+}
+
+// lookupTreeEntry returns the tree entry of a path at a ref, or nil if the
+// path does not exist. The Contents API does not report file modes, so
+// this reads the tree of the parent directory.
+func (b *Backend) lookupTreeEntry(ctx context.Context, ref, normalPath string) (*github.TreeEntry, error) {
+	dir, name := path.Split(normalPath)
+	treeish := ref
+	if dir != "" {
+		treeish = ref + ":" + strings.TrimSuffix(dir, "/")
+	}
+
+	tree, resp, err := b.client.Git.GetTree(ctx, b.config.Owner, b.config.Repo, treeish, false)
+	if err != nil {
+		if resp != nil && resp.StatusCode == 404 {
+			return nil, nil
+		}
+		return nil, b.translateError(err, resp)
+	}
+
+	for _, entry := range tree.Entries {
+		if entry.GetPath() == name {
+			return entry, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListInfo returns the metadata of the files with the given prefix, in
+// path order. Unlike List, it includes submodules. Every entry reports
+// its Git mode under MetadataFileMode.
+//
+// The metadata comes from the recursive tree of the branch, with one more
+// request per symbolic link and per possible Git LFS pointer, like Stat.
+// If GitHub truncates the recursive tree, it is listed one directory per
+// request instead.
+// With Config.StatModTime, the ModTimes are looked up in bulk with the
+// GraphQL API. With Config.StatContentSHA1, every file reports HashSHA1.
+func (b *Backend) ListInfo(ctx context.Context, prefix string) ([]omnistorage.ObjectInfo, error) {
+	if err := b.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	normalPrefix := pathutil.Normalize(prefix)
+
+	snapshot, err := b.treeSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		head, err := b.headCommit(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot, err = b.loadTreeSnapshot(ctx, head); err != nil {
+			return nil, err
+		}
+		if snapshot.truncated {
+			if snapshot.entries, err = b.walkTree(ctx, head); err != nil {
+				return nil, err
+			}
+		}
+		if attrs, ok := snapshot.entries[".gitattributes"]; ok && !b.config.DisableLFS {
+			if snapshot.lfsPatterns, err = b.lfsPatternsAt(ctx, attrs.sha); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var paths []string
+	for p, entry := range snapshot.entries {
+		if (entry.typ == "blob" || entry.typ == "commit") && strings.HasPrefix(p, normalPrefix) {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	infos := make([]*omnistorage.BasicObjectInfo, 0, len(paths))
+	for _, p := range paths {
+		info, ok, err := snapshot.stat(p)
+		if !ok {
+			info, err = b.statContents(ctx, p, snapshot.entries[p])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("github: stat %s: %w", p, err)
+		}
+		infos = append(infos, info)
+	}
+
+	if b.config.StatContentSHA1 {
+		for _, info := range infos {
+			if err := b.setContentSHA1(ctx, info); err != nil {
+				return nil, fmt.Errorf("github: hashing %s: %w", info.ObjectPath, err)
+			}
+		}
+	}
+
+	if b.config.StatModTime {
+		if err := b.setModTimes(ctx, snapshot.commitSHA, infos); err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]omnistorage.ObjectInfo, len(infos))
+	for i, info := range infos {
+		out[i] = info
+	}
+	return out, nil
+}
@@ -0,0 +1,197 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/plexusone/omni-github/omnistorage/backend/github/githubtest"
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+// modeBackend returns a fake server backend whose branch has an
+// executable, a symbolic link and a submodule.
+func modeBackend(t *testing.T, treeCache bool) (*Backend, *githubtest.Server) {
+	t.Helper()
+	backend, srv := fakeServerBackend(t)
+	t.Cleanup(func() { _ = backend.Close() })
+	backend.config.TreeCache = treeCache
+
+	srv.CommitEntry(githubtest.DefaultBranch, "Add script", "scripts/run.sh", "100755", []byte("#!/bin/sh\n"))
+	srv.CommitEntry(githubtest.DefaultBranch, "Add link", "docs/readme", "120000", []byte("../README.md"))
+	srv.CommitEntry(githubtest.DefaultBranch, "Add submodule", "vendor/lib", "160000", []byte("0123456789abcdef0123456789abcdef01234567"))
+	return backend, srv
+}
+
+func TestStatFileModes(t *testing.T) {
+	for _, treeCache := range []bool{false, true} {
+		backend, _ := modeBackend(t, treeCache)
+		backend.config.StatFileMode = true
+		ctx := context.Background()
+
+		tests := []struct {
+			path     string
+			metadata map[string]string
+			size     int64
+		}{
+			{"README.md", map[string]string{MetadataFileMode: "100644"}, 53},
+			{"scripts/run.sh", map[string]string{MetadataFileMode: "100755"}, 10},
+			{"docs/readme", map[string]string{MetadataFileMode: "120000", MetadataSymlinkTarget: "../README.md"}, 12},
+			{"vendor/lib", map[string]string{MetadataFileMode: "160000", MetadataSubmoduleCommit: "0123456789abcdef0123456789abcdef01234567"}, 0},
+		}
+		for _, tt := range tests {
+			info, err := backend.Stat(ctx, tt.path)
+			if err != nil {
+				t.Fatalf("treeCache=%v: Stat(%q) failed: %v", treeCache, tt.path, err)
+			}
+			if info.IsDir() || info.Size() != tt.size {
+				t.Errorf("treeCache=%v: Stat(%q) IsDir = %v, Size = %d, want false, %d", treeCache, tt.path, info.IsDir(), info.Size(), tt.size)
+			}
+			for key, want := range tt.metadata {
+				if got := info.Metadata()[key]; got != want {
+					t.Errorf("treeCache=%v: Stat(%q) metadata %s = %q, want %q", treeCache, tt.path, key, got, want)
+				}
+			}
+		}
+
+		infos, err := backend.ListInfo(ctx, "")
+		if err != nil {
+			t.Fatalf("treeCache=%v: ListInfo failed: %v", treeCache, err)
+		}
+		var paths []string
+		for _, info := range infos {
+			paths = append(paths, info.Path()+" "+info.Metadata()[MetadataFileMode])
+		}
+		want := []string{
+			"README.md 100644",
+			"backend/file/file.go 100644",
+			"backend/memory/mem.go 100644",
+			"docs/readme 120000",
+			"scripts/run.sh 100755",
+			"vendor/lib 160000",
+		}
+		if len(paths) != len(want) {
+			t.Fatalf("treeCache=%v: ListInfo = %q, want %q", treeCache, paths, want)
+		}
+		for i := range want {
+			if paths[i] != want[i] {
+				t.Errorf("treeCache=%v: ListInfo[%d] = %q, want %q", treeCache, i, paths[i], want[i])
+			}
+		}
+	}
+}
+
+func TestListInfoTruncatedTree(t *testing.T) {
+	for _, treeCache := range []bool{false, true} {
+		backend, srv := modeBackend(t, treeCache)
+		srv.SetTreeLimit(2)
+
+		infos, err := backend.ListInfo(context.Background(), "")
+		if err != nil {
+			t.Fatalf("treeCache=%v: ListInfo failed: %v", treeCache, err)
+		}
+		if len(infos) != 6 {
+			t.Errorf("treeCache=%v: ListInfo returned %d entries from a truncated tree, want 6", treeCache, len(infos))
+		}
+	}
+}
+
+func TestStatFileModeRequests(t *testing.T) {
+	backend, srv := modeBackend(t, false)
+	ctx := context.Background()
+
+	// Without StatFileMode, Stat of a regular file is one request
+	srv.ResetRequests()
+	info, err := backend.Stat(ctx, "scripts/run.sh")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if mode, ok := info.Metadata()[MetadataFileMode]; ok {
+		t.Errorf("mode = %q without StatFileMode, want none", mode)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("Stat made %d requests, want 1: %v", n, srv.Requests())
+	}
+
+	// Symbolic links are still recognized
+	info, err = backend.Stat(ctx, "docs/readme")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Metadata()[MetadataFileMode] != string(FileModeSymlink) {
+		t.Errorf("docs/readme metadata = %v, want a symbolic link", info.Metadata())
+	}
+}
+
+func TestReadSymlinkAndSubmodule(t *testing.T) {
+	for _, cached := range []bool{false, true} {
+		backend, _ := modeBackend(t, false)
+		if cached {
+			backend.config.Cache = NewMemoryCache(0)
+		}
+		ctx := context.Background()
+
+		assertContent(t, ctx, backend, "docs/readme", []byte("../README.md"))
+
+		_, err := backend.NewReader(ctx, "vendor/lib")
+		if !errors.Is(err, ErrSubmodule) {
+			t.Errorf("cache=%v: NewReader(submodule) = %v, want ErrSubmodule", cached, err)
+		}
+	}
+}
+
+func TestWritePreservesFileMode(t *testing.T) {
+	backend, srv := modeBackend(t, false)
+	ctx := context.Background()
+
+	// The Contents API keeps the mode of executables, and symbolic links
+	// are written through a batch
+	mustWrite(t, ctx, backend, "scripts/run.sh", []byte("#!/bin/sh\nexit 0\n"))
+	mustWrite(t, ctx, backend, "docs/readme", []byte("../CHANGELOG.md"))
+	if mode := treeMode(t, backend, srv, "main", "scripts/run.sh"); mode != "100755" {
+		t.Errorf("scripts/run.sh mode = %s, want 100755", mode)
+	}
+	if mode := treeMode(t, backend, srv, "main", "docs/readme"); mode != "120000" {
+		t.Errorf("docs/readme mode = %s, want 120000", mode)
+	}
+	assertContent(t, ctx, backend, "docs/readme", []byte("../CHANGELOG.md"))
+
+	batch, err := backend.NewBatch(ctx, "Update tools")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	if err := batch.Write("scripts/run.sh", []byte("#!/bin/sh\nexit 1\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := batch.WriteMode("bin/tool", []byte("../scripts/run.sh"), FileModeSymlink); err != nil {
+		t.Fatalf("WriteMode failed: %v", err)
+	}
+	if err := batch.WriteMode("bin/other", nil, FileModeSubmodule); err == nil {
+		t.Error("WriteMode with the submodule mode succeeded")
+	}
+	if _, err := batch.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if mode := treeMode(t, backend, srv, "main", "scripts/run.sh"); mode != "100755" {
+		t.Errorf("batch: scripts/run.sh mode = %s, want 100755", mode)
+	}
+	info, err := backend.Stat(ctx, "bin/tool")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if target := info.Metadata()[MetadataSymlinkTarget]; target != "../scripts/run.sh" {
+		t.Errorf("bin/tool target = %q, want ../scripts/run.sh", target)
+	}
+
+	// The GraphQL engine cannot keep the mode
+	backend.config.CommitEngine = CommitEngineGraphQL
+	w, err := backend.NewWriter(ctx, "scripts/run.sh")
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	_, _ = io.WriteString(w, "#!/bin/sh\nexit 2\n")
+	if err := w.Close(); !errors.Is(err, omnistorage.ErrNotSupported) {
+		t.Errorf("GraphQL overwrite of an executable = %v, want ErrNotSupported", err)
+	}
+}
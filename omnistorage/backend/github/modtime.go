@@ -0,0 +1,177 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+// modTimeBatchSize is the number of paths whose last commit is looked up
+// per GraphQL request.
+const modTimeBatchSize = 50
+
+// modTimesQuery looks up the last commit of several paths. The variable
+// declarations and the aliased history fields, one per path, are added
+// with fmt.Sprintf.
+const modTimesQuery = `query($owner: String!, $name: String!, $expression: String!%s) {
+  repository(owner: $owner, name: $name) {
+    object(expression: $expression) {
+      ... on Commit {
+%s      }
+    }
+  }
+}`
+
+// modTimeCache remembers the time of the last commit that changed each
+// path, as of one commit of the branch. Any commit can change it, even one
+// that restores earlier content, so the times are only kept across the
+// commits of this backend, whose changed paths are known.
+type modTimeCache struct {
+	mu     sync.Mutex
+	commit string
+	times  map[string]time.Time
+}
+
+func (c *modTimeCache) get(commit, filePath string) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if commit != c.commit {
+		return time.Time{}, false
+	}
+	t, ok := c.times[filePath]
+	return t, ok
+}
+
+func (c *modTimeCache) set(commit, filePath string, t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if commit != c.commit || c.times == nil {
+		c.commit = commit
+		c.times = make(map[string]time.Time)
+	}
+	c.times[filePath] = t
+}
+
+// advance moves the cache from parentSHA to commitSHA, a commit made by
+// this backend, forgetting the paths it changed. The cache is left as is,
+// and so unused, if it is not at parentSHA.
+func (c *modTimeCache) advance(parentSHA, commitSHA string, changes []treeChange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.commit != parentSHA {
+		return
+	}
+	for _, change := range changes {
+		delete(c.times, change.path)
+	}
+	c.commit = commitSHA
+}
+
+// headCommit returns the commit at the head of the branch, from the tree
+// cache when enabled.
+func (b *Backend) headCommit(ctx context.Context) (string, error) {
+	snapshot, err := b.treeSnapshot(ctx)
+	if err != nil {
+		return "", err
+	}
+	if snapshot != nil {
+		return snapshot.commitSHA, nil
+	}
+
+	ref, resp, err := b.client.Git.GetRef(ctx, b.config.Owner, b.config.Repo, "heads/"+b.config.Branch)
+	if err != nil {
+		return "", b.translateError(err, resp)
+	}
+	return ref.GetObject().GetSHA(), nil
+}
+
+// setModTime sets the ModTime of a file to the time of the last commit
+// that changed it.
+func (b *Backend) setModTime(ctx context.Context, info *omnistorage.BasicObjectInfo) error {
+	head, err := b.headCommit(ctx)
+	if err != nil {
+		return err
+	}
+	if t, ok := b.modTimes.get(head, info.ObjectPath); ok {
+		info.ObjectModTime = t
+		return nil
+	}
+
+	commits, resp, err := b.client.Repositories.ListCommits(ctx, b.config.Owner, b.config.Repo, &github.CommitsListOptions{
+		SHA:         head,
+		Path:        info.ObjectPath,
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		return b.translateError(err, resp)
+	}
+	if len(commits) == 0 {
+		return nil
+	}
+
+	info.ObjectModTime = commits[0].GetCommit().GetCommitter().GetDate().Time
+	b.modTimes.set(head, info.ObjectPath, info.ObjectModTime)
+	return nil
+}
+
+// setModTimes sets the ModTime of files to the time of the last commit
+// that changed them as of commitSHA, looking up the paths not in the cache
+// with the GraphQL history API.
+func (b *Backend) setModTimes(ctx context.Context, commitSHA string, infos []*omnistorage.BasicObjectInfo) error {
+	var missing []*omnistorage.BasicObjectInfo
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		if t, ok := b.modTimes.get(commitSHA, info.ObjectPath); ok {
+			info.ObjectModTime = t
+		} else {
+			missing = append(missing, info)
+		}
+	}
+
+	for start := 0; start < len(missing); start += modTimeBatchSize {
+		batch := missing[start:min(start+modTimeBatchSize, len(missing))]
+
+		var declarations, fields strings.Builder
+		variables := map[string]any{
+			"owner":      b.config.Owner,
+			"name":       b.config.Repo,
+			"expression": commitSHA,
+		}
+		for i, info := range batch {
+			fmt.Fprintf(&declarations, ", $p%d: String!", i)
+			fmt.Fprintf(&fields, "        p%d: history(first: 1, path: $p%d) { nodes { committedDate } }\n", i, i)
+			variables[fmt.Sprintf("p%d", i)] = info.ObjectPath
+		}
+
+		var result struct {
+			Repository struct {
+				Object map[string]struct {
+					Nodes []struct {
+						CommittedDate time.Time `json:"committedDate"`
+					} `json:"nodes"`
+				} `json:"object"`
+			} `json:"repository"`
+		}
+		query := fmt.Sprintf(modTimesQuery, declarations.String(), fields.String())
+		if err := b.graphQL(ctx, query, variables, &result); err != nil {
+			return err
+		}
+
+		for i, info := range batch {
+			nodes := result.Repository.Object[fmt.Sprintf("p%d", i)].Nodes
+			if len(nodes) == 0 {
+				continue
+			}
+			info.ObjectModTime = nodes[0].CommittedDate
+			b.modTimes.set(commitSHA, info.ObjectPath, info.ObjectModTime)
+		}
+	}
+	return nil
+}
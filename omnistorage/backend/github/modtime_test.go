@@ -0,0 +1,144 @@
+package github
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omni-github/omnistorage/backend/github/githubtest"
+)
+
+// countRequests returns the number of requests to the fake server whose
+// "METHOD /path" line contains substr.
+func countRequests(srv *githubtest.Server, substr string) int {
+	n := 0
+	for _, req := range srv.Requests() {
+		if strings.Contains(req, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestStatModTime(t *testing.T) {
+	for _, treeCache := range []bool{false, true} {
+		backend, srv := fakeServerBackend(t)
+		backend.config.CommitAuthor = &CommitAuthor{Name: "Test Bot", Email: "bot@example.com"}
+		backend.config.TreeCache = treeCache
+
+		jan := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		feb := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+		mustWrite(t, WithCommitOptions(context.Background(), CommitOptions{CommitDate: jan}), backend, "docs/a.md", []byte("a"))
+		mustWrite(t, WithCommitOptions(context.Background(), CommitOptions{CommitDate: feb}), backend, "docs/b.md", []byte("b"))
+		ctx := context.Background()
+
+		info, err := backend.Stat(ctx, "docs/a.md")
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if !info.ModTime().IsZero() {
+			t.Errorf("treeCache=%v: ModTime = %v without StatModTime, want zero", treeCache, info.ModTime())
+		}
+
+		backend.config.StatModTime = true
+		info, err = backend.Stat(ctx, "docs/a.md")
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if !info.ModTime().Equal(jan) {
+			t.Errorf("treeCache=%v: Stat ModTime = %v, want %v", treeCache, info.ModTime(), jan)
+		}
+
+		// Unchanged files are served from the cache
+		srv.ResetRequests()
+		if _, err := backend.Stat(ctx, "docs/a.md"); err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if n := countRequests(srv, "/commits?"); n != 0 {
+			t.Errorf("treeCache=%v: repeated Stat listed commits %d times", treeCache, n)
+		}
+
+		// ListInfo looks up the remaining files in one GraphQL request
+		srv.ResetRequests()
+		infos, err := backend.ListInfo(ctx, "")
+		if err != nil {
+			t.Fatalf("ListInfo failed: %v", err)
+		}
+		if n := countRequests(srv, "POST /api/graphql"); n != 1 {
+			t.Errorf("treeCache=%v: ListInfo made %d GraphQL requests, want 1", treeCache, n)
+		}
+		for _, info := range infos {
+			switch {
+			case info.Path() == "docs/a.md" && !info.ModTime().Equal(jan),
+				info.Path() == "docs/b.md" && !info.ModTime().Equal(feb),
+				info.ModTime().IsZero():
+				t.Errorf("treeCache=%v: ListInfo %s ModTime = %v", treeCache, info.Path(), info.ModTime())
+			}
+		}
+
+		srv.ResetRequests()
+		if _, err := backend.ListInfo(ctx, ""); err != nil {
+			t.Fatalf("ListInfo failed: %v", err)
+		}
+		if n := countRequests(srv, "POST /api/graphql"); n != 0 {
+			t.Errorf("treeCache=%v: repeated ListInfo made %d GraphQL requests", treeCache, n)
+		}
+
+		// A changed file is looked up again
+		mar := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		mustWrite(t, WithCommitOptions(ctx, CommitOptions{CommitDate: mar}), backend, "docs/a.md", []byte("a2"))
+		if info, err = backend.Stat(ctx, "docs/a.md"); err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if !info.ModTime().Equal(mar) {
+			t.Errorf("treeCache=%v: Stat ModTime after write = %v, want %v", treeCache, info.ModTime(), mar)
+		}
+		_ = backend.Close()
+	}
+}
+
+func TestStatModTimeRevert(t *testing.T) {
+	for _, treeCache := range []bool{false, true} {
+		backend, srv := fakeServerBackend(t)
+		backend.config.CommitAuthor = &CommitAuthor{Name: "Test Bot", Email: "bot@example.com"}
+		backend.config.TreeCache = treeCache
+		backend.config.StatModTime = true
+		ctx := context.Background()
+
+		stat := func(want time.Time) {
+			t.Helper()
+			info, err := backend.Stat(ctx, "docs/a.md")
+			if err != nil {
+				t.Fatalf("Stat failed: %v", err)
+			}
+			if !info.ModTime().Equal(want) {
+				t.Errorf("treeCache=%v: ModTime = %v, want %v", treeCache, info.ModTime(), want)
+			}
+		}
+
+		jan := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		feb := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+		mar := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		mustWrite(t, WithCommitOptions(ctx, CommitOptions{CommitDate: jan}), backend, "docs/a.md", []byte("a"))
+		stat(jan)
+
+		// Restoring earlier content is a change
+		mustWrite(t, WithCommitOptions(ctx, CommitOptions{CommitDate: feb}), backend, "docs/a.md", []byte("b"))
+		mustWrite(t, WithCommitOptions(ctx, CommitOptions{CommitDate: mar}), backend, "docs/a.md", []byte("a"))
+		stat(mar)
+
+		// So is a revert pushed by someone else
+		srv.CommitFiles(githubtest.DefaultBranch, "Change a", map[string][]byte{"docs/a.md": []byte("b")})
+		srv.CommitFiles(githubtest.DefaultBranch, "Revert a", map[string][]byte{"docs/a.md": []byte("a")})
+		backend.config.TreeCacheInterval = time.Nanosecond
+		info, err := backend.Stat(ctx, "docs/a.md")
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if info.ModTime().Equal(mar) || info.ModTime().IsZero() {
+			t.Errorf("treeCache=%v: ModTime after an external revert = %v", treeCache, info.ModTime())
+		}
+		_ = backend.Close()
+	}
+}
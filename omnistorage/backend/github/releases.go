@@ -0,0 +1,568 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/grokify/gogithub/pathutil"
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+const releasesBackendName = "github-releases"
+
+// releaseUploadSuffix is appended to the name of an asset while its
+// replacement is uploaded.
+const releaseUploadSuffix = ".upload"
+
+func init() {
+	omnistorage.Register(releasesBackendName, func(config map[string]string) (omnistorage.Backend, error) {
+		cfg := ConfigFromMap(config)
+		return NewReleases(cfg)
+	})
+}
+
+// ReleasesBackend implements omnistorage.ExtendedBackend on top of GitHub
+// release assets.
+//
+// Paths have the form "<tag>/<asset-name>". Each tag is a release and each
+// asset is an object. Assets may be up to 2 GB and are streamed through the
+// upload and download endpoints rather than the Contents API.
+// Writing to a tag that has no release creates the release, targeting
+// Config.Branch. Overwriting an asset uploads the new content as
+// "<asset-name>.upload" and then replaces the old asset with it, so a
+// failed upload keeps the old asset. The replacement itself is not atomic:
+// a failure after the upload leaves the new content under the temporary
+// name.
+type ReleasesBackend struct {
+	client *github.Client
+	config Config
+	closed bool
+	mu     sync.RWMutex
+}
+
+// releaseWriter spools content to a temporary file and uploads it as a
+// release asset on Close.
+type releaseWriter struct {
+	backend     *ReleasesBackend
+	ctx         context.Context
+	tag         string
+	name        string
+	contentType string
+	file        *os.File
+	closed      bool
+	mu          sync.Mutex
+}
+
+// NewReleases creates a new GitHub release assets backend.
+func NewReleases(cfg Config) (*ReleasesBackend, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	client, err := newClient(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReleasesBackend{
+		client: client,
+		config: cfg,
+	}, nil
+}
+
+// NewWriter creates a writer for the asset at "<tag>/<asset-name>".
+// Content is spooled to a temporary file and uploaded on Close, replacing
+// any existing asset with the same name.
+func (b *ReleasesBackend) NewWriter(ctx context.Context, filePath string, opts ...omnistorage.WriterOption) (io.WriteCloser, error) {
+	if err := b.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tag, name, err := splitAssetPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp("", "omnistorage-github-asset-*")
+	if err != nil {
+		return nil, fmt.Errorf("github: creating temp file: %w", err)
+	}
+
+	cfg := omnistorage.ApplyWriterOptions(opts...)
+
+	return &releaseWriter{
+		backend:     b,
+		ctx:         ctx,
+		tag:         tag,
+		name:        name,
+		contentType: cfg.ContentType,
+		file:        file,
+	}, nil
+}
+
+// Write writes data to the spool file.
+func (w *releaseWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, omnistorage.ErrWriterClosed
+	}
+
+	return w.file.Write(p)
+}
+
+// Close uploads the spooled content as a release asset.
+func (w *releaseWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	defer func() {
+		_ = w.file.Close()
+		_ = os.Remove(w.file.Name())
+	}()
+
+	if err := w.backend.checkClosed(); err != nil {
+		return err
+	}
+
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("github: rewinding temp file: %w", err)
+	}
+
+	b := w.backend
+
+	release, err := b.getOrCreateRelease(w.ctx, w.tag)
+	if err != nil {
+		return err
+	}
+
+	// Asset names are unique per release. Upload a replacement under a
+	// temporary name, so that the existing asset survives a failed upload,
+	// then swap it in.
+	existing, err := b.findAsset(w.ctx, release, w.name)
+	if err != nil && err != omnistorage.ErrNotFound {
+		return err
+	}
+	name := w.name
+	if existing != nil {
+		name = w.name + releaseUploadSuffix
+		if err := b.deleteAsset(w.ctx, release, name); err != nil {
+			return err
+		}
+	}
+
+	mediaType := w.contentType
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+
+	asset, resp, err := b.client.Repositories.UploadReleaseAsset(
+		w.ctx,
+		b.config.Owner,
+		b.config.Repo,
+		release.GetID(),
+		&github.UploadOptions{
+			Name:      name,
+			MediaType: mediaType,
+		},
+		w.file,
+	)
+	if err != nil {
+		return translateAPIError(err, resp)
+	}
+	if existing == nil {
+		return nil
+	}
+
+	// Swap in the upload. If the old asset cannot be deleted the upload is
+	// discarded, and if the rename fails it stays under the temporary name.
+	resp, err = b.client.Repositories.DeleteReleaseAsset(w.ctx, b.config.Owner, b.config.Repo, existing.GetID())
+	if err != nil {
+		_, _ = b.client.Repositories.DeleteReleaseAsset(context.WithoutCancel(w.ctx), b.config.Owner, b.config.Repo, asset.GetID())
+		return translateAPIError(err, resp)
+	}
+	_, resp, err = b.client.Repositories.EditReleaseAsset(w.ctx, b.config.Owner, b.config.Repo, asset.GetID(), &github.ReleaseAsset{
+		Name: github.Ptr(w.name),
+	})
+	if err != nil {
+		return fmt.Errorf("github: renaming uploaded asset %s: %w", name, translateAPIError(err, resp))
+	}
+
+	return nil
+}
+
+// deleteAsset deletes the asset with the given name if it exists.
+func (b *ReleasesBackend) deleteAsset(ctx context.Context, release *github.RepositoryRelease, name string) error {
+	asset, err := b.findAsset(ctx, release, name)
+	if err == omnistorage.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp, err := b.client.Repositories.DeleteReleaseAsset(ctx, b.config.Owner, b.config.Repo, asset.GetID())
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return translateAPIError(err, resp)
+	}
+	return nil
+}
+
+// NewReader streams the asset at "<tag>/<asset-name>".
+func (b *ReleasesBackend) NewReader(ctx context.Context, filePath string, opts ...omnistorage.ReaderOption) (io.ReadCloser, error) {
+	if err := b.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tag, name, err := splitAssetPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := b.getRelease(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	asset, err := b.findAsset(ctx, release, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Assets are served from a pre-signed storage URL, which must not
+	// receive our API token, so the redirect is followed unauthenticated.
+	rc, _, err := b.client.Repositories.DownloadReleaseAsset(
+		ctx,
+		b.config.Owner,
+		b.config.Repo,
+		asset.GetID(),
+		http.DefaultClient,
+	)
+	if err != nil {
+		return nil, translateAPIError(err, nil)
+	}
+
+	// Apply reader options
+	cfg := omnistorage.ApplyReaderOptions(opts...)
+
+	// Handle offset
+	if cfg.Offset > 0 {
+		if _, err := io.CopyN(io.Discard, rc, cfg.Offset); err != nil && err != io.EOF {
+			_ = rc.Close()
+			return nil, fmt.Errorf("github: skipping to offset: %w", err)
+		}
+	}
+
+	// Handle limit
+	if cfg.Limit > 0 {
+		return &limitedReadCloser{Reader: io.LimitReader(rc, cfg.Limit), Closer: rc}, nil
+	}
+
+	return rc, nil
+}
+
+// limitedReadCloser pairs a limited reader with the underlying closer.
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Exists checks if a release ("<tag>") or asset ("<tag>/<asset-name>") exists.
+func (b *ReleasesBackend) Exists(ctx context.Context, filePath string) (bool, error) {
+	_, err := b.Stat(ctx, filePath)
+	if err == omnistorage.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete removes the asset at "<tag>/<asset-name>".
+// Returns nil if the asset does not exist (idempotent).
+// The release itself is kept.
+func (b *ReleasesBackend) Delete(ctx context.Context, filePath string) error {
+	if err := b.checkClosed(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tag, name, err := splitAssetPath(filePath)
+	if err != nil {
+		return err
+	}
+
+	release, err := b.getRelease(ctx, tag)
+	if err == omnistorage.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return b.deleteAsset(ctx, release, name)
+}
+
+// List lists asset paths ("<tag>/<asset-name>") with the given prefix.
+func (b *ReleasesBackend) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := b.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	normalPrefix := pathutil.Normalize(prefix)
+
+	var paths []string
+	for release, err := range b.client.Repositories.ListReleasesIter(ctx, b.config.Owner, b.config.Repo, nil) {
+		if err != nil {
+			return nil, translateAPIError(err, nil)
+		}
+
+		tag := release.GetTagName()
+
+		// Skip releases that cannot contain matching assets
+		if normalPrefix != "" && !strings.HasPrefix(tag+"/", normalPrefix) &&
+			!strings.HasPrefix(normalPrefix, tag+"/") {
+			continue
+		}
+
+		for asset, err := range b.client.Repositories.ListReleaseAssetsIter(ctx, b.config.Owner, b.config.Repo, release.GetID(), nil) {
+			if err != nil {
+				return nil, translateAPIError(err, nil)
+			}
+
+			assetPath := tag + "/" + asset.GetName()
+			if normalPrefix != "" && !strings.HasPrefix(assetPath, normalPrefix) {
+				continue
+			}
+			paths = append(paths, assetPath)
+		}
+	}
+
+	return paths, nil
+}
+
+// Close releases any resources held by the backend.
+func (b *ReleasesBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+// Stat returns metadata about a release ("<tag>", reported as a directory)
+// or an asset ("<tag>/<asset-name>").
+func (b *ReleasesBackend) Stat(ctx context.Context, filePath string) (omnistorage.ObjectInfo, error) {
+	if err := b.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := pathutil.Validate(filePath); err != nil {
+		return nil, translatePathError(err)
+	}
+
+	normalPath := pathutil.Normalize(filePath)
+	if normalPath == "" {
+		return nil, omnistorage.ErrInvalidPath
+	}
+
+	// A bare tag refers to the release itself
+	if !strings.Contains(normalPath, "/") {
+		release, err := b.getRelease(ctx, normalPath)
+		if err != nil {
+			return nil, err
+		}
+		return &omnistorage.BasicObjectInfo{
+			ObjectPath:    normalPath,
+			ObjectModTime: release.GetCreatedAt().Time,
+			ObjectIsDir:   true,
+		}, nil
+	}
+
+	tag, name, err := splitAssetPath(normalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := b.getRelease(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	asset, err := b.findAsset(ctx, release, name)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &omnistorage.BasicObjectInfo{
+		ObjectPath:        normalPath,
+		ObjectSize:        int64(asset.GetSize()),
+		ObjectModTime:     asset.GetUpdatedAt().Time,
+		ObjectContentType: asset.GetContentType(),
+	}
+
+	// GitHub reports asset digests as "sha256:<hex>"
+	if digest, ok := strings.CutPrefix(asset.GetDigest(), "sha256:"); ok {
+		info.ObjectHashes = map[omnistorage.HashType]string{
+			omnistorage.HashSHA256: digest,
+		}
+	}
+
+	return info, nil
+}
+
+// Mkdir returns ErrNotSupported (releases are created on first write).
+func (b *ReleasesBackend) Mkdir(ctx context.Context, filePath string) error {
+	if err := b.checkClosed(); err != nil {
+		return err
+	}
+	return omnistorage.ErrNotSupported
+}
+
+// Rmdir returns ErrNotSupported.
+func (b *ReleasesBackend) Rmdir(ctx context.Context, filePath string) error {
+	if err := b.checkClosed(); err != nil {
+		return err
+	}
+	return omnistorage.ErrNotSupported
+}
+
+// Copy returns ErrNotSupported.
+func (b *ReleasesBackend) Copy(ctx context.Context, src, dst string) error {
+	if err := b.checkClosed(); err != nil {
+		return err
+	}
+	return omnistorage.ErrNotSupported
+}
+
+// Move returns ErrNotSupported.
+func (b *ReleasesBackend) Move(ctx context.Context, src, dst string) error {
+	if err := b.checkClosed(); err != nil {
+		return err
+	}
+	return omnistorage.ErrNotSupported
+}
+
+// Features returns the capabilities of the release assets backend.
+func (b *ReleasesBackend) Features() omnistorage.Features {
+	return omnistorage.Features{
+		Copy:                 false,
+		Move:                 false,
+		Mkdir:                false,
+		Rmdir:                false,
+		Stat:                 true,
+		Hashes:               []omnistorage.HashType{omnistorage.HashSHA256},
+		CanStream:            true,
+		ServerSideEncryption: false,
+		Versioning:           false,
+		RangeRead:            true, // Implemented client-side
+		ListPrefix:           true,
+	}
+}
+
+// checkClosed returns an error if the backend is closed.
+func (b *ReleasesBackend) checkClosed() error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return omnistorage.ErrBackendClosed
+	}
+	return nil
+}
+
+// getRelease returns the published release for tag.
+func (b *ReleasesBackend) getRelease(ctx context.Context, tag string) (*github.RepositoryRelease, error) {
+	release, resp, err := b.client.Repositories.GetReleaseByTag(ctx, b.config.Owner, b.config.Repo, tag)
+	if err != nil {
+		return nil, translateAPIError(err, resp)
+	}
+	return release, nil
+}
+
+// getOrCreateRelease returns the release for tag, creating it (and the
+// tag, at Config.Branch) if it does not exist.
+func (b *ReleasesBackend) getOrCreateRelease(ctx context.Context, tag string) (*github.RepositoryRelease, error) {
+	release, err := b.getRelease(ctx, tag)
+	if err != omnistorage.ErrNotFound {
+		return release, err
+	}
+
+	release, resp, err := b.client.Repositories.CreateRelease(ctx, b.config.Owner, b.config.Repo, &github.RepositoryRelease{
+		TagName:         github.Ptr(tag),
+		TargetCommitish: github.Ptr(b.config.Branch),
+		Name:            github.Ptr(tag),
+	})
+	if err != nil {
+		return nil, translateAPIError(err, resp)
+	}
+	return release, nil
+}
+
+// findAsset returns the asset called name in release.
+func (b *ReleasesBackend) findAsset(ctx context.Context, release *github.RepositoryRelease, name string) (*github.ReleaseAsset, error) {
+	for asset, err := range b.client.Repositories.ListReleaseAssetsIter(ctx, b.config.Owner, b.config.Repo, release.GetID(), nil) {
+		if err != nil {
+			return nil, translateAPIError(err, nil)
+		}
+		if asset.GetName() == name {
+			return asset, nil
+		}
+	}
+	return nil, omnistorage.ErrNotFound
+}
+
+// splitAssetPath splits "<tag>/<asset-name>" into its parts.
+func splitAssetPath(filePath string) (tag, name string, err error) {
+	if err := pathutil.Validate(filePath); err != nil {
+		return "", "", translatePathError(err)
+	}
+
+	tag, name, ok := strings.Cut(pathutil.Normalize(filePath), "/")
+	if !ok || tag == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", omnistorage.ErrInvalidPath
+	}
+
+	return tag, name, nil
+}
+
+// Ensure ReleasesBackend implements interfaces.
+var (
+	_ omnistorage.Backend         = (*ReleasesBackend)(nil)
+	_ omnistorage.ExtendedBackend = (*ReleasesBackend)(nil)
+)
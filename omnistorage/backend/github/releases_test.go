@@ -0,0 +1,328 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+// fakeReleases is an in-memory implementation of the release endpoints.
+type fakeReleases struct {
+	mu       sync.Mutex
+	nextID   int64
+	releases map[string]int64           // tag -> release ID
+	assets   map[int64]map[string]int64 // release ID -> name -> asset ID
+	data     map[int64][]byte           // asset ID -> content
+
+	failUploads bool
+}
+
+func newFakeReleases() *fakeReleases {
+	return &fakeReleases{
+		releases: make(map[string]int64),
+		assets:   make(map[int64]map[string]int64),
+		data:     make(map[int64][]byte),
+	}
+}
+
+func (f *fakeReleases) id() int64 {
+	f.nextID++
+	return f.nextID
+}
+
+func (f *fakeReleases) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	const api = "/api/v3/repos/owner/repo/releases"
+	const uploads = "/api/uploads/repos/owner/repo/releases/"
+
+	w.Header().Set("Content-Type", "application/json")
+
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(path, api+"/tags/"):
+		tag := strings.TrimPrefix(path, api+"/tags/")
+		id, ok := f.releases[tag]
+		if !ok {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": id, "tag_name": tag})
+
+	case r.Method == http.MethodGet && path == api:
+		var out []map[string]any
+		for tag, id := range f.releases {
+			out = append(out, map[string]any{"id": id, "tag_name": tag})
+		}
+		_ = json.NewEncoder(w).Encode(out)
+
+	case r.Method == http.MethodPost && path == api:
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		tag, _ := body["tag_name"].(string)
+		id := f.id()
+		f.releases[tag] = id
+		f.assets[id] = make(map[string]int64)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": id, "tag_name": tag})
+
+	case r.Method == http.MethodGet && strings.HasPrefix(path, api+"/assets/"):
+		assetID, _ := strconv.ParseInt(strings.TrimPrefix(path, api+"/assets/"), 10, 64)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(f.data[assetID])
+
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, api+"/assets/"):
+		assetID, _ := strconv.ParseInt(strings.TrimPrefix(path, api+"/assets/"), 10, 64)
+		for _, names := range f.assets {
+			for name, id := range names {
+				if id == assetID {
+					delete(names, name)
+				}
+			}
+		}
+		delete(f.data, assetID)
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, api+"/assets/"):
+		assetID, _ := strconv.ParseInt(strings.TrimPrefix(path, api+"/assets/"), 10, 64)
+		var body struct {
+			Name string `json:"name"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		for _, names := range f.assets {
+			for name, id := range names {
+				if id == assetID {
+					delete(names, name)
+					names[body.Name] = id
+				}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": assetID, "name": body.Name})
+
+	case r.Method == http.MethodGet && strings.HasPrefix(path, api+"/") && strings.HasSuffix(path, "/assets"):
+		releaseID, _ := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(path, api+"/"), "/assets"), 10, 64)
+		out := []map[string]any{}
+		for name, id := range f.assets[releaseID] {
+			out = append(out, map[string]any{"id": id, "name": name, "size": len(f.data[id])})
+		}
+		_ = json.NewEncoder(w).Encode(out)
+
+	case r.Method == http.MethodPost && strings.HasPrefix(path, uploads):
+		releaseID, _ := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(path, uploads), "/assets"), 10, 64)
+		name := r.URL.Query().Get("name")
+		if _, exists := f.assets[releaseID][name]; exists {
+			http.Error(w, `{"message":"already_exists"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		if f.failUploads {
+			http.Error(w, `{"message":"upload failed"}`, http.StatusBadGateway)
+			return
+		}
+		content, _ := io.ReadAll(r.Body)
+		id := f.id()
+		f.assets[releaseID][name] = id
+		f.data[id] = content
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": id, "name": name, "size": len(content)})
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestReleasesBackendRoundTrip(t *testing.T) {
+	srv := httptest.NewServer(newFakeReleases())
+	defer srv.Close()
+
+	backend, err := NewReleases(Config{
+		Owner:     "owner",
+		Repo:      "repo",
+		Token:     "token",
+		BaseURL:   srv.URL + "/",
+		UploadURL: srv.URL + "/",
+	})
+	if err != nil {
+		t.Fatalf("NewReleases failed: %v", err)
+	}
+	defer func() { _ = backend.Close() }()
+
+	ctx := context.Background()
+
+	// Write twice: the release is created once and the asset is replaced
+	for _, content := range []string{"first", "bundle contents"} {
+		w, err := backend.NewWriter(ctx, "v1.0.0/bundle.tar.gz")
+		if err != nil {
+			t.Fatalf("NewWriter failed: %v", err)
+		}
+		if _, err := io.WriteString(w, content); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+
+	r, err := backend.NewReader(ctx, "v1.0.0/bundle.tar.gz", omnistorage.WithOffset(7), omnistorage.WithLimit(4))
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	_ = r.Close()
+	if string(data) != "cont" {
+		t.Errorf("NewReader content = %q, want %q", data, "cont")
+	}
+
+	info, err := backend.Stat(ctx, "v1.0.0/bundle.tar.gz")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Size() != int64(len("bundle contents")) {
+		t.Errorf("Size = %d, want %d", info.Size(), len("bundle contents"))
+	}
+
+	info, err = backend.Stat(ctx, "v1.0.0")
+	if err != nil {
+		t.Fatalf("Stat(release) failed: %v", err)
+	}
+	if !info.IsDir() {
+		t.Error("Expected release to be reported as a directory")
+	}
+
+	paths, err := backend.List(ctx, "v1")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if fmt.Sprint(paths) != "[v1.0.0/bundle.tar.gz]" {
+		t.Errorf("List = %v, want [v1.0.0/bundle.tar.gz]", paths)
+	}
+
+	if err := backend.Delete(ctx, "v1.0.0/bundle.tar.gz"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := backend.Delete(ctx, "v1.0.0/bundle.tar.gz"); err != nil {
+		t.Errorf("Delete should be idempotent, got: %v", err)
+	}
+
+	exists, err := backend.Exists(ctx, "v1.0.0/bundle.tar.gz")
+	if err != nil {
+		t.Fatalf("Exists failed: %v", err)
+	}
+	if exists {
+		t.Error("Expected asset to be deleted")
+	}
+}
+
+func TestReleasesBackendFailedOverwrite(t *testing.T) {
+	fake := newFakeReleases()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	backend, err := NewReleases(Config{
+		Owner:     "owner",
+		Repo:      "repo",
+		Token:     "token",
+		BaseURL:   srv.URL + "/",
+		UploadURL: srv.URL + "/",
+	})
+	if err != nil {
+		t.Fatalf("NewReleases failed: %v", err)
+	}
+	defer func() { _ = backend.Close() }()
+
+	ctx := context.Background()
+	write := func(content string) error {
+		w, err := backend.NewWriter(ctx, "v1.0.0/bundle.tar.gz")
+		if err != nil {
+			t.Fatalf("NewWriter failed: %v", err)
+		}
+		_, _ = io.WriteString(w, content)
+		return w.Close()
+	}
+
+	if err := write("first"); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// A failed upload keeps the existing asset
+	fake.mu.Lock()
+	fake.failUploads = true
+	fake.mu.Unlock()
+	if err := write("second"); err == nil {
+		t.Fatal("Expected the failed upload to be reported")
+	}
+	r, err := backend.NewReader(ctx, "v1.0.0/bundle.tar.gz")
+	if err != nil {
+		t.Fatalf("NewReader after a failed upload: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	_ = r.Close()
+	if string(data) != "first" {
+		t.Errorf("content after a failed upload = %q, want %q", data, "first")
+	}
+
+	// A successful overwrite leaves only the new asset
+	fake.mu.Lock()
+	fake.failUploads = false
+	fake.mu.Unlock()
+	if err := write("third"); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	paths, err := backend.List(ctx, "")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if fmt.Sprint(paths) != "[v1.0.0/bundle.tar.gz]" {
+		t.Errorf("List = %v, want [v1.0.0/bundle.tar.gz]", paths)
+	}
+}
+
+func TestSplitAssetPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		tag     string
+		name    string
+		wantErr bool
+	}{
+		{"v1.0.0/app.zip", "v1.0.0", "app.zip", false},
+		{"/v1.0.0/app.zip", "v1.0.0", "app.zip", false},
+		{"v1.0.0", "", "", true},
+		{"v1.0.0/dir/app.zip", "", "", true},
+		{"../app.zip", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			tag, name, err := splitAssetPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitAssetPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if tag != tt.tag || name != tt.name {
+				t.Errorf("splitAssetPath(%q) = %q, %q, want %q, %q", tt.path, tag, name, tt.tag, tt.name)
+			}
+		})
+	}
+}
+
+func TestReleasesBackendRegistered(t *testing.T) {
+	backend, err := omnistorage.Open(releasesBackendName, map[string]string{
+		"owner": "owner",
+		"repo":  "repo",
+		"token": "token",
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, ok := backend.(*ReleasesBackend); !ok {
+		t.Errorf("Expected *ReleasesBackend, got %T", backend)
+	}
+}
@@ -0,0 +1,157 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// ErrSigningAuthorRequired is returned by Config.Validate when a commit
+// signer is set without a commit author. The signature covers the author
+// and committer, so they cannot be left for GitHub to fill in.
+var ErrSigningAuthorRequired = errors.New("github: commit author is required to sign commits")
+
+// CommitSigner signs commits created by the backend.
+type CommitSigner interface {
+	// SignCommit returns an ASCII-armored detached signature of payload,
+	// the canonical Git commit object without its signature header.
+	SignCommit(ctx context.Context, payload []byte) ([]byte, error)
+}
+
+// CommitSignerFunc is a function implementation of CommitSigner, for
+// example to sign in process with an OpenPGP library:
+//
+//	signer := github.CommitSignerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+//	    var sig bytes.Buffer
+//	    err := openpgp.ArmoredDetachSign(&sig, entity, bytes.NewReader(payload), nil)
+//	    return sig.Bytes(), err
+//	})
+type CommitSignerFunc func(ctx context.Context, payload []byte) ([]byte, error)
+
+// SignCommit implements CommitSigner.
+func (f CommitSignerFunc) SignCommit(ctx context.Context, payload []byte) ([]byte, error) {
+	return f(ctx, payload)
+}
+
+// GPGSigner signs commits with an OpenPGP key through the gpg program,
+// like git with gpg.format=openpgp.
+type GPGSigner struct {
+	// KeyID selects the signing key. Default: gpg's default key.
+	KeyID string
+
+	// Program is the gpg executable. Default: "gpg".
+	Program string
+}
+
+// SignCommit implements CommitSigner.
+func (s *GPGSigner) SignCommit(ctx context.Context, payload []byte) ([]byte, error) {
+	program := s.Program
+	if program == "" {
+		program = "gpg"
+	}
+
+	args := []string{"--status-fd=2", "-bsa"}
+	if s.KeyID != "" {
+		args = append(args, "-u", s.KeyID)
+	}
+
+	sig, status, err := runSigner(ctx, program, args, payload)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(status, "[GNUPG:] SIG_CREATED ") {
+		return nil, fmt.Errorf("github: %s did not create a signature: %s", program, strings.TrimSpace(status))
+	}
+	return sig, nil
+}
+
+// SSHSigner signs commits with an SSH key through ssh-keygen, like git
+// with gpg.format=ssh.
+type SSHSigner struct {
+	// KeyFile is the private key, or the public key of a key held by
+	// ssh-agent. Required.
+	KeyFile string
+
+	// Program is the ssh-keygen executable. Default: "ssh-keygen".
+	Program string
+}
+
+// SignCommit implements CommitSigner.
+func (s *SSHSigner) SignCommit(ctx context.Context, payload []byte) ([]byte, error) {
+	if s.KeyFile == "" {
+		return nil, errors.New("github: ssh signing key file is required")
+	}
+
+	program := s.Program
+	if program == "" {
+		program = "ssh-keygen"
+	}
+
+	sig, _, err := runSigner(ctx, program, []string{"-Y", "sign", "-n", "git", "-f", s.KeyFile}, payload)
+	return sig, err
+}
+
+// runSigner runs a signing program with payload on stdin and returns its
+// stdout and stderr.
+func runSigner(ctx context.Context, program string, args []string, payload []byte) ([]byte, string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, program, args...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, "", fmt.Errorf("github: signing with %s: %w: %s", program, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), stderr.String(), nil
+}
+
+// signCommit fills in missing author and committer dates of commit and
+// sets its signature. Git signs the commit object as stored, so the dates must
+// be fixed before signing rather than left for GitHub to fill in.
+func (b *Backend) signCommit(ctx context.Context, commit *github.Commit) error {
+	now := github.Timestamp{Time: time.Now().UTC().Truncate(time.Second)}
+	if commit.Author.Date == nil {
+		commit.Author.Date = &now
+	}
+	if commit.Committer == nil {
+		committer := *commit.Author
+		committer.Date = nil
+		commit.Committer = &committer
+	}
+	if commit.Committer.Date == nil {
+		commit.Committer.Date = &now
+	}
+
+	sig, err := b.config.CommitSigner.SignCommit(ctx, commitPayload(commit))
+	if err != nil {
+		return err
+	}
+	commit.Verification = &github.SignatureVerification{Signature: github.Ptr(string(sig))}
+	return nil
+}
+
+// commitPayload returns the canonical Git commit object of commit without
+// a signature header, which is what commit signatures cover.
+func commitPayload(commit *github.Commit) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "tree %s\n", commit.GetTree().GetSHA())
+	for _, parent := range commit.Parents {
+		fmt.Fprintf(&buf, "parent %s\n", parent.GetSHA())
+	}
+	fmt.Fprintf(&buf, "author %s\n", signatureLine(commit.Author))
+	fmt.Fprintf(&buf, "committer %s\n", signatureLine(commit.Committer))
+	fmt.Fprintf(&buf, "\n%s", commit.GetMessage())
+	return buf.Bytes()
+}
+
+// signatureLine formats an author or committer as in a Git object.
+func signatureLine(a *github.CommitAuthor) string {
+	date := a.GetDate().Time
+	return fmt.Sprintf("%s <%s> %d %s", a.GetName(), a.GetEmail(), date.Unix(), date.Format("-0700"))
+}
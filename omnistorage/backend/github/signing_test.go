@@ -0,0 +1,203 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1" //nolint:gosec // Git object IDs are SHA-1
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// signedBackend returns a fake server backend that signs with signer.
+func signedBackend(t *testing.T, signer CommitSigner) *Backend {
+	t.Helper()
+	backend, _ := fakeServerBackend(t)
+	t.Cleanup(func() { _ = backend.Close() })
+	backend.config.CommitAuthor = &CommitAuthor{Name: "Test Bot", Email: "bot@example.com"}
+	backend.config.CommitSigner = signer
+	return backend
+}
+
+// storedCommit returns the payload and signature of the branch head,
+// rebuilt from the commit GitHub returns. It fails the test unless the
+// payload with the signature header hashes to the commit SHA, which proves
+// the signature covers the commit object exactly as stored.
+func storedCommit(t *testing.T, backend *Backend) (payload, signature string) {
+	t.Helper()
+	ctx := context.Background()
+	ref, _, err := backend.client.Git.GetRef(ctx, "grokify", "omnistorage", "heads/"+backend.config.Branch)
+	if err != nil {
+		t.Fatalf("GetRef failed: %v", err)
+	}
+	commit, _, err := backend.client.Git.GetCommit(ctx, "grokify", "omnistorage", ref.GetObject().GetSHA())
+	if err != nil {
+		t.Fatalf("GetCommit failed: %v", err)
+	}
+
+	ident := func(a *github.CommitAuthor) string {
+		date := a.GetDate().Time
+		return fmt.Sprintf("%s <%s> %d %s", a.GetName(), a.GetEmail(), date.Unix(), date.Format("-0700"))
+	}
+	var header strings.Builder
+	fmt.Fprintf(&header, "tree %s\n", commit.GetTree().GetSHA())
+	for _, parent := range commit.Parents {
+		fmt.Fprintf(&header, "parent %s\n", parent.GetSHA())
+	}
+	fmt.Fprintf(&header, "author %s\n", ident(commit.GetAuthor()))
+	fmt.Fprintf(&header, "committer %s\n", ident(commit.GetCommitter()))
+
+	signature = commit.GetVerification().GetSignature()
+	payload = header.String() + "\n" + commit.GetMessage()
+	object := header.String() +
+		"gpgsig " + strings.ReplaceAll(strings.TrimSuffix(signature, "\n"), "\n", "\n ") + "\n" +
+		"\n" + commit.GetMessage()
+
+	h := sha1.New() //nolint:gosec // Git object IDs are SHA-1
+	fmt.Fprintf(h, "commit %d\x00%s", len(object), object)
+	if got := hex.EncodeToString(h.Sum(nil)); got != commit.GetSHA() {
+		t.Fatalf("stored commit object hashes to %s, want %s", got, commit.GetSHA())
+	}
+	return payload, signature
+}
+
+func TestCommitSigner(t *testing.T) {
+	var payloads []string
+	backend := signedBackend(t, CommitSignerFunc(func(_ context.Context, payload []byte) ([]byte, error) {
+		payloads = append(payloads, string(payload))
+		return []byte(fmt.Sprintf("-----BEGIN TEST SIGNATURE-----\n%d\n-----END TEST SIGNATURE-----\n", len(payloads))), nil
+	}))
+	ctx := context.Background()
+
+	batch, err := backend.NewBatch(ctx, "Add docs")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	if err := batch.Write("docs/a.md", []byte("a")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, err := batch.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	payload, signature := storedCommit(t, backend)
+	if len(payloads) != 1 || payloads[0] != payload {
+		t.Fatalf("signed payloads = %q, want [%q]", payloads, payload)
+	}
+	if !strings.Contains(payload, "\nauthor Test Bot <bot@example.com> ") {
+		t.Errorf("payload has no configured author:\n%s", payload)
+	}
+	if !strings.Contains(signature, "\n1\n") {
+		t.Errorf("signature = %q", signature)
+	}
+
+	// Writes and deletes also go through the Git Data API to be signed
+	mustWrite(t, ctx, backend, "docs/b.md", []byte("b"))
+	if payload, _ = storedCommit(t, backend); len(payloads) != 2 || payloads[1] != payload {
+		t.Errorf("write: signed payloads = %q, want last %q", payloads, payload)
+	}
+	if err := backend.Delete(ctx, "docs/a.md"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if payload, _ = storedCommit(t, backend); len(payloads) != 3 || payloads[2] != payload {
+		t.Errorf("delete: signed payloads = %q, want last %q", payloads, payload)
+	}
+}
+
+func TestCommitSignerError(t *testing.T) {
+	errSign := errors.New("no key")
+	backend := signedBackend(t, CommitSignerFunc(func(context.Context, []byte) ([]byte, error) {
+		return nil, errSign
+	}))
+
+	w, err := backend.NewWriter(context.Background(), "docs/a.md")
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	_, _ = w.Write([]byte("a"))
+	if err := w.Close(); !errors.Is(err, errSign) {
+		t.Errorf("Close() = %v, want %v", err, errSign)
+	}
+}
+
+func TestGPGSigner(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+	home := t.TempDir()
+	t.Setenv("GNUPGHOME", home)
+	t.Cleanup(func() { _ = exec.Command("gpgconf", "--kill", "gpg-agent").Run() })
+	runTool(t, nil, "gpg", "--batch", "--passphrase", "", "--quick-gen-key", "Test Bot <bot@example.com>", "ed25519", "sign", "never")
+
+	backend := signedBackend(t, &GPGSigner{KeyID: "bot@example.com"})
+	mustWrite(t, context.Background(), backend, "docs/a.md", []byte("a"))
+
+	payload, signature := storedCommit(t, backend)
+	if !strings.HasPrefix(signature, "-----BEGIN PGP SIGNATURE-----") {
+		t.Fatalf("signature = %q", signature)
+	}
+	sigFile := filepath.Join(t.TempDir(), "commit.sig")
+	if err := os.WriteFile(sigFile, []byte(signature), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	runTool(t, []byte(payload), "gpg", "--verify", sigFile, "-")
+}
+
+func TestSSHSigner(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not installed")
+	}
+	key := filepath.Join(t.TempDir(), "id_ed25519")
+	runTool(t, nil, "ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", "bot@example.com", "-f", key)
+
+	backend := signedBackend(t, &SSHSigner{KeyFile: key})
+	mustWrite(t, context.Background(), backend, "docs/a.md", []byte("a"))
+
+	payload, signature := storedCommit(t, backend)
+	if !strings.HasPrefix(signature, "-----BEGIN SSH SIGNATURE-----") {
+		t.Fatalf("signature = %q", signature)
+	}
+	sigFile := filepath.Join(t.TempDir(), "commit.sig")
+	if err := os.WriteFile(sigFile, []byte(signature), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	runTool(t, []byte(payload), "ssh-keygen", "-Y", "check-novalidate", "-n", "git", "-s", sigFile)
+}
+
+func TestConfigCommitSigner(t *testing.T) {
+	cfg := Config{Owner: "o", Repo: "r", Token: "t", CommitSigner: &GPGSigner{}}
+	if err := cfg.Validate(); !errors.Is(err, ErrSigningAuthorRequired) {
+		t.Errorf("Validate() = %v, want ErrSigningAuthorRequired", err)
+	}
+
+	cfg.CommitAuthor = &CommitAuthor{Name: "n", Email: "e"}
+	cfg.CommitEngine = CommitEngineGraphQL
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidCommitEngine) {
+		t.Errorf("Validate() = %v, want ErrInvalidCommitEngine", err)
+	}
+
+	cfg = ConfigFromMap(map[string]string{"signing_key": "~/.ssh/id_ed25519", "signing_format": "ssh"})
+	if signer, ok := cfg.CommitSigner.(*SSHSigner); !ok || signer.KeyFile != "~/.ssh/id_ed25519" {
+		t.Errorf("CommitSigner = %#v, want SSHSigner", cfg.CommitSigner)
+	}
+	cfg = ConfigFromMap(map[string]string{"signing_key": "ABCD1234"})
+	if signer, ok := cfg.CommitSigner.(*GPGSigner); !ok || signer.KeyID != "ABCD1234" {
+		t.Errorf("CommitSigner = %#v, want GPGSigner", cfg.CommitSigner)
+	}
+}
+
+// runTool runs an external program and fails the test if it fails.
+func runTool(t *testing.T, stdin []byte, name string, args ...string) {
+	t.Helper()
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s %s failed: %v\n%s", name, strings.Join(args, " "), err, out)
+	}
+}
@@ -0,0 +1,127 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+)
+
+// ErrTagNameRequired is returned when a tag is requested without a name.
+var ErrTagNameRequired = errors.New("github: tag name is required")
+
+// TagOptions describes a tag to create on a batch commit.
+type TagOptions struct {
+	// Name is the tag name (e.g. "v1.2.0"). Required.
+	Name string
+
+	// Message creates an annotated tag with this message.
+	// If empty, a lightweight tag is created.
+	Message string
+
+	// Release, if set, also publishes a GitHub Release for the tag.
+	Release *ReleaseOptions
+}
+
+// ReleaseOptions describes a GitHub Release to create for a tag.
+type ReleaseOptions struct {
+	// Name is the release title. Default: the tag name.
+	Name string
+
+	// Body is the release description.
+	Body string
+
+	// GenerateNotes asks GitHub to generate release notes from the
+	// commits since the previous release. They are appended to Body.
+	GenerateNotes bool
+
+	// Draft creates an unpublished release.
+	Draft bool
+
+	// Prerelease marks the release as a pre-release.
+	Prerelease bool
+}
+
+// Tag requests that the commit created by Commit be tagged.
+// The tag (and optional release) is created after the branch has been
+// updated. If the batch is empty or changes nothing, the current branch
+// head is tagged instead. Calling Tag again replaces the previous request.
+func (batch *Batch) Tag(opts TagOptions) error {
+	batch.mu.Lock()
+	defer batch.mu.Unlock()
+
+	if batch.committed {
+		return fmt.Errorf("github: batch already committed")
+	}
+
+	if opts.Name == "" {
+		return ErrTagNameRequired
+	}
+
+	batch.tag = &opts
+	return nil
+}
+
+// createTag creates the tag ref, an annotated tag object if a message is
+// set, and the release if requested, all pointing at commitSHA.
+func (b *Backend) createTag(ctx context.Context, commitSHA string, opts *TagOptions) error {
+	target := commitSHA
+
+	if opts.Message != "" {
+		tagOpts := github.CreateTag{
+			Tag:     opts.Name,
+			Message: opts.Message,
+			Object:  commitSHA,
+			Type:    "commit",
+		}
+
+		// Set tagger if configured
+		if b.config.CommitAuthor != nil {
+			tagOpts.Tagger = &github.CommitAuthor{
+				Name:  github.Ptr(b.config.CommitAuthor.Name),
+				Email: github.Ptr(b.config.CommitAuthor.Email),
+				Date:  &github.Timestamp{Time: time.Now()},
+			}
+		}
+
+		tagObj, resp, err := b.client.Git.CreateTag(ctx, b.config.Owner, b.config.Repo, tagOpts)
+		if err != nil {
+			return b.translateError(err, resp)
+		}
+		target = tagObj.GetSHA()
+	}
+
+	_, resp, err := b.client.Git.CreateRef(ctx, b.config.Owner, b.config.Repo, github.CreateRef{
+		Ref: "refs/tags/" + opts.Name,
+		SHA: target,
+	})
+	if err != nil {
+		return b.translateError(err, resp)
+	}
+
+	if opts.Release == nil {
+		return nil
+	}
+
+	name := opts.Release.Name
+	if name == "" {
+		name = opts.Name
+	}
+
+	_, resp, err = b.client.Repositories.CreateRelease(ctx, b.config.Owner, b.config.Repo, &github.RepositoryRelease{
+		TagName:              github.Ptr(opts.Name),
+		TargetCommitish:      github.Ptr(commitSHA),
+		Name:                 github.Ptr(name),
+		Body:                 github.Ptr(opts.Release.Body),
+		Draft:                github.Ptr(opts.Release.Draft),
+		Prerelease:           github.Ptr(opts.Release.Prerelease),
+		GenerateReleaseNotes: github.Ptr(opts.Release.GenerateNotes),
+	})
+	if err != nil {
+		return b.translateError(err, resp)
+	}
+
+	return nil
+}
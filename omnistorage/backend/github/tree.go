@@ -0,0 +1,406 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v84/github"
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+// defaultTreeCacheInterval is how often the branch head is checked when
+// Config.TreeCacheInterval is not set.
+const defaultTreeCacheInterval = 30 * time.Second
+
+// treeCacheEntry describes a path in a cached tree.
+type treeCacheEntry struct {
+	typ  string // "blob", "tree" or "commit" (submodule)
+	mode FileMode
+	sha  string
+	size int64
+}
+
+// treeSnapshot is an immutable view of the branch's recursive tree at a
+// commit. Directories, including the root "", are entries of type "tree".
+type treeSnapshot struct {
+	commitSHA   string
+	entries     map[string]treeCacheEntry
+	lfsPatterns []lfsPattern
+
+	// truncated is set when GitHub could not return the whole tree; the
+	// backend then falls back to per-path API calls until the head changes.
+	truncated bool
+}
+
+// treeChange is a change to a path made by a commit of this backend. An
+// empty sha removes the path, and an empty mode keeps the mode of an
+// existing file.
+type treeChange struct {
+	path string
+	mode FileMode
+	sha  string
+	size int64
+}
+
+// treeCache holds the latest snapshot of the branch tree.
+type treeCache struct {
+	mu       sync.Mutex
+	snapshot *treeSnapshot
+	checked  time.Time
+}
+
+// treeSnapshot returns the cached tree of the branch, refreshing it if the
+// branch head has moved since the last check. It returns nil if tree
+// caching is disabled or the tree is too large to cache.
+func (b *Backend) treeSnapshot(ctx context.Context) (*treeSnapshot, error) {
+	if !b.config.TreeCache {
+		return nil, nil
+	}
+
+	interval := b.config.TreeCacheInterval
+	if interval == 0 {
+		interval = defaultTreeCacheInterval
+	}
+
+	b.tree.mu.Lock()
+	current, checked := b.tree.snapshot, b.tree.checked
+	b.tree.mu.Unlock()
+
+	if current == nil || time.Since(checked) >= interval {
+		// Check the branch head, which is cheap with ETags
+		ref, resp, err := b.client.Git.GetRef(ctx, b.config.Owner, b.config.Repo, "heads/"+b.config.Branch)
+		if err != nil {
+			return nil, b.translateError(err, resp)
+		}
+
+		next := current
+		if head := ref.GetObject().GetSHA(); current == nil || current.commitSHA != head {
+			if next, err = b.loadTreeSnapshot(ctx, head); err != nil {
+				return nil, err
+			}
+
+			// Stat reports the real size of LFS objects, so remember which
+			// paths may be LFS pointers
+			if attrs, ok := next.entries[".gitattributes"]; ok && !b.config.DisableLFS {
+				if next.lfsPatterns, err = b.lfsPatternsAt(ctx, attrs.sha); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// Keep a snapshot updated by a concurrent write
+		b.tree.mu.Lock()
+		if b.tree.snapshot == current {
+			b.tree.snapshot = next
+			b.tree.checked = time.Now()
+		}
+		current = b.tree.snapshot
+		b.tree.mu.Unlock()
+	}
+
+	if current.truncated {
+		return nil, nil
+	}
+	return current, nil
+}
+
+// loadTreeSnapshot fetches the recursive tree of a commit.
+func (b *Backend) loadTreeSnapshot(ctx context.Context, commitSHA string) (*treeSnapshot, error) {
+	tree, resp, err := b.client.Git.GetTree(ctx, b.config.Owner, b.config.Repo, commitSHA, true)
+	if err != nil {
+		return nil, b.translateError(err, resp)
+	}
+
+	snapshot := &treeSnapshot{commitSHA: commitSHA, truncated: tree.GetTruncated()}
+	if snapshot.truncated {
+		return snapshot, nil
+	}
+
+	snapshot.entries = newTreeEntries(tree)
+	return snapshot, nil
+}
+
+// newTreeEntries returns the entries of a recursive tree, with the root
+// directory "".
+func newTreeEntries(tree *github.Tree) map[string]treeCacheEntry {
+	entries := make(map[string]treeCacheEntry, len(tree.Entries)+1)
+	entries[""] = treeCacheEntry{typ: "tree"}
+	for _, entry := range tree.Entries {
+		entries[entry.GetPath()] = newTreeCacheEntry(entry)
+	}
+	return entries
+}
+
+func newTreeCacheEntry(entry *github.TreeEntry) treeCacheEntry {
+	return treeCacheEntry{
+		typ:  entry.GetType(),
+		mode: FileMode(entry.GetMode()),
+		sha:  entry.GetSHA(),
+		size: int64(entry.GetSize()),
+	}
+}
+
+// walkTree returns the entries of a commit's tree like newTreeEntries,
+// fetching one directory per request. It is used when the recursive tree
+// is truncated.
+func (b *Backend) walkTree(ctx context.Context, commitSHA string) (map[string]treeCacheEntry, error) {
+	entries := map[string]treeCacheEntry{"": {typ: "tree"}}
+
+	var walk func(treeSHA, dir string) error
+	walk = func(treeSHA, dir string) error {
+		tree, resp, err := b.client.Git.GetTree(ctx, b.config.Owner, b.config.Repo, treeSHA, false)
+		if err != nil {
+			return b.translateError(err, resp)
+		}
+		if tree.GetTruncated() {
+			return fmt.Errorf("github: tree of %q is too large to list", dir)
+		}
+
+		for _, entry := range tree.Entries {
+			p := path.Join(dir, entry.GetPath())
+			entries[p] = newTreeCacheEntry(entry)
+			if entry.GetType() == "tree" {
+				if err := walk(entry.GetSHA(), p); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := walk(commitSHA, ""); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// baseTree returns the part of a commit's tree needed to resolve paths:
+// the tree cache if it is at that commit, or else the recursive tree of the
+// deepest directory containing all paths. Directories above it are walked
+// one level at a time, so the request count grows with the depth of that
+// directory rather than with the number of paths.
+func (b *Backend) baseTree(ctx context.Context, commitSHA, treeSHA string, paths []string) (*treeSnapshot, error) {
+	if snapshot, err := b.treeSnapshot(ctx); err == nil && snapshot != nil && snapshot.commitSHA == commitSHA {
+		return snapshot, nil
+	}
+
+	dir := commonDir(paths)
+	if dir != "" {
+		for _, name := range strings.Split(dir, "/") {
+			tree, resp, err := b.client.Git.GetTree(ctx, b.config.Owner, b.config.Repo, treeSHA, false)
+			if err != nil {
+				return nil, b.translateError(err, resp)
+			}
+
+			treeSHA = ""
+			for _, entry := range tree.Entries {
+				if entry.GetPath() == name && entry.GetType() == "tree" {
+					treeSHA = entry.GetSHA()
+				}
+			}
+			if treeSHA == "" {
+				// The directory does not exist yet
+				return &treeSnapshot{commitSHA: commitSHA, entries: map[string]treeCacheEntry{}}, nil
+			}
+		}
+	}
+
+	snapshot, err := b.loadTreeSnapshot(ctx, treeSHA)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.commitSHA = commitSHA
+
+	// Make the paths relative to the repository root
+	if dir != "" && !snapshot.truncated {
+		entries := make(map[string]treeCacheEntry, len(snapshot.entries))
+		for p, entry := range snapshot.entries {
+			entries[path.Join(dir, p)] = entry
+		}
+		snapshot.entries = entries
+	}
+	return snapshot, nil
+}
+
+// commonDir returns the deepest directory containing all paths, or "" for
+// the repository root.
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+
+	common := strings.Split(path.Dir(paths[0]), "/")
+	for _, p := range paths[1:] {
+		parts := strings.Split(path.Dir(p), "/")
+		n := 0
+		for n < len(common) && n < len(parts) && common[n] == parts[n] {
+			n++
+		}
+		common = common[:n]
+	}
+
+	dir := strings.Join(common, "/")
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// updateTreeCache applies the changes of a commit made by this backend to
+// the cached tree and modification times. Commits to other branches are
+// ignored. If the commit is not on top of the cached head, or changes
+// .gitattributes, the cache is dropped and reloaded on next use.
+func (b *Backend) updateTreeCache(branch, parentSHA, commitSHA string, changes []treeChange) {
+	if branch != b.config.Branch {
+		return
+	}
+	b.modTimes.advance(parentSHA, commitSHA, changes)
+
+	b.tree.mu.Lock()
+	defer b.tree.mu.Unlock()
+
+	current := b.tree.snapshot
+	if current == nil || current.truncated {
+		return
+	}
+	if current.commitSHA != parentSHA {
+		b.tree.snapshot = nil
+		return
+	}
+
+	next := &treeSnapshot{
+		commitSHA:   commitSHA,
+		entries:     make(map[string]treeCacheEntry, len(current.entries)+len(changes)),
+		lfsPatterns: current.lfsPatterns,
+	}
+	for p, entry := range current.entries {
+		next.entries[p] = entry
+	}
+
+	var removed []string
+	for _, change := range changes {
+		if change.path == ".gitattributes" {
+			b.tree.snapshot = nil
+			return
+		}
+		if change.sha == "" {
+			removed = append(removed, change.path)
+			continue
+		}
+		mode := change.mode
+		if mode == "" {
+			mode = FileModeRegular
+			if existing, ok := next.entries[change.path]; ok && existing.typ == "blob" {
+				mode = existing.mode
+			}
+		}
+		next.add(change.path, treeCacheEntry{typ: "blob", mode: mode, sha: change.sha, size: change.size})
+	}
+	next.remove(removed...)
+
+	b.tree.snapshot = next
+}
+
+// expireTreeCache makes the next lookup check the branch head unless the
+// cached tree is already at head.
+func (b *Backend) expireTreeCache(head string) {
+	b.tree.mu.Lock()
+	defer b.tree.mu.Unlock()
+
+	if b.tree.snapshot != nil && b.tree.snapshot.commitSHA != head {
+		b.tree.checked = time.Time{}
+	}
+}
+
+// add sets a file entry and creates its parent directories.
+func (s *treeSnapshot) add(filePath string, entry treeCacheEntry) {
+	s.entries[filePath] = entry
+	for dir := path.Dir(filePath); dir != "."; dir = path.Dir(dir) {
+		s.entries[dir] = treeCacheEntry{typ: "tree"}
+	}
+}
+
+// remove deletes file entries and the parent directories they leave empty,
+// since Git does not store empty directories. The entries are scanned
+// once, however many files are removed.
+func (s *treeSnapshot) remove(filePaths ...string) {
+	if len(filePaths) == 0 {
+		return
+	}
+
+	// Count the remaining children of every parent directory
+	children := make(map[string]int)
+	for _, filePath := range filePaths {
+		delete(s.entries, filePath)
+		for dir := path.Dir(filePath); dir != "."; dir = path.Dir(dir) {
+			children[dir] = 0
+		}
+	}
+	for p := range s.entries {
+		if n, ok := children[path.Dir(p)]; ok {
+			children[path.Dir(p)] = n + 1
+		}
+	}
+
+	// Remove empty directories deepest first, so that removing one can
+	// empty its parent
+	dirs := make([]string, 0, len(children))
+	for dir := range children {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return strings.Count(dirs[i], "/") > strings.Count(dirs[j], "/")
+	})
+	for _, dir := range dirs {
+		if _, ok := s.entries[dir]; !ok || children[dir] > 0 {
+			continue
+		}
+		delete(s.entries, dir)
+		if parent := path.Dir(dir); parent != "." {
+			children[parent]--
+		}
+	}
+}
+
+// stat answers Stat from the snapshot. It returns false if the path must be
+// looked up through the API, which is the case for symbolic links, whose
+// target is only known from the content, and for possible LFS pointers,
+// whose real size is only known from the content.
+func (s *treeSnapshot) stat(normalPath string) (*omnistorage.BasicObjectInfo, bool, error) {
+	entry, ok := s.entries[normalPath]
+	if !ok {
+		return nil, true, omnistorage.ErrNotFound
+	}
+
+	switch {
+	case entry.typ == "tree":
+		return &omnistorage.BasicObjectInfo{
+			ObjectPath:  normalPath,
+			ObjectSize:  0,
+			ObjectIsDir: true,
+		}, true, nil
+	case entry.typ == "commit":
+		return submoduleInfo(normalPath, entry.sha), true, nil
+	case entry.typ != "blob" || entry.mode == FileModeSymlink || matchLFSPatterns(s.lfsPatterns, normalPath):
+		return nil, false, nil
+	}
+
+	return fileInfo(normalPath, entry.size, entry.sha, entry.mode), true, nil
+}
+
+// list returns the files with the given prefix, in path order.
+func (s *treeSnapshot) list(normalPrefix string) []string {
+	var paths []string
+	for p, entry := range s.entries {
+		if entry.typ == "blob" && strings.HasPrefix(p, normalPrefix) {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
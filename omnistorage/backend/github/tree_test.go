@@ -0,0 +1,152 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omni-github/omnistorage/backend/github/githubtest"
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+func TestTreeCache(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	backend.config.TreeCache = true
+	backend.config.TreeCacheInterval = time.Hour
+
+	ctx := context.Background()
+	countRequests := func(fragment string) int {
+		n := 0
+		for _, req := range srv.Requests() {
+			if strings.Contains(req, fragment) {
+				n++
+			}
+		}
+		return n
+	}
+
+	// Repeated lookups are answered from a single tree fetch
+	for i := 0; i < 3; i++ {
+		if exists, err := backend.Exists(ctx, "backend/file/file.go"); err != nil || !exists {
+			t.Fatalf("Exists = %v, %v, want true", exists, err)
+		}
+		if exists, err := backend.Exists(ctx, "missing.txt"); err != nil || exists {
+			t.Fatalf("Exists(missing) = %v, %v, want false", exists, err)
+		}
+		info, err := backend.Stat(ctx, "README.md")
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if info.Size() != int64(len("# Omnistorage\n\nA unified storage abstraction for Go.\n")) {
+			t.Errorf("Stat size = %d", info.Size())
+		}
+		if info, err := backend.Stat(ctx, "backend"); err != nil || !info.IsDir() {
+			t.Fatalf("Stat(dir) = %v, %v, want a directory", info, err)
+		}
+		if _, err := backend.Stat(ctx, "missing.txt"); !errors.Is(err, omnistorage.ErrNotFound) {
+			t.Errorf("Stat(missing) error = %v, want ErrNotFound", err)
+		}
+		paths, err := backend.List(ctx, "backend/")
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if want := []string{"backend/file/file.go", "backend/memory/mem.go"}; !reflect.DeepEqual(paths, want) {
+			t.Errorf("List = %v, want %v", paths, want)
+		}
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("Expected 2 requests (ref and tree), got %d: %v", n, srv.Requests())
+	}
+
+	// Writes and deletes through the backend update the cached tree
+	srv.ResetRequests()
+	mustWrite(t, ctx, backend, "docs/guide.md", []byte("# Guide"))
+	if err := backend.Delete(ctx, "backend/memory/mem.go"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	batch, err := backend.NewBatch(ctx, "Batch")
+	if err != nil {
+		t.Fatalf("NewBatch failed: %v", err)
+	}
+	_ = batch.Write("docs/api.md", []byte("# API"))
+	_ = batch.Delete("docs/guide.md")
+	if _, err := batch.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	paths, err := backend.List(ctx, "")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if want := []string{"README.md", "backend/file/file.go", "docs/api.md"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("List after writes = %v, want %v", paths, want)
+	}
+	if exists, _ := backend.Exists(ctx, "backend/memory"); exists {
+		t.Error("Expected emptied directory to be gone")
+	}
+	if info, err := backend.Stat(ctx, "docs/api.md"); err != nil || info.Size() != int64(len("# API")) {
+		t.Errorf("Stat after batch = %v, %v", info, err)
+	}
+	if n := countRequests("/git/trees/"); n != 0 {
+		t.Errorf("Expected no tree fetch after own writes, got %d", n)
+	}
+
+	// Commits by other clients are seen once the interval has passed
+	srv.CommitFiles(githubtest.DefaultBranch, "External", map[string][]byte{"external.txt": []byte("x")})
+	if exists, _ := backend.Exists(ctx, "external.txt"); exists {
+		t.Error("Expected the external commit to be unseen within the interval")
+	}
+	backend.config.TreeCacheInterval = time.Nanosecond
+	if exists, _ := backend.Exists(ctx, "external.txt"); !exists {
+		t.Error("Expected the external commit to be seen after the interval")
+	}
+}
+
+func TestTreeCacheLFS(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	backend.config.TreeCache = true
+
+	content := []byte("large binary content")
+	pointer := newLFSPointer(content)
+	srv.CommitFiles(githubtest.DefaultBranch, "Track LFS", map[string][]byte{
+		".gitattributes": []byte("*.bin filter=lfs diff=lfs merge=lfs -text\n"),
+		"data.bin":       []byte(pointer.String()),
+	})
+
+	// LFS-tracked paths are looked up through the API for their real size
+	info, err := backend.Stat(context.Background(), "data.bin")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Size() != int64(len(content)) {
+		t.Errorf("Stat size = %d, want %d", info.Size(), len(content))
+	}
+}
+
+func TestTreeSnapshotRemove(t *testing.T) {
+	s := &treeSnapshot{entries: map[string]treeCacheEntry{"": {typ: "tree"}}}
+	for _, p := range []string{"a/b/c/1.txt", "a/b/c/2.txt", "a/b/d.txt", "a/e/f.txt", "g.txt"} {
+		s.add(p, treeCacheEntry{typ: "blob"})
+	}
+
+	s.remove("a/b/c/1.txt", "a/b/c/2.txt", "a/e/f.txt", "a/missing/x.txt")
+
+	var got []string
+	for p := range s.entries {
+		got = append(got, p)
+	}
+	sort.Strings(got)
+	want := []string{"", "a", "a/b", "a/b/d.txt", "g.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+
+	s.remove("a/b/d.txt")
+	if _, ok := s.entries["a"]; ok {
+		t.Error("Expected the emptied directory tree to be removed")
+	}
+}
@@ -0,0 +1,277 @@
+package github
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grokify/gogithub/pathutil"
+)
+
+// ChangeType is the kind of change made to a path.
+type ChangeType string
+
+const (
+	// ChangeCreate is a path that was added.
+	ChangeCreate ChangeType = "create"
+	// ChangeUpdate is a path whose content or file mode changed.
+	ChangeUpdate ChangeType = "update"
+	// ChangeDelete is a path that was removed.
+	ChangeDelete ChangeType = "delete"
+)
+
+// defaultWatchPollInterval is how often a Watcher checks the branch head.
+const defaultWatchPollInterval = 10 * time.Second
+
+// ChangeEvent is a change to a file on the branch.
+type ChangeEvent struct {
+	Type ChangeType
+	Path string
+
+	// OldSHA is the blob SHA before the change, or "" for creates. Events
+	// from the file lists of webhook push payloads have no SHAs.
+	OldSHA string
+
+	// NewSHA is the blob SHA after the change, or "" for deletes.
+	NewSHA string
+
+	// Commit is the branch head that includes the change.
+	Commit ChangeCommit
+}
+
+// ChangeCommit describes the commit a ChangeEvent was observed at. When
+// the branch moved by several commits at once, it is the newest of them
+// and the events cover all of them.
+type ChangeCommit struct {
+	// SHA is the commit the branch moved to.
+	SHA string
+
+	// PreviousSHA is the commit the branch moved from.
+	PreviousSHA string
+
+	Message string
+	Author  CommitAuthor
+	Date    time.Time
+	HTMLURL string
+}
+
+// WatchOptions configures a Watcher.
+type WatchOptions struct {
+	// Since is the commit to report changes from, typically Watcher.Commit
+	// of a previous run. Default: the branch head when Watch is called.
+	Since string
+
+	// PollInterval is how often the branch head is checked. The check is
+	// revalidated with ETags, so an unchanged branch costs no rate limit
+	// quota. Default: 10s.
+	PollInterval time.Duration
+}
+
+// Watcher reports changes to the files of a branch. Read events from
+// Events until it is closed, then check Err.
+type Watcher struct {
+	backend *Backend
+	prefix  string
+	opts    WatchOptions
+	events  chan ChangeEvent
+
+	mu     sync.Mutex
+	commit string
+	err    error
+}
+
+// Watch polls the branch for new commits and emits an event for each file
+// under prefix that was created, updated or deleted. Changes are found by
+// diffing the recursive trees of the old and new branch heads.
+//
+// The watcher stops when ctx is canceled or polling fails; Err then
+// returns the reason. To resume after a restart without missing changes,
+// pass the last Commit as WatchOptions.Since. Events of a commit are
+// delivered at least once.
+func (b *Backend) Watch(ctx context.Context, prefix string, opts WatchOptions) (*Watcher, error) {
+	if err := b.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultWatchPollInterval
+	}
+
+	since := opts.Since
+	if since == "" {
+		ref, resp, err := b.client.Git.GetRef(ctx, b.config.Owner, b.config.Repo, "heads/"+b.config.Branch)
+		if err != nil {
+			return nil, b.translateError(err, resp)
+		}
+		since = ref.GetObject().GetSHA()
+	}
+
+	snapshot, err := b.loadWatchSnapshot(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		backend: b,
+		prefix:  pathutil.Normalize(prefix),
+		opts:    opts,
+		events:  make(chan ChangeEvent),
+		commit:  since,
+	}
+	go w.run(ctx, snapshot)
+
+	return w, nil
+}
+
+// Events returns the channel of change events. It is closed when the
+// watcher stops.
+func (w *Watcher) Events() <-chan ChangeEvent {
+	return w.events
+}
+
+// Commit returns the last commit whose events have all been delivered.
+func (w *Watcher) Commit() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.commit
+}
+
+// Err returns the reason the watcher stopped, once Events is closed.
+func (w *Watcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// run polls the branch until ctx is canceled or an error occurs.
+func (w *Watcher) run(ctx context.Context, snapshot *treeSnapshot) {
+	defer close(w.events)
+
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		next, err := w.poll(ctx, snapshot)
+		if err != nil {
+			w.mu.Lock()
+			w.err = err
+			w.mu.Unlock()
+			return
+		}
+		snapshot = next
+
+		select {
+		case <-ctx.Done():
+			w.mu.Lock()
+			w.err = ctx.Err()
+			w.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll checks the branch head and emits the changes since snapshot.
+func (w *Watcher) poll(ctx context.Context, snapshot *treeSnapshot) (*treeSnapshot, error) {
+	b := w.backend
+	if err := b.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	ref, resp, err := b.client.Git.GetRef(ctx, b.config.Owner, b.config.Repo, "heads/"+b.config.Branch)
+	if err != nil {
+		return nil, b.translateError(err, resp)
+	}
+	head := ref.GetObject().GetSHA()
+	if head == snapshot.commitSHA {
+		return snapshot, nil
+	}
+
+	next, err := b.loadWatchSnapshot(ctx, head)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, resp, err := b.client.Git.GetCommit(ctx, b.config.Owner, b.config.Repo, head)
+	if err != nil {
+		return nil, b.translateError(err, resp)
+	}
+	info := ChangeCommit{
+		SHA:         head,
+		PreviousSHA: snapshot.commitSHA,
+		Message:     commit.GetMessage(),
+		Author: CommitAuthor{
+			Name:  commit.GetAuthor().GetName(),
+			Email: commit.GetAuthor().GetEmail(),
+		},
+		Date:    commit.GetAuthor().GetDate().Time,
+		HTMLURL: commit.GetHTMLURL(),
+	}
+
+	for _, event := range diffTrees(snapshot, next, w.prefix) {
+		event.Commit = info
+		select {
+		case w.events <- event:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	w.mu.Lock()
+	w.commit = head
+	w.mu.Unlock()
+
+	return next, nil
+}
+
+// loadWatchSnapshot loads the tree of a commit for diffing. If GitHub
+// truncates the recursive tree, it is walked one directory per request.
+func (b *Backend) loadWatchSnapshot(ctx context.Context, commitSHA string) (*treeSnapshot, error) {
+	snapshot, err := b.loadTreeSnapshot(ctx, commitSHA)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.truncated {
+		if snapshot.entries, err = b.walkTree(ctx, commitSHA); err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+// diffTrees returns the file changes between two trees under prefix, in
+// path order.
+func diffTrees(from, to *treeSnapshot, prefix string) []ChangeEvent {
+	var events []ChangeEvent
+	for p, entry := range to.entries {
+		if entry.typ != "blob" || !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		old, ok := from.entries[p]
+		switch {
+		case !ok || old.typ != "blob":
+			events = append(events, ChangeEvent{Type: ChangeCreate, Path: p, NewSHA: entry.sha})
+		case old.sha != entry.sha || old.mode != entry.mode:
+			events = append(events, ChangeEvent{Type: ChangeUpdate, Path: p, OldSHA: old.sha, NewSHA: entry.sha})
+		}
+	}
+	for p, entry := range from.entries {
+		if entry.typ != "blob" || !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		if current, ok := to.entries[p]; !ok || current.typ != "blob" {
+			events = append(events, ChangeEvent{Type: ChangeDelete, Path: p, OldSHA: entry.sha})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Path < events[j].Path
+	})
+	return events
+}
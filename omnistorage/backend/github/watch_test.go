@@ -0,0 +1,133 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omni-github/omnistorage/backend/github/githubtest"
+)
+
+// collectEvents reads n events from a watcher.
+func collectEvents(t *testing.T, w *Watcher, n int) []ChangeEvent {
+	t.Helper()
+	var events []ChangeEvent
+	timeout := time.After(5 * time.Second)
+	for len(events) < n {
+		select {
+		case event, ok := <-w.Events():
+			if !ok {
+				t.Fatalf("Watcher stopped after %d events: %v", len(events), w.Err())
+			}
+			events = append(events, event)
+		case <-timeout:
+			t.Fatalf("Timed out after %d of %d events: %+v", len(events), n, events)
+		}
+	}
+	return events
+}
+
+func TestWatch(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err := backend.Watch(ctx, "backend/", WatchOptions{PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	start := w.Commit()
+	blobSHA := func(p string) string {
+		data, _ := srv.File(start, p)
+		return githubtest.BlobSHA(data)
+	}
+
+	head := srv.CommitFiles(githubtest.DefaultBranch, "Change backends", map[string][]byte{
+		"backend/file/file.go":  []byte("package file // changed\n"),
+		"backend/memory/mem.go": nil,
+		"backend/s3/s3.go":      []byte("package s3\n"),
+		"README.md":             []byte("# Outside the prefix"),
+	})
+
+	events := collectEvents(t, w, 3)
+	for i := range events {
+		if events[i].Commit.SHA != head || events[i].Commit.PreviousSHA != start {
+			t.Errorf("event %d commit = %+v, want %s from %s", i, events[i].Commit, head, start)
+		}
+		if events[i].Commit.Message != "Change backends" {
+			t.Errorf("event %d message = %q", i, events[i].Commit.Message)
+		}
+		events[i].Commit = ChangeCommit{}
+	}
+	want := []ChangeEvent{
+		{Type: ChangeUpdate, Path: "backend/file/file.go", OldSHA: blobSHA("backend/file/file.go"), NewSHA: githubtest.BlobSHA([]byte("package file // changed\n"))},
+		{Type: ChangeDelete, Path: "backend/memory/mem.go", OldSHA: blobSHA("backend/memory/mem.go")},
+		{Type: ChangeCreate, Path: "backend/s3/s3.go", NewSHA: githubtest.BlobSHA([]byte("package s3\n"))},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %+v, want %+v", events, want)
+	}
+
+	// Commit advances once all events of the move are delivered
+	deadline := time.Now().Add(5 * time.Second)
+	for w.Commit() != head && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if w.Commit() != head {
+		t.Errorf("Commit = %s, want %s", w.Commit(), head)
+	}
+
+	cancel()
+	for range w.Events() {
+	}
+	if !errors.Is(w.Err(), context.Canceled) {
+		t.Errorf("Err = %v, want context.Canceled", w.Err())
+	}
+}
+
+func TestWatchTruncatedTree(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	srv.SetTreeLimit(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err := backend.Watch(ctx, "backend/", WatchOptions{PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	srv.CommitFiles(githubtest.DefaultBranch, "Add backend", map[string][]byte{
+		"backend/s3/s3.go": []byte("package s3\n"),
+	})
+
+	events := collectEvents(t, w, 1)
+	if events[0].Type != ChangeCreate || events[0].Path != "backend/s3/s3.go" {
+		t.Errorf("event = %+v", events[0])
+	}
+}
+
+func TestWatchResume(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	since := srv.Head(githubtest.DefaultBranch)
+
+	// Changes made while no watcher was running are reported on resume
+	srv.CommitFiles(githubtest.DefaultBranch, "First", map[string][]byte{"a.txt": []byte("a")})
+	srv.CommitFiles(githubtest.DefaultBranch, "Second", map[string][]byte{"b.txt": []byte("b")})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err := backend.Watch(ctx, "", WatchOptions{Since: since, PollInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	events := collectEvents(t, w, 2)
+	if events[0].Path != "a.txt" || events[1].Path != "b.txt" || events[1].Commit.Message != "Second" {
+		t.Errorf("events = %+v", events)
+	}
+
+	if _, err := backend.Watch(ctx, "", WatchOptions{Since: "0000000000000000000000000000000000000000"}); err == nil {
+		t.Error("Expected an error for an unknown commit")
+	}
+}
@@ -0,0 +1,186 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v84/github"
+	"github.com/grokify/gogithub/pathutil"
+)
+
+// ErrWebhookSecretRequired is returned by NewWebhookHandler without a secret.
+var ErrWebhookSecretRequired = errors.New("github: webhook secret is required")
+
+// webhookMaxPayloadSize is the largest payload GitHub delivers.
+const webhookMaxPayloadSize = 25 << 20
+
+// zeroSHA is the before or after SHA of a push that creates or deletes a
+// branch.
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+// WebhookOptions configures a WebhookHandler.
+type WebhookOptions struct {
+	// Secret is the webhook secret used to validate the
+	// X-Hub-Signature-256 header of deliveries. Required.
+	Secret string
+
+	// Prefix limits events to files under a path prefix. Optional.
+	Prefix string
+
+	// OnChange is called with the events of each push to the branch, in
+	// commit order. It runs before the delivery is acknowledged, and
+	// GitHub times out deliveries after 10 seconds, so long work should be
+	// handed off. Optional.
+	OnChange func(ctx context.Context, events []ChangeEvent)
+}
+
+// WebhookHandler is an http.Handler for GitHub push webhooks. It turns
+// pushes to the configured branch into the same events as Watch, and
+// expires the backend's tree cache so that reads see the new head.
+type WebhookHandler struct {
+	backend *Backend
+	opts    WebhookOptions
+}
+
+// NewWebhookHandler creates a handler for push webhooks of the configured
+// repository. Deliveries with an invalid signature are rejected, and
+// events for other repositories, other branches and other event types are
+// acknowledged and ignored.
+func (b *Backend) NewWebhookHandler(opts WebhookOptions) (*WebhookHandler, error) {
+	if opts.Secret == "" {
+		return nil, ErrWebhookSecretRequired
+	}
+	opts.Prefix = pathutil.Normalize(opts.Prefix)
+	return &WebhookHandler{backend: b, opts: opts}, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, webhookMaxPayloadSize)
+	payload, err := github.ValidatePayload(r, []byte(h.opts.Secret))
+	if err != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	if github.WebHookType(r) != "push" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	event, err := github.ParseWebHook("push", payload)
+	if err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	push := event.(*github.PushEvent)
+
+	if !h.matches(push) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	h.backend.expireTreeCache(push.GetAfter())
+
+	events, err := h.pushEvents(r.Context(), push)
+	if err != nil {
+		// Ask GitHub to redeliver
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if h.opts.OnChange != nil && len(events) > 0 {
+		h.opts.OnChange(r.Context(), events)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// matches reports whether a push is to the configured repository and branch.
+func (h *WebhookHandler) matches(push *github.PushEvent) bool {
+	cfg := h.backend.config
+	return strings.EqualFold(push.GetRepo().GetFullName(), cfg.Owner+"/"+cfg.Repo) &&
+		push.GetRef() == "refs/heads/"+cfg.Branch
+}
+
+// pushEvents translates a push into change events.
+//
+// The file lists of each commit are used when the push only added commits.
+// Forced pushes and branch creation rewrite history the lists do not
+// describe, so the trees before and after the push are diffed instead;
+// those events carry blob SHAs, which push payloads do not include.
+func (h *WebhookHandler) pushEvents(ctx context.Context, push *github.PushEvent) ([]ChangeEvent, error) {
+	if push.GetDeleted() {
+		return nil, nil
+	}
+	if push.GetForced() || push.GetCreated() {
+		return h.diffPush(ctx, push)
+	}
+
+	var events []ChangeEvent
+	previous := push.GetBefore()
+	for _, commit := range push.Commits {
+		info := headCommitInfo(commit, previous)
+		previous = commit.GetID()
+
+		add := func(typ ChangeType, paths []string) {
+			for _, p := range paths {
+				if strings.HasPrefix(p, h.opts.Prefix) {
+					events = append(events, ChangeEvent{Type: typ, Path: p, Commit: info})
+				}
+			}
+		}
+		add(ChangeCreate, commit.Added)
+		add(ChangeUpdate, commit.Modified)
+		add(ChangeDelete, commit.Removed)
+	}
+	return events, nil
+}
+
+// diffPush returns the events of a push by diffing the trees before and
+// after it.
+func (h *WebhookHandler) diffPush(ctx context.Context, push *github.PushEvent) ([]ChangeEvent, error) {
+	from := &treeSnapshot{}
+	if before := push.GetBefore(); before != "" && before != zeroSHA {
+		var err error
+		if from, err = h.backend.loadWatchSnapshot(ctx, before); err != nil {
+			return nil, err
+		}
+	}
+
+	to, err := h.backend.loadWatchSnapshot(ctx, push.GetAfter())
+	if err != nil {
+		return nil, err
+	}
+
+	info := headCommitInfo(push.GetHeadCommit(), push.GetBefore())
+	info.SHA = push.GetAfter()
+
+	events := diffTrees(from, to, h.opts.Prefix)
+	for i := range events {
+		events[i].Commit = info
+	}
+	return events, nil
+}
+
+// headCommitInfo converts a push payload commit.
+func headCommitInfo(commit *github.HeadCommit, previousSHA string) ChangeCommit {
+	return ChangeCommit{
+		SHA:         commit.GetID(),
+		PreviousSHA: previousSHA,
+		Message:     commit.GetMessage(),
+		Author: CommitAuthor{
+			Name:  commit.GetAuthor().GetName(),
+			Email: commit.GetAuthor().GetEmail(),
+		},
+		Date:    commit.GetTimestamp().Time,
+		HTMLURL: commit.GetURL(),
+	}
+}
@@ -0,0 +1,179 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plexusone/omni-github/omnistorage/backend/github/githubtest"
+)
+
+const testWebhookSecret = "It's a Secret to Everybody"
+
+// deliver sends a signed webhook delivery to h and returns the status code.
+func deliver(t *testing.T, h http.Handler, eventType string, payload []byte, secret string) int {
+	t.Helper()
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", eventType)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestWebhookHandler(t *testing.T) {
+	backend, _ := fakeServerBackend(t)
+
+	if _, err := backend.NewWebhookHandler(WebhookOptions{}); !errors.Is(err, ErrWebhookSecretRequired) {
+		t.Errorf("NewWebhookHandler without secret error = %v", err)
+	}
+
+	var events []ChangeEvent
+	h, err := backend.NewWebhookHandler(WebhookOptions{
+		Secret: testWebhookSecret,
+		OnChange: func(_ context.Context, e []ChangeEvent) {
+			events = append(events, e...)
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewWebhookHandler failed: %v", err)
+	}
+
+	payload, err := os.ReadFile("testdata/push_event.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := deliver(t, h, "push", payload, "wrong secret"); code != http.StatusUnauthorized {
+		t.Errorf("bad signature status = %d, want 401", code)
+	}
+	if code := deliver(t, h, "ping", []byte(`{"zen":"Keep it logically awesome."}`), testWebhookSecret); code != http.StatusNoContent {
+		t.Errorf("ping status = %d, want 204", code)
+	}
+	otherBranch := bytes.Replace(payload, []byte(`"refs/heads/main"`), []byte(`"refs/heads/dev"`), 1)
+	if code := deliver(t, h, "push", otherBranch, testWebhookSecret); code != http.StatusNoContent {
+		t.Errorf("other branch status = %d, want 204", code)
+	}
+	otherRepo := bytes.Replace(payload, []byte(`"grokify/omnistorage"`), []byte(`"grokify/other"`), 1)
+	if code := deliver(t, h, "push", otherRepo, testWebhookSecret); code != http.StatusNoContent {
+		t.Errorf("other repo status = %d, want 204", code)
+	}
+	if len(events) != 0 {
+		t.Fatalf("Expected no events for ignored deliveries, got %+v", events)
+	}
+
+	if code := deliver(t, h, "push", payload, testWebhookSecret); code != http.StatusNoContent {
+		t.Fatalf("push status = %d, want 204", code)
+	}
+
+	type summary struct {
+		Type   ChangeType
+		Path   string
+		Commit string
+		Prev   string
+	}
+	var got []summary
+	for _, e := range events {
+		got = append(got, summary{e.Type, e.Path, e.Commit.SHA[:7], e.Commit.PreviousSHA[:7]})
+	}
+	want := []summary{
+		{ChangeCreate, "backend/s3/s3.go", "b0b1e8e", "6113728"},
+		{ChangeUpdate, "README.md", "b0b1e8e", "6113728"},
+		{ChangeUpdate, "backend/file/file.go", "59b20b8", "b0b1e8e"},
+		{ChangeDelete, "backend/memory/mem.go", "59b20b8", "b0b1e8e"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+
+	commit := events[0].Commit
+	if commit.Message != "Add S3 backend" || commit.Author.Email != "octocat@example.com" {
+		t.Errorf("commit = %+v", commit)
+	}
+	if want := time.Date(2026, 10, 18, 7, 12, 45, 0, time.UTC); !commit.Date.Equal(want) {
+		t.Errorf("commit date = %v, want %v", commit.Date, want)
+	}
+}
+
+func TestWebhookForcedPush(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	backend.config.TreeCache = true
+	backend.config.TreeCacheInterval = time.Hour
+
+	var events []ChangeEvent
+	h, err := backend.NewWebhookHandler(WebhookOptions{
+		Secret: testWebhookSecret,
+		Prefix: "backend",
+		OnChange: func(_ context.Context, e []ChangeEvent) {
+			events = append(events, e...)
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewWebhookHandler failed: %v", err)
+	}
+
+	ctx := context.Background()
+	if exists, _ := backend.Exists(ctx, "backend/s3/s3.go"); exists {
+		t.Fatal("Expected s3.go not to exist yet")
+	}
+
+	before := srv.Head(githubtest.DefaultBranch)
+	after := srv.CommitFiles(githubtest.DefaultBranch, "Rewrite", map[string][]byte{
+		"backend/s3/s3.go": []byte("package s3\n"),
+		"README.md":        []byte("# Outside the prefix"),
+	})
+	payload := fmt.Sprintf(`{
+		"ref": "refs/heads/main", "before": %q, "after": %q, "forced": true,
+		"commits": [], "head_commit": {"id": %q, "message": "Rewrite"},
+		"repository": {"full_name": "grokify/omnistorage"}
+	}`, before, after, after)
+
+	if code := deliver(t, h, "push", []byte(payload), testWebhookSecret); code != http.StatusNoContent {
+		t.Fatalf("push status = %d, want 204", code)
+	}
+
+	want := []ChangeEvent{{
+		Type:   ChangeCreate,
+		Path:   "backend/s3/s3.go",
+		NewSHA: githubtest.BlobSHA([]byte("package s3\n")),
+		Commit: ChangeCommit{SHA: after, PreviousSHA: before, Message: "Rewrite"},
+	}}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %+v, want %+v", events, want)
+	}
+
+	// The push expires the tree cache before its interval
+	if exists, _ := backend.Exists(ctx, "backend/s3/s3.go"); !exists {
+		t.Error("Expected the pushed file to be visible after the webhook")
+	}
+}
+
+func TestWebhookMethod(t *testing.T) {
+	backend, _ := fakeServerBackend(t)
+	h, err := backend.NewWebhookHandler(WebhookOptions{Secret: testWebhookSecret})
+	if err != nil {
+		t.Fatalf("NewWebhookHandler failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhook", strings.NewReader("")))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want 405", rec.Code)
+	}
+}
@@ -0,0 +1,126 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+// ErrSHAMismatch is returned by writes with WithExpectedSHA when the file
+// on the branch does not have the expected blob SHA.
+var ErrSHAMismatch = errors.New("github: file does not have the expected SHA")
+
+// Writer option metadata keys. omnistorage.WriterOption can only set the
+// fields of omnistorage.WriterConfig, so the options of this backend are
+// carried in its Metadata.
+const (
+	metadataCommitMessage = "github-commit-message"
+	metadataAuthorName    = "github-author-name"
+	metadataAuthorEmail   = "github-author-email"
+	metadataExpectedSHA   = "github-expected-sha"
+	metadataFileMode      = "github-file-mode"
+	metadataBranch        = "github-branch"
+)
+
+// writerOptions are the options of a writer set with the With* writer
+// options of this package.
+type writerOptions struct {
+	message     string
+	author      *CommitAuthor
+	expectedSHA *string
+	mode        FileMode
+	branch      string
+}
+
+// WithCommitMessage sets the commit message of a write, instead of the
+// Config's templates. Trailers from the commit options are still added.
+//
+// The options of this package are stored in the writer metadata, so pass
+// them after omnistorage.WithMetadata, which replaces it.
+func WithCommitMessage(message string) omnistorage.WriterOption {
+	return withWriterMetadata(metadataCommitMessage, message)
+}
+
+// WithCommitAuthor sets the author of a write's commit.
+func WithCommitAuthor(author CommitAuthor) omnistorage.WriterOption {
+	return func(c *omnistorage.WriterConfig) {
+		withWriterMetadata(metadataAuthorName, author.Name)(c)
+		withWriterMetadata(metadataAuthorEmail, author.Email)(c)
+	}
+}
+
+// WithExpectedSHA makes a write fail with ErrSHAMismatch unless the file
+// on the branch has the given blob SHA, such as the HashGitBlob from Stat.
+// An empty SHA requires that the file does not exist. The check and the
+// commit are atomic, so concurrent writers cannot overwrite each other's
+// changes.
+func WithExpectedSHA(sha string) omnistorage.WriterOption {
+	return withWriterMetadata(metadataExpectedSHA, sha)
+}
+
+// WithFileMode sets the Git mode of the written file, for example
+// FileModeExecutable for scripts. Not supported by the GraphQL commit
+// engine.
+func WithFileMode(mode FileMode) omnistorage.WriterOption {
+	return withWriterMetadata(metadataFileMode, string(mode))
+}
+
+// WithBranch commits a write to another branch of the repository than
+// Config.Branch. The branch must exist.
+func WithBranch(branch string) omnistorage.WriterOption {
+	return withWriterMetadata(metadataBranch, branch)
+}
+
+// withWriterMetadata returns an option that sets a metadata key. The map
+// is copied so that a map passed to omnistorage.WithMetadata is not
+// modified.
+func withWriterMetadata(key, value string) omnistorage.WriterOption {
+	return func(c *omnistorage.WriterConfig) {
+		metadata := maps.Clone(c.Metadata)
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[key] = value
+		c.Metadata = metadata
+	}
+}
+
+// newWriterOptions reads the options of this package from writer options.
+func newWriterOptions(opts ...omnistorage.WriterOption) (writerOptions, error) {
+	metadata := omnistorage.ApplyWriterOptions(opts...).Metadata
+
+	wo := writerOptions{
+		message: metadata[metadataCommitMessage],
+		mode:    FileMode(metadata[metadataFileMode]),
+		branch:  metadata[metadataBranch],
+	}
+	name, hasName := metadata[metadataAuthorName]
+	email, hasEmail := metadata[metadataAuthorEmail]
+	if hasName || hasEmail {
+		wo.author = &CommitAuthor{Name: name, Email: email}
+	}
+	if sha, ok := metadata[metadataExpectedSHA]; ok {
+		wo.expectedSHA = &sha
+	}
+
+	if wo.mode != "" {
+		if err := checkFileMode(wo.mode); err != nil {
+			return writerOptions{}, err
+		}
+	}
+	return wo, nil
+}
+
+// checkExpectedSHA compares the blob SHA of a file, or "" if it does not
+// exist, with the SHA expected by a write.
+func checkExpectedSHA(filePath string, expected *string, actual string) error {
+	if expected == nil || *expected == actual {
+		return nil
+	}
+	if actual == "" {
+		return fmt.Errorf("%w: %s does not exist", ErrSHAMismatch, filePath)
+	}
+	return fmt.Errorf("%w: %s is at %s", ErrSHAMismatch, filePath, actual)
+}
@@ -0,0 +1,172 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/plexusone/omni-github/omnistorage/backend/github/githubtest"
+	omnistorage "github.com/plexusone/omnistorage-core/object"
+)
+
+// writeWith writes content with writer options and returns the error of
+// Close.
+func writeWith(t *testing.T, backend *Backend, p string, content []byte, opts ...omnistorage.WriterOption) error {
+	t.Helper()
+	w, err := backend.NewWriter(context.Background(), p, opts...)
+	if err != nil {
+		t.Fatalf("NewWriter(%q) failed: %v", p, err)
+	}
+	if _, err := w.Write(content); err != nil {
+		t.Fatalf("Write(%q) failed: %v", p, err)
+	}
+	return w.Close()
+}
+
+// treeMode returns the mode of a path in the tree of a branch head.
+func treeMode(t *testing.T, backend *Backend, srv *githubtest.Server, branch, p string) string {
+	t.Helper()
+	ctx := context.Background()
+	commit, _, err := backend.client.Git.GetCommit(ctx, "grokify", "omnistorage", srv.Head(branch))
+	if err != nil {
+		t.Fatalf("GetCommit failed: %v", err)
+	}
+	tree, _, err := backend.client.Git.GetTree(ctx, "grokify", "omnistorage", commit.GetTree().GetSHA(), true)
+	if err != nil {
+		t.Fatalf("GetTree failed: %v", err)
+	}
+	for _, entry := range tree.Entries {
+		if entry.GetPath() == p {
+			return entry.GetMode()
+		}
+	}
+	t.Fatalf("%s not in tree of %s", p, branch)
+	return ""
+}
+
+func TestWithCommitMessageAndAuthor(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	defer func() { _ = backend.Close() }()
+	backend.config.CommitAuthor = &CommitAuthor{Name: "Test Bot", Email: "bot@example.com"}
+	backend.config.CommitSignOff = true
+
+	// The options must not modify a map passed to WithMetadata
+	metadata := map[string]string{"x": "y"}
+	err := writeWith(t, backend, "docs/a.md", []byte("a"),
+		omnistorage.WithMetadata(metadata),
+		WithCommitMessage("Add docs"),
+		WithCommitAuthor(CommitAuthor{Name: "Jane Doe", Email: "jane@example.com"}),
+	)
+	if err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if len(metadata) != 1 {
+		t.Errorf("metadata = %v, want it unmodified", metadata)
+	}
+
+	commit, _, err := backend.client.Git.GetCommit(context.Background(), "grokify", "omnistorage", srv.Head(githubtest.DefaultBranch))
+	if err != nil {
+		t.Fatalf("GetCommit failed: %v", err)
+	}
+	if want := "Add docs\n\nSigned-off-by: Jane Doe <jane@example.com>"; commit.GetMessage() != want {
+		t.Errorf("message = %q, want %q", commit.GetMessage(), want)
+	}
+	if name := commit.GetAuthor().GetName(); name != "Jane Doe" {
+		t.Errorf("author = %q, want Jane Doe", name)
+	}
+}
+
+func TestWithExpectedSHA(t *testing.T) {
+	backend, _ := fakeServerBackend(t)
+	defer func() { _ = backend.Close() }()
+	ctx := context.Background()
+
+	info, err := backend.Stat(ctx, "README.md")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	sha := info.Hash(HashGitBlob)
+
+	if err := writeWith(t, backend, "README.md", []byte("v2"), WithExpectedSHA(sha)); err != nil {
+		t.Fatalf("write with current SHA: %v", err)
+	}
+	assertContent(t, ctx, backend, "README.md", []byte("v2"))
+
+	// The SHA is stale after the first write
+	if err := writeWith(t, backend, "README.md", []byte("v3"), WithExpectedSHA(sha)); !errors.Is(err, ErrSHAMismatch) {
+		t.Errorf("write with stale SHA = %v, want ErrSHAMismatch", err)
+	}
+	assertContent(t, ctx, backend, "README.md", []byte("v2"))
+
+	// An empty SHA creates the file only if it does not exist
+	if err := writeWith(t, backend, "new.txt", []byte("new"), WithExpectedSHA("")); err != nil {
+		t.Fatalf("create with empty SHA: %v", err)
+	}
+	if err := writeWith(t, backend, "new.txt", []byte("again"), WithExpectedSHA("")); !errors.Is(err, ErrSHAMismatch) {
+		t.Errorf("overwrite with empty SHA = %v, want ErrSHAMismatch", err)
+	}
+	assertContent(t, ctx, backend, "new.txt", []byte("new"))
+}
+
+func TestWithFileMode(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	defer func() { _ = backend.Close() }()
+
+	if err := writeWith(t, backend, "scripts/build.sh", []byte("#!/bin/sh\n"), WithFileMode(FileModeExecutable)); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if mode := treeMode(t, backend, srv, "main", "scripts/build.sh"); mode != "100755" {
+		t.Errorf("mode = %s, want 100755", mode)
+	}
+
+	// Changing only the mode still commits
+	if err := writeWith(t, backend, "scripts/build.sh", []byte("#!/bin/sh\n"), WithFileMode(FileModeRegular)); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if mode := treeMode(t, backend, srv, "main", "scripts/build.sh"); mode != "100644" {
+		t.Errorf("mode = %s, want 100644", mode)
+	}
+
+	if _, err := backend.NewWriter(context.Background(), "a.txt", WithFileMode("040000")); err == nil {
+		t.Error("NewWriter with a tree mode succeeded")
+	}
+
+	backend.config.CommitEngine = CommitEngineGraphQL
+	if err := writeWith(t, backend, "run.sh", []byte("x"), WithFileMode(FileModeExecutable)); !errors.Is(err, omnistorage.ErrNotSupported) {
+		t.Errorf("GraphQL write with mode = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestWithBranch(t *testing.T) {
+	backend, srv := fakeServerBackend(t)
+	defer func() { _ = backend.Close() }()
+	srv.CommitFiles("dev", "Create dev", map[string][]byte{"dev.txt": []byte("dev")})
+	mainHead := srv.Head(githubtest.DefaultBranch)
+
+	for _, engine := range []CommitEngine{CommitEngineREST, CommitEngineGraphQL} {
+		backend.config.CommitEngine = engine
+		if err := writeWith(t, backend, "notes.txt", []byte(engine), WithBranch("dev")); err != nil {
+			t.Fatalf("%s: Close failed: %v", engine, err)
+		}
+		if got, ok := srv.File("dev", "notes.txt"); !ok || string(got) != string(engine) {
+			t.Errorf("%s: dev notes.txt = %q, %v", engine, got, ok)
+		}
+	}
+	if err := writeWith(t, backend, "notes.txt", []byte("x"), WithBranch("dev"), WithFileMode(FileModeExecutable)); err == nil {
+		t.Error("GraphQL write with mode succeeded")
+	}
+	backend.config.CommitEngine = CommitEngineREST
+	if err := writeWith(t, backend, "run.sh", []byte("x"), WithBranch("dev"), WithFileMode(FileModeExecutable)); err != nil {
+		t.Fatalf("batch write to dev failed: %v", err)
+	}
+	if mode := treeMode(t, backend, srv, "dev", "run.sh"); mode != "100755" {
+		t.Errorf("mode = %s, want 100755", mode)
+	}
+
+	if head := srv.Head(githubtest.DefaultBranch); head != mainHead {
+		t.Errorf("main moved to %s", head)
+	}
+	if _, err := backend.Stat(context.Background(), "notes.txt"); !errors.Is(err, omnistorage.ErrNotFound) {
+		t.Errorf("Stat on main = %v, want ErrNotFound", err)
+	}
+}